@@ -0,0 +1,98 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"notes/internal/database/generated"
+	"notes/internal/model"
+	"notes/internal/repository"
+)
+
+func dbAccessTokenToAccessToken(dbToken generated.PersonalAccessToken) *model.PersonalAccessToken {
+	token := &model.PersonalAccessToken{
+		ID:        dbToken.ID,
+		UserID:    dbToken.UserID,
+		Name:      dbToken.Name,
+		Scopes:    strings.Fields(dbToken.Scopes),
+		CreatedAt: dbToken.CreatedAt,
+	}
+	if dbToken.ExpiresAt.Valid {
+		token.ExpiresAt = &dbToken.ExpiresAt.Time
+	}
+	if dbToken.LastUsedAt.Valid {
+		token.LastUsedAt = &dbToken.LastUsedAt.Time
+	}
+	return token
+}
+
+func (r *Repository) CreateAccessToken(ctx context.Context, accessToken model.PersonalAccessTokenDTO) (*model.PersonalAccessToken, error) {
+	token, err := newToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	token = model.AccessTokenPrefix + token
+
+	// timestamps are stored without a time zone, in UTC
+	var expiresAt sql.NullTime
+	if accessToken.ExpiresAt != nil {
+		expiresAt = sql.NullTime{Time: accessToken.ExpiresAt.UTC(), Valid: true}
+	}
+
+	dbToken, err := r.Queries.CreatePersonalAccessToken(ctx, generated.CreatePersonalAccessTokenParams{
+		UserID:    accessToken.UserID,
+		Name:      accessToken.Name,
+		TokenHash: hashToken(token),
+		Scopes:    strings.Join(accessToken.Scopes, " "),
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	created := dbAccessTokenToAccessToken(dbToken)
+	created.Token = token
+	return created, nil
+}
+
+func (r *Repository) ListAccessTokens(ctx context.Context, userID int32) ([]model.PersonalAccessToken, error) {
+	dbTokens, err := r.Queries.ListPersonalAccessTokens(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := make([]model.PersonalAccessToken, 0, len(dbTokens))
+
+	for _, dbToken := range dbTokens {
+		tokens = append(tokens, *dbAccessTokenToAccessToken(dbToken))
+	}
+
+	return tokens, nil
+}
+
+func (r *Repository) RevokeAccessToken(ctx context.Context, userID, tokenID int32) error {
+	revoked, err := r.Queries.RevokePersonalAccessToken(ctx, generated.RevokePersonalAccessTokenParams{
+		ID:     tokenID,
+		UserID: userID,
+	})
+	if err != nil {
+		return err
+	}
+	if revoked == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *Repository) UseAccessToken(ctx context.Context, token string) (*model.PersonalAccessToken, error) {
+	dbToken, err := r.Queries.UsePersonalAccessToken(ctx, hashToken(token))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrInvalidToken
+		}
+		return nil, err
+	}
+	return dbAccessTokenToAccessToken(dbToken), nil
+}
@@ -0,0 +1,240 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"notes/internal/database/generated"
+	"notes/internal/model"
+	"notes/internal/repository"
+)
+
+// likeEscaper escapes the wildcards of LIKE patterns, so searches match
+// them literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// admin listings are ordered by ID, their cursor is the ID of the last
+// entry of the previous page
+func decodeIDCursor(cursor string) (int32, error) {
+	id, err := strconv.ParseInt(cursor, 10, 32)
+	if err != nil || id < 0 {
+		return 0, repository.ErrInvalidCursor
+	}
+	return int32(id), nil
+}
+
+func (r *Repository) ListUsers(ctx context.Context, search string, page model.AdminPage) (*model.UserList, error) {
+	var afterID int32
+	if page.Cursor != "" {
+		var err error
+		afterID, err = decodeIDCursor(page.Cursor)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// one more than the limit tells whether there is a next page
+	rows, err := r.Queries.ListUsersWithUsage(ctx, generated.ListUsersWithUsageParams{
+		Search:   likeEscaper.Replace(search),
+		AfterID:  afterID,
+		RowLimit: page.Limit + 1,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	list := &model.UserList{Users: make([]model.UserWithUsage, 0, len(rows))}
+	for _, row := range rows {
+		list.Users = append(list.Users, *dbUserWithUsage(generated.User{
+			ID:              row.ID,
+			Username:        row.Username,
+			Email:           row.Email,
+			PasswordHash:    row.PasswordHash,
+			CreatedAt:       row.CreatedAt,
+			EmailVerifiedAt: row.EmailVerifiedAt,
+			Role:            row.Role,
+			DisabledAt:      row.DisabledAt,
+			SearchLanguage:  row.SearchLanguage,
+		}, row.NoteCount, row.StorageBytes))
+	}
+	if len(list.Users) > int(page.Limit) {
+		list.Users = list.Users[:page.Limit]
+		list.NextCursor = strconv.Itoa(int(list.Users[len(list.Users)-1].ID))
+	}
+	return list, nil
+}
+
+func (r *Repository) GetUserWithUsage(ctx context.Context, userID int32) (*model.UserWithUsage, error) {
+	row, err := r.Queries.GetUserWithUsage(ctx, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return dbUserWithUsage(generated.User{
+		ID:              row.ID,
+		Username:        row.Username,
+		Email:           row.Email,
+		PasswordHash:    row.PasswordHash,
+		CreatedAt:       row.CreatedAt,
+		EmailVerifiedAt: row.EmailVerifiedAt,
+		Role:            row.Role,
+		DisabledAt:      row.DisabledAt,
+		SearchLanguage:  row.SearchLanguage,
+	}, row.NoteCount, row.StorageBytes), nil
+}
+
+func dbUserWithUsage(dbUser generated.User, noteCount, storageBytes int64) *model.UserWithUsage {
+	return &model.UserWithUsage{
+		User:         *dbUserToUser(dbUser),
+		NoteCount:    noteCount,
+		StorageBytes: storageBytes,
+	}
+}
+
+func (r *Repository) SetUserDisabled(ctx context.Context, userID int32, disabled bool) (*model.User, error) {
+	if !disabled {
+		dbUser, err := r.Queries.EnableUser(ctx, userID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return nil, repository.ErrNotFound
+			}
+			return nil, err
+		}
+		return dbUserToUser(dbUser), nil
+	}
+
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	qtx := r.Queries.WithTx(tx)
+
+	dbUser, err := qtx.DisableUser(ctx, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+
+	if err := qtx.RevokeUserRefreshTokens(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return dbUserToUser(dbUser), nil
+}
+
+func (r *Repository) ForcePasswordReset(ctx context.Context, userID int32, ttl time.Duration) (*model.User, string, error) {
+	token, err := newToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer tx.Rollback()
+
+	qtx := r.Queries.WithTx(tx)
+
+	dbUser, err := qtx.GetUserByID(ctx, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, "", repository.ErrNotFound
+		}
+		return nil, "", err
+	}
+
+	// no password matches an empty hash, so the old password stops working
+	// until the user chooses a new one
+	err = qtx.UpdateUserPassword(ctx, generated.UpdateUserPasswordParams{
+		PasswordHash: "",
+		ID:           userID,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := qtx.RevokeUserRefreshTokens(ctx, userID); err != nil {
+		return nil, "", err
+	}
+
+	_, err = qtx.CreateUserToken(ctx, generated.CreateUserTokenParams{
+		UserID:     userID,
+		Purpose:    purposePasswordReset,
+		TokenHash:  hashToken(token),
+		TtlSeconds: ttl.Seconds(),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, "", err
+	}
+	return dbUserToUser(dbUser), token, nil
+}
+
+func (r *Repository) CreateAuditEntry(ctx context.Context, entry model.AuditEntryDTO) error {
+	return r.Queries.CreateAuditEntry(ctx, generated.CreateAuditEntryParams{
+		ActorID:      sql.NullInt32{Int32: entry.ActorID, Valid: true},
+		Action:       entry.Action,
+		TargetUserID: ptrToNullInt32(entry.TargetUserID),
+		Details:      entry.Details,
+		Ip:           entry.IP,
+		Status:       entry.Status,
+	})
+}
+
+func (r *Repository) ListAuditEntries(ctx context.Context, filter model.AuditFilter, page model.AdminPage) (*model.AuditLog, error) {
+	// newest first, so the cursor is the ID entries are older than
+	beforeID := int32(math.MaxInt32)
+	if page.Cursor != "" {
+		var err error
+		beforeID, err = decodeIDCursor(page.Cursor)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	dbEntries, err := r.Queries.ListAuditEntries(ctx, generated.ListAuditEntriesParams{
+		ActorID:      ptrToNullInt32(filter.ActorID),
+		TargetUserID: ptrToNullInt32(filter.TargetUserID),
+		BeforeID:     beforeID,
+		RowLimit:     page.Limit + 1,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log := &model.AuditLog{Entries: make([]model.AuditEntry, 0, len(dbEntries))}
+	for _, dbEntry := range dbEntries {
+		log.Entries = append(log.Entries, model.AuditEntry{
+			ID:           dbEntry.ID,
+			ActorID:      nullInt32ToPtr(dbEntry.ActorID),
+			Action:       dbEntry.Action,
+			TargetUserID: nullInt32ToPtr(dbEntry.TargetUserID),
+			Details:      dbEntry.Details,
+			IP:           dbEntry.Ip,
+			Status:       dbEntry.Status,
+			CreatedAt:    dbEntry.CreatedAt,
+		})
+	}
+	if len(log.Entries) > int(page.Limit) {
+		log.Entries = log.Entries[:page.Limit]
+		log.NextCursor = strconv.Itoa(int(log.Entries[len(log.Entries)-1].ID))
+	}
+	return log, nil
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"sync"
+	"time"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 	_ "github.com/joho/godotenv/autoload"
@@ -35,6 +37,10 @@ type Config struct {
 type Repository struct {
 	Db      *sql.DB
 	Queries *generated.Queries
+	// RequireVerifiedEmail keeps notes and notebooks from being shared with
+	// users who haven't verified their email. Notes shared with them are
+	// kept as invitations until they do.
+	RequireVerifiedEmail bool
 }
 
 var _ repository.Repository = (*Repository)(nil)
@@ -45,7 +51,15 @@ func (r *Repository) CreateUser(ctx context.Context, user model.UserCreateDTO) (
 		return nil, fmt.Errorf("failed to hash password: %w", err)
 	}
 
-	dbUser, err := r.Queries.CreateUser(ctx, generated.CreateUserParams{
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	qtx := r.Queries.WithTx(tx)
+
+	dbUser, err := qtx.CreateUser(ctx, generated.CreateUserParams{
 		Username:     user.Username,
 		Email:        user.Email,
 		PasswordHash: string(hash),
@@ -54,71 +68,164 @@ func (r *Repository) CreateUser(ctx context.Context, user model.UserCreateDTO) (
 		return nil, err
 	}
 
+	// notes shared with the email before it signed up, unless the email
+	// has to be verified first
+	if !r.RequireVerifiedEmail {
+		err = qtx.AcceptShareInvitations(ctx, generated.AcceptShareInvitationsParams{
+			Email:  dbUser.Email,
+			UserID: dbUser.ID,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
 	return dbUserToUser(dbUser), nil
 }
 
 func dbUserToUser(dbUser generated.User) *model.User {
-	return &model.User{
-		ID:           dbUser.ID,
-		Username:     dbUser.Username,
-		Email:        dbUser.Email,
-		PasswordHash: dbUser.PasswordHash,
-		CreatedAt:    dbUser.CreatedAt.Time,
+	user := &model.User{
+		ID:             dbUser.ID,
+		Username:       dbUser.Username,
+		Email:          dbUser.Email,
+		PasswordHash:   dbUser.PasswordHash,
+		CreatedAt:      dbUser.CreatedAt.Time,
+		Role:           dbUser.Role,
+		SearchLanguage: dbUser.SearchLanguage,
 	}
+	if dbUser.EmailVerifiedAt.Valid {
+		user.EmailVerifiedAt = &dbUser.EmailVerifiedAt.Time
+	}
+	if dbUser.DisabledAt.Valid {
+		user.DisabledAt = &dbUser.DisabledAt.Time
+	}
+	return user
 }
 
 func dbNoteToNote(dbNote generated.Note) *model.Note {
-	return &model.Note{
+	note := &model.Note{
 		ID:        dbNote.ID,
 		UserID:    dbNote.UserID,
 		Title:     dbNote.Title,
 		Content:   dbNote.Content,
 		CreatedAt: dbNote.CreatedAt,
 		UpdatedAt: dbNote.UpdatedAt,
+		Tags:      []string{},
+		Language:  dbNote.Language,
+	}
+	if dbNote.DeletedAt.Valid {
+		note.DeletedAt = &dbNote.DeletedAt.Time
 	}
+	note.NotebookID = nullInt32ToPtr(dbNote.NotebookID)
+	return note
 }
 
+func nullInt32ToPtr(n sql.NullInt32) *int32 {
+	if !n.Valid {
+		return nil
+	}
+	return &n.Int32
+}
+
+func ptrToNullInt32(p *int32) sql.NullInt32 {
+	if p == nil {
+		return sql.NullInt32{}
+	}
+	return sql.NullInt32{Int32: *p, Valid: true}
+}
+
+func dbNoteRevisionToNoteRevision(dbRevision generated.NoteRevision) *model.NoteRevision {
+	return &model.NoteRevision{
+		NoteID:    dbRevision.NoteID,
+		Revision:  dbRevision.Revision,
+		Title:     dbRevision.Title,
+		Content:   dbRevision.Content,
+		CreatedAt: dbRevision.CreatedAt,
+	}
+}
+
+// dummyPasswordHash is checked against when no user has the email, so the
+// response takes as long as for a wrong password
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+	return hash
+})
+
 func (r *Repository) GetUserByEmailAndPassword(ctx context.Context, login model.LogInDTO) (*model.User, error) {
 	dbUser, err := r.Queries.GetUserByEmail(ctx, login.Email)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, repository.ErrNotFound
+			_ = bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(login.Password))
+			return nil, repository.ErrInvalidPassword
 		}
 		return nil, err
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(dbUser.PasswordHash), []byte(login.Password)); err != nil {
-		return nil, err
+		return nil, repository.ErrInvalidPassword
 	}
 	return dbUserToUser(dbUser), nil
 }
 
 func (r *Repository) CreateNote(ctx context.Context, note model.NoteDTO) (*model.Note, error) {
-	dbNote, err := r.Queries.CreatNote(ctx, generated.CreatNoteParams{
-		UserID:  note.UserID,
-		Title:   note.Title,
-		Content: note.Content,
-	})
+	tx, err := r.Db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback()
+
+	qtx := r.Queries.WithTx(tx)
+
+	if note.NotebookID != nil {
+		_, err := qtx.GetOwnedNotebook(ctx, generated.GetOwnedNotebookParams{
+			ID:     *note.NotebookID,
+			UserID: note.UserID,
+		})
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return nil, repository.ErrNotFound
+			}
+			return nil, err
+		}
+	}
 
-	return dbNoteToNote(dbNote), nil
-}
-
-func (r *Repository) ListNotesByUserID(ctx context.Context, userID int32) ([]model.Note, error) {
-	dbNotes, err := r.Queries.ListNotesByUserID(ctx, userID)
+	dbNote, err := qtx.CreatNote(ctx, generated.CreatNoteParams{
+		UserID:     note.UserID,
+		Title:      note.Title,
+		Content:    note.Content,
+		NotebookID: ptrToNullInt32(note.NotebookID),
+		Language:   sql.NullString{String: note.Language, Valid: note.Language != ""},
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	notes := make([]model.Note, 0, len(dbNotes))
+	created := dbNoteToNote(dbNote)
+	if len(note.Tags) > 0 {
+		if err := setNoteTags(ctx, qtx, dbNote.UserID, dbNote.ID, note.Tags); err != nil {
+			return nil, err
+		}
+		if created.Tags, err = listNoteTags(ctx, qtx, dbNote.ID); err != nil {
+			return nil, err
+		}
+	}
 
-	for _, dbNote := range dbNotes {
-		notes = append(notes, *dbNoteToNote(dbNote))
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (r *Repository) ListNotesByUserID(ctx context.Context, userID int32, filter model.NoteFilter, page model.NotePage) (*model.NoteList, error) {
+	q := newNoteQuery(userID)
+	if err := q.filter(filter); err != nil {
+		return nil, err
 	}
 
-	return notes, nil
+	return r.listNotes(ctx, userID, q, page)
 }
 
 func (r *Repository) GetNoteByUserID(ctx context.Context, noteID int32, userID int32) (*model.Note, error) {
@@ -133,16 +240,59 @@ func (r *Repository) GetNoteByUserID(ctx context.Context, noteID int32, userID i
 		return nil, err
 	}
 
-	return dbNoteToNote(dbNote), nil
+	note := dbNoteToNote(dbNote)
+	if note.Tags, err = listNoteTags(ctx, r.Queries, note.ID); err != nil {
+		return nil, err
+	}
+
+	return note, nil
 }
 
 func (r *Repository) UpdateNote(ctx context.Context, noteID int32, note model.NoteDTO) (*model.Note, error) {
-	fmt.Println("updating note", noteID, note)
-	dbNote, err := r.Queries.UpdateNote(ctx, generated.UpdateNoteParams{
-		ID:      noteID,
-		UserID:  note.UserID,
-		Title:   note.Title,
-		Content: note.Content,
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	updated, err := updateNote(ctx, r.Queries.WithTx(tx), noteID, note)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// updateNote saves the current title and content of the note as a new
+// revision before overwriting them, so that every edit can be restored later.
+// The tags of the note are only replaced when note.Tags is not nil. The note
+// row is locked first so that concurrent edits number their revisions one
+// after the other, which is why q must be bound to a transaction.
+func updateNote(ctx context.Context, q *generated.Queries, noteID int32, note model.NoteDTO) (*model.Note, error) {
+	if err := q.LockNote(ctx, noteID); err != nil {
+		return nil, err
+	}
+
+	_, err := q.CreateNoteRevision(ctx, generated.CreateNoteRevisionParams{
+		NoteID: noteID,
+		UserID: note.UserID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, noteAccessError(ctx, q, noteID, note.UserID)
+		}
+		return nil, err
+	}
+
+	dbNote, err := q.UpdateNote(ctx, generated.UpdateNoteParams{
+		ID:       noteID,
+		UserID:   note.UserID,
+		Title:    note.Title,
+		Content:  note.Content,
+		Language: sql.NullString{String: note.Language, Valid: note.Language != ""},
 	})
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -150,7 +300,34 @@ func (r *Repository) UpdateNote(ctx context.Context, noteID int32, note model.No
 		}
 		return nil, err
 	}
-	return dbNoteToNote(dbNote), nil
+
+	if note.Tags != nil {
+		if err := setNoteTags(ctx, q, dbNote.UserID, dbNote.ID, note.Tags); err != nil {
+			return nil, err
+		}
+	}
+
+	updated := dbNoteToNote(dbNote)
+	if updated.Tags, err = listNoteTags(ctx, q, dbNote.ID); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// noteAccessError tells apart a note the user cannot see from one that is
+// shared with them without the editor role
+func noteAccessError(ctx context.Context, q *generated.Queries, noteID, userID int32) error {
+	_, err := q.GetNoteByUserID(ctx, generated.GetNoteByUserIDParams{
+		ID:     noteID,
+		UserID: userID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return repository.ErrNotFound
+		}
+		return err
+	}
+	return repository.ErrForbidden
 }
 
 func (r *Repository) DeleteNote(ctx context.Context, noteID, userID int32) error {
@@ -170,24 +347,196 @@ func (r *Repository) DeleteNote(ctx context.Context, noteID, userID int32) error
 	return err
 }
 
-func (r *Repository) ShareNote(ctx context.Context, share model.NoteShareDTO) error {
-	_, err := r.Queries.ShareNote(ctx, generated.ShareNoteParams{
+func (r *Repository) ListDeletedNotes(ctx context.Context, userID int32) ([]model.Note, error) {
+	dbNotes, err := r.Queries.ListDeletedNotesByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	notes := make([]model.Note, 0, len(dbNotes))
+
+	for _, dbNote := range dbNotes {
+		notes = append(notes, *dbNoteToNote(dbNote))
+	}
+
+	if err := r.attachTags(ctx, notes); err != nil {
+		return nil, err
+	}
+
+	return notes, nil
+}
+
+func (r *Repository) RestoreNote(ctx context.Context, noteID, userID int32) (*model.Note, error) {
+	dbNote, err := r.Queries.RestoreNote(ctx, generated.RestoreNoteParams{
+		ID:     noteID,
+		UserID: userID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+
+	note := dbNoteToNote(dbNote)
+	if note.Tags, err = listNoteTags(ctx, r.Queries, note.ID); err != nil {
+		return nil, err
+	}
+
+	return note, nil
+}
+
+func (r *Repository) MoveNote(ctx context.Context, noteID, userID int32, notebookID *int32) (*model.Note, error) {
+	if notebookID != nil {
+		_, err := r.Queries.GetOwnedNotebook(ctx, generated.GetOwnedNotebookParams{
+			ID:     *notebookID,
+			UserID: userID,
+		})
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return nil, repository.ErrNotFound
+			}
+			return nil, err
+		}
+	}
+
+	dbNote, err := r.Queries.MoveNote(ctx, generated.MoveNoteParams{
+		NotebookID: ptrToNullInt32(notebookID),
+		ID:         noteID,
+		UserID:     userID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+
+	note := dbNoteToNote(dbNote)
+	if note.Tags, err = listNoteTags(ctx, r.Queries, note.ID); err != nil {
+		return nil, err
+	}
+
+	return note, nil
+}
+
+// PurgeDeletedNotes permanently removes the notes that have been in the
+// trash for longer than retention and returns how many were removed.
+func (r *Repository) PurgeDeletedNotes(ctx context.Context, retention time.Duration) (int64, error) {
+	return r.Queries.PurgeDeletedNotes(ctx, retention.Seconds())
+}
+
+func (r *Repository) ShareNote(ctx context.Context, share model.NoteShareDTO) (*model.ShareInvitation, error) {
+	dbUser, err := r.Queries.GetUserByEmail(ctx, share.SharedWith)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return r.inviteToNote(ctx, share)
+		}
+		return nil, err
+	}
+
+	if r.RequireVerifiedEmail && !dbUser.EmailVerifiedAt.Valid {
+		return r.inviteToNote(ctx, share)
+	}
+
+	_, err = r.Queries.ShareNote(ctx, generated.ShareNoteParams{
 		Noteid:          share.NoteID,
 		Userid:          share.UserID,
 		Sharedwithemail: share.SharedWith,
+		Role:            share.Role,
 	})
 
 	if err == sql.ErrNoRows {
+		return nil, repository.ErrNotFound
+	}
+
+	return nil, err
+}
+
+func (r *Repository) ListNoteShares(ctx context.Context, noteID, userID int32) ([]model.NoteShare, error) {
+	if _, err := r.GetNoteByUserID(ctx, noteID, userID); err != nil {
+		return nil, err
+	}
+
+	dbShares, err := r.Queries.ListNoteShares(ctx, noteID)
+	if err != nil {
+		return nil, err
+	}
+
+	shares := make([]model.NoteShare, 0, len(dbShares))
+
+	for _, dbShare := range dbShares {
+		shares = append(shares, model.NoteShare{
+			UserID:   dbShare.ID,
+			Username: dbShare.Username,
+			Email:    dbShare.Email,
+			Role:     dbShare.Role,
+		})
+	}
+
+	return shares, nil
+}
+
+func (r *Repository) RevokeNoteShare(ctx context.Context, noteID, userID, sharedWithUserID int32) error {
+	revoked, err := r.Queries.RevokeNoteShare(ctx, generated.RevokeNoteShareParams{
+		NoteID:           noteID,
+		UserID:           userID,
+		SharedWithUserID: sharedWithUserID,
+	})
+	if err != nil {
+		return err
+	}
+
+	if revoked == 0 {
 		return repository.ErrNotFound
 	}
 
-	return err
+	return nil
+}
+
+func (r *Repository) LeaveNote(ctx context.Context, noteID, userID int32) error {
+	left, err := r.Queries.LeaveNote(ctx, generated.LeaveNoteParams{
+		NoteID:           noteID,
+		SharedWithUserID: userID,
+	})
+	if err != nil {
+		return err
+	}
+
+	if left == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *Repository) ListNoteRevisions(ctx context.Context, noteID, userID int32) ([]model.NoteRevision, error) {
+	if _, err := r.GetNoteByUserID(ctx, noteID, userID); err != nil {
+		return nil, err
+	}
+
+	dbRevisions, err := r.Queries.ListNoteRevisions(ctx, noteID)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := make([]model.NoteRevision, 0, len(dbRevisions))
+
+	for _, dbRevision := range dbRevisions {
+		revisions = append(revisions, *dbNoteRevisionToNoteRevision(dbRevision))
+	}
+
+	return revisions, nil
 }
 
-func (r *Repository) SearchNotes(ctx context.Context, id int32, query string) ([]model.Note, error) {
-	dbNotes, err := r.Queries.SearchNotes(ctx, generated.SearchNotesParams{
-		UserID:         id,
-		PlaintoTsquery: query,
+func (r *Repository) GetNoteRevision(ctx context.Context, noteID, userID, revision int32) (*model.NoteRevision, error) {
+	if _, err := r.GetNoteByUserID(ctx, noteID, userID); err != nil {
+		return nil, err
+	}
+
+	dbRevision, err := r.Queries.GetNoteRevision(ctx, generated.GetNoteRevisionParams{
+		NoteID:   noteID,
+		Revision: revision,
 	})
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -196,13 +545,44 @@ func (r *Repository) SearchNotes(ctx context.Context, id int32, query string) ([
 		return nil, err
 	}
 
-	notes := make([]model.Note, 0, len(dbNotes))
+	return dbNoteRevisionToNoteRevision(dbRevision), nil
+}
 
-	for _, dbNote := range dbNotes {
-		notes = append(notes, *dbNoteToNote(dbNote))
+// RestoreNoteRevision overwrites the note with the given revision. The state
+// being replaced is itself saved as a new revision, so a restore can be undone.
+func (r *Repository) RestoreNoteRevision(ctx context.Context, noteID, userID, revision int32) (*model.Note, error) {
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
 	}
+	defer tx.Rollback()
 
-	return notes, nil
+	qtx := r.Queries.WithTx(tx)
+
+	dbRevision, err := qtx.GetNoteRevision(ctx, generated.GetNoteRevisionParams{
+		NoteID:   noteID,
+		Revision: revision,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+
+	note, err := updateNote(ctx, qtx, noteID, model.NoteDTO{
+		UserID:  userID,
+		Title:   dbRevision.Title,
+		Content: dbRevision.Content,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return note, nil
 }
 
 func New() *Repository {
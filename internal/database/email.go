@@ -0,0 +1,125 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"notes/internal/database/generated"
+	"notes/internal/model"
+	"notes/internal/repository"
+)
+
+// purpose of the user tokens mailed to verify an email address
+const purposeEmailVerification = "email_verification"
+
+func (r *Repository) CreateEmailVerificationToken(ctx context.Context, email string, ttl time.Duration) (string, error) {
+	dbUser, err := r.Queries.GetUserByEmail(ctx, email)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", repository.ErrNotFound
+		}
+		return "", err
+	}
+
+	if dbUser.EmailVerifiedAt.Valid {
+		return "", repository.ErrNotFound
+	}
+
+	return r.createEmailVerificationToken(ctx, dbUser.ID, dbUser.Email, ttl)
+}
+
+func (r *Repository) CreateEmailChangeToken(ctx context.Context, userID int32, password, email string, ttl time.Duration) (string, error) {
+	if _, err := getUserWithPassword(ctx, r.Queries, userID, password); err != nil {
+		return "", err
+	}
+
+	dbUser, err := r.Queries.GetUserByEmail(ctx, email)
+	if err != nil && err != sql.ErrNoRows {
+		return "", err
+	}
+	if err == nil && dbUser.ID != userID {
+		return "", repository.ErrAlreadyExists
+	}
+
+	return r.createEmailVerificationToken(ctx, userID, email, ttl)
+}
+
+// createEmailVerificationToken returns a token that sets the email of the
+// user and marks it verified
+func (r *Repository) createEmailVerificationToken(ctx context.Context, userID int32, email string, ttl time.Duration) (string, error) {
+	token, err := newToken()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = r.Queries.CreateUserToken(ctx, generated.CreateUserTokenParams{
+		UserID:     userID,
+		Purpose:    purposeEmailVerification,
+		TokenHash:  hashToken(token),
+		Email:      sql.NullString{String: email, Valid: true},
+		TtlSeconds: ttl.Seconds(),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (r *Repository) VerifyEmail(ctx context.Context, token string) (*model.User, error) {
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	qtx := r.Queries.WithTx(tx)
+
+	dbToken, err := qtx.GetUserTokenByHash(ctx, generated.GetUserTokenByHashParams{
+		TokenHash: hashToken(token),
+		Purpose:   purposeEmailVerification,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	used, err := qtx.UseUserTokens(ctx, generated.UseUserTokensParams{
+		UserID:  dbToken.UserID,
+		Purpose: purposeEmailVerification,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if used == 0 {
+		return nil, repository.ErrInvalidToken
+	}
+
+	// the email may have been taken since the token was sent
+	dbUser, err := qtx.VerifyUserEmail(ctx, generated.VerifyUserEmailParams{
+		Email: dbToken.Email.String,
+		ID:    dbToken.UserID,
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, repository.ErrAlreadyExists
+		}
+		return nil, err
+	}
+
+	// notes shared with the email while it wasn't verified
+	err = qtx.AcceptShareInvitations(ctx, generated.AcceptShareInvitationsParams{
+		Email:  dbUser.Email,
+		UserID: dbUser.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return dbUserToUser(dbUser), nil
+}
@@ -9,24 +9,177 @@ import (
 	"time"
 )
 
+type AuditLog struct {
+	ID           int32
+	ActorID      sql.NullInt32
+	Action       string
+	TargetUserID sql.NullInt32
+	Details      string
+	Ip           string
+	CreatedAt    time.Time
+	Status       int32
+}
+
+type LoginFailure struct {
+	Scope         string
+	Key           string
+	Failures      int32
+	LastFailureAt time.Time
+	LockedUntil   sql.NullTime
+}
+
 type Note struct {
-	ID        int32
-	UserID    int32
+	ID         int32
+	UserID     int32
+	Title      string
+	Content    string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	DeletedAt  sql.NullTime
+	NotebookID sql.NullInt32
+	Language   string
+}
+
+type NoteRevision struct {
+	NoteID    int32
+	Revision  int32
 	Title     string
 	Content   string
 	CreatedAt time.Time
+}
+
+type NoteTag struct {
+	NoteID int32
+	TagID  int32
+}
+
+type Notebook struct {
+	ID        int32
+	UserID    int32
+	ParentID  sql.NullInt32
+	Name      string
+	CreatedAt time.Time
 	UpdatedAt time.Time
 }
 
+type NotebookAccess struct {
+	NotebookID int32
+	UserID     int32
+}
+
+type PersonalAccessToken struct {
+	ID         int32
+	UserID     int32
+	Name       string
+	TokenHash  string
+	Scopes     string
+	ExpiresAt  sql.NullTime
+	LastUsedAt sql.NullTime
+	CreatedAt  time.Time
+}
+
+type PublicLink struct {
+	ID           int32
+	NoteID       int32
+	Token        string
+	PasswordHash sql.NullString
+	ExpiresAt    sql.NullTime
+	CreatedAt    time.Time
+}
+
+type RecoveryCode struct {
+	ID        int32
+	UserID    int32
+	CodeHash  string
+	UsedAt    sql.NullTime
+	CreatedAt time.Time
+}
+
+type RefreshToken struct {
+	ID            int32
+	UserID        int32
+	FamilyID      string
+	TokenHash     string
+	AccessTokenID string
+	ExpiresAt     time.Time
+	UsedAt        sql.NullTime
+	RevokedAt     sql.NullTime
+	CreatedAt     time.Time
+}
+
+type SavedSearch struct {
+	ID           int32
+	UserID       int32
+	Name         string
+	Query        string
+	Mode         string
+	Threshold    float64
+	Language     sql.NullString
+	CreatedAt    time.Time
+	LastViewedAt sql.NullTime
+}
+
+type ShareInvitation struct {
+	ID        int32
+	NoteID    int32
+	Email     string
+	Role      string
+	CreatedAt time.Time
+}
+
 type SharedNote struct {
 	NoteID           int32
 	SharedWithUserID int32
+	Role             string
+}
+
+type SharedNotebook struct {
+	NotebookID       int32
+	SharedWithUserID int32
+}
+
+type Tag struct {
+	ID     int32
+	UserID int32
+	Name   string
 }
 
 type User struct {
-	ID           int32
-	Username     string
-	Email        string
-	PasswordHash string
-	CreatedAt    sql.NullTime
+	ID              int32
+	Username        string
+	Email           string
+	PasswordHash    string
+	CreatedAt       sql.NullTime
+	EmailVerifiedAt sql.NullTime
+	Role            string
+	DisabledAt      sql.NullTime
+	SearchLanguage  string
+}
+
+type UserIdentity struct {
+	ID        int32
+	UserID    int32
+	Issuer    string
+	Subject   string
+	Email     sql.NullString
+	CreatedAt time.Time
+}
+
+type UserToken struct {
+	ID        int32
+	UserID    int32
+	Purpose   string
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+	CreatedAt time.Time
+	Email     sql.NullString
+}
+
+type UserTotp struct {
+	UserID      int32
+	Secret      string
+	LastCounter sql.NullInt64
+	EnabledAt   sql.NullTime
+	CreatedAt   time.Time
 }
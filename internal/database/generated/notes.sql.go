@@ -7,22 +7,31 @@ package generated
 
 import (
 	"context"
+	"database/sql"
 )
 
 const creatNote = `-- name: CreatNote :one
-INSERT INTO notes (user_id, title, content)
-VALUES ( $1, $2, $3 )
-RETURNING id, user_id, title, content, created_at, updated_at
+INSERT INTO notes (user_id, title, content, notebook_id, language)
+VALUES ( $1, $2, $3, $4, COALESCE($5::regconfig, (SELECT u.search_language FROM users u WHERE u.id = $1)) )
+RETURNING id, user_id, title, content, created_at, updated_at, deleted_at, notebook_id, language
 `
 
 type CreatNoteParams struct {
-	UserID  int32
-	Title   string
-	Content string
+	UserID     int32
+	Title      string
+	Content    string
+	NotebookID sql.NullInt32
+	Language   sql.NullString
 }
 
 func (q *Queries) CreatNote(ctx context.Context, arg CreatNoteParams) (Note, error) {
-	row := q.db.QueryRowContext(ctx, creatNote, arg.UserID, arg.Title, arg.Content)
+	row := q.db.QueryRowContext(ctx, creatNote,
+		arg.UserID,
+		arg.Title,
+		arg.Content,
+		arg.NotebookID,
+		arg.Language,
+	)
 	var i Note
 	err := row.Scan(
 		&i.ID,
@@ -31,14 +40,45 @@ func (q *Queries) CreatNote(ctx context.Context, arg CreatNoteParams) (Note, err
 		&i.Content,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.NotebookID,
+		&i.Language,
+	)
+	return i, err
+}
+
+const createNoteRevision = `-- name: CreateNoteRevision :one
+INSERT INTO note_revisions (note_id, revision, title, content, created_at)
+SELECT n.id, COALESCE((SELECT MAX(r.revision) FROM note_revisions r WHERE r.note_id = n.id), 0) + 1, n.title, n.content, n.updated_at
+FROM notes n
+WHERE n.id = $1 AND n.deleted_at IS NULL
+    AND (n.user_id = $2 OR EXISTS (SELECT 1 FROM shared_notes sn WHERE sn.note_id = n.id AND sn.shared_with_user_id = $2 AND sn.role = 'editor'))
+RETURNING note_id, revision, title, content, created_at
+`
+
+type CreateNoteRevisionParams struct {
+	NoteID int32
+	UserID int32
+}
+
+func (q *Queries) CreateNoteRevision(ctx context.Context, arg CreateNoteRevisionParams) (NoteRevision, error) {
+	row := q.db.QueryRowContext(ctx, createNoteRevision, arg.NoteID, arg.UserID)
+	var i NoteRevision
+	err := row.Scan(
+		&i.NoteID,
+		&i.Revision,
+		&i.Title,
+		&i.Content,
+		&i.CreatedAt,
 	)
 	return i, err
 }
 
 const deleteNote = `-- name: DeleteNote :one
-DELETE FROM notes
-WHERE id = $1 AND user_id = $2
-RETURNING id, user_id, title, content, created_at, updated_at
+UPDATE notes
+SET deleted_at = now()
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+RETURNING id, user_id, title, content, created_at, updated_at, deleted_at, notebook_id, language
 `
 
 type DeleteNoteParams struct {
@@ -56,15 +96,18 @@ func (q *Queries) DeleteNote(ctx context.Context, arg DeleteNoteParams) (Note, e
 		&i.Content,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.NotebookID,
+		&i.Language,
 	)
 	return i, err
 }
 
 const getNoteByUserID = `-- name: GetNoteByUserID :one
-SELECT n.id, n.user_id, n.title, n.content, n.created_at, n.updated_at
+SELECT n.id, n.user_id, n.title, n.content, n.created_at, n.updated_at, n.deleted_at, n.notebook_id, n.language
 FROM notes n
 LEFT JOIN shared_notes sn ON n.id = sn.note_id
-WHERE (n.id = $1) AND (n.user_id = $2 OR sn.shared_with_user_id = $2)
+WHERE (n.id = $1) AND (n.user_id = $2 OR sn.shared_with_user_id = $2 OR n.notebook_id IN (SELECT na.notebook_id FROM notebook_access na WHERE na.user_id = $2)) AND n.deleted_at IS NULL
 `
 
 type GetNoteByUserIDParams struct {
@@ -82,20 +125,62 @@ func (q *Queries) GetNoteByUserID(ctx context.Context, arg GetNoteByUserIDParams
 		&i.Content,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.NotebookID,
+		&i.Language,
 	)
 	return i, err
 }
 
-const listNotesByUserID = `-- name: ListNotesByUserID :many
-SELECT n.id, n.user_id, n.title, n.content, n.created_at, n.updated_at
-FROM notes n
-LEFT JOIN shared_notes sn ON n.id = sn.note_id
-WHERE n.user_id = $1 OR sn.shared_with_user_id = $1
-ORDER BY n.created_at DESC
+const getNoteRevision = `-- name: GetNoteRevision :one
+SELECT note_id, revision, title, content, created_at FROM note_revisions
+WHERE note_id = $1 AND revision = $2
+`
+
+type GetNoteRevisionParams struct {
+	NoteID   int32
+	Revision int32
+}
+
+func (q *Queries) GetNoteRevision(ctx context.Context, arg GetNoteRevisionParams) (NoteRevision, error) {
+	row := q.db.QueryRowContext(ctx, getNoteRevision, arg.NoteID, arg.Revision)
+	var i NoteRevision
+	err := row.Scan(
+		&i.NoteID,
+		&i.Revision,
+		&i.Title,
+		&i.Content,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const leaveNote = `-- name: LeaveNote :execrows
+DELETE FROM shared_notes
+WHERE note_id = $1 AND shared_with_user_id = $2
 `
 
-func (q *Queries) ListNotesByUserID(ctx context.Context, userID int32) ([]Note, error) {
-	rows, err := q.db.QueryContext(ctx, listNotesByUserID, userID)
+type LeaveNoteParams struct {
+	NoteID           int32
+	SharedWithUserID int32
+}
+
+func (q *Queries) LeaveNote(ctx context.Context, arg LeaveNoteParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, leaveNote, arg.NoteID, arg.SharedWithUserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const listDeletedNotesByUserID = `-- name: ListDeletedNotesByUserID :many
+SELECT id, user_id, title, content, created_at, updated_at, deleted_at, notebook_id, language FROM notes
+WHERE user_id = $1 AND deleted_at IS NOT NULL
+ORDER BY deleted_at DESC
+`
+
+func (q *Queries) ListDeletedNotesByUserID(ctx context.Context, userID int32) ([]Note, error) {
+	rows, err := q.db.QueryContext(ctx, listDeletedNotesByUserID, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -110,6 +195,9 @@ func (q *Queries) ListNotesByUserID(ctx context.Context, userID int32) ([]Note,
 			&i.Content,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.NotebookID,
+			&i.Language,
 		); err != nil {
 			return nil, err
 		}
@@ -124,34 +212,27 @@ func (q *Queries) ListNotesByUserID(ctx context.Context, userID int32) ([]Note,
 	return items, nil
 }
 
-const searchNotes = `-- name: SearchNotes :many
-SELECT n.id, n.user_id, n.title, n.content, n.created_at, n.updated_at
-FROM notes n
-LEFT JOIN shared_notes sn ON n.id = sn.note_id
-WHERE (n.user_id = $1 OR sn.shared_with_user_id = $1) AND to_tsvector('english', n.content) @@ plainto_tsquery('english', $2)
+const listNoteRevisions = `-- name: ListNoteRevisions :many
+SELECT note_id, revision, title, content, created_at FROM note_revisions
+WHERE note_id = $1
+ORDER BY revision DESC
 `
 
-type SearchNotesParams struct {
-	UserID         int32
-	PlaintoTsquery string
-}
-
-func (q *Queries) SearchNotes(ctx context.Context, arg SearchNotesParams) ([]Note, error) {
-	rows, err := q.db.QueryContext(ctx, searchNotes, arg.UserID, arg.PlaintoTsquery)
+func (q *Queries) ListNoteRevisions(ctx context.Context, noteID int32) ([]NoteRevision, error) {
+	rows, err := q.db.QueryContext(ctx, listNoteRevisions, noteID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Note
+	var items []NoteRevision
 	for rows.Next() {
-		var i Note
+		var i NoteRevision
 		if err := rows.Scan(
-			&i.ID,
-			&i.UserID,
+			&i.NoteID,
+			&i.Revision,
 			&i.Title,
 			&i.Content,
 			&i.CreatedAt,
-			&i.UpdatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -166,46 +247,202 @@ func (q *Queries) SearchNotes(ctx context.Context, arg SearchNotesParams) ([]Not
 	return items, nil
 }
 
+const listNoteShares = `-- name: ListNoteShares :many
+SELECT u.id, u.username, u.email, sn.role
+FROM shared_notes sn
+JOIN users u ON u.id = sn.shared_with_user_id
+WHERE sn.note_id = $1
+ORDER BY u.username
+`
+
+type ListNoteSharesRow struct {
+	ID       int32
+	Username string
+	Email    string
+	Role     string
+}
+
+func (q *Queries) ListNoteShares(ctx context.Context, noteID int32) ([]ListNoteSharesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listNoteShares, noteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListNoteSharesRow
+	for rows.Next() {
+		var i ListNoteSharesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Username,
+			&i.Email,
+			&i.Role,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockNote = `-- name: LockNote :exec
+SELECT id FROM notes
+WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) LockNote(ctx context.Context, id int32) error {
+	_, err := q.db.ExecContext(ctx, lockNote, id)
+	return err
+}
+
+const moveNote = `-- name: MoveNote :one
+UPDATE notes
+SET notebook_id = $1
+WHERE id = $2 AND user_id = $3 AND deleted_at IS NULL
+RETURNING id, user_id, title, content, created_at, updated_at, deleted_at, notebook_id, language
+`
+
+type MoveNoteParams struct {
+	NotebookID sql.NullInt32
+	ID         int32
+	UserID     int32
+}
+
+func (q *Queries) MoveNote(ctx context.Context, arg MoveNoteParams) (Note, error) {
+	row := q.db.QueryRowContext(ctx, moveNote, arg.NotebookID, arg.ID, arg.UserID)
+	var i Note
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Title,
+		&i.Content,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.NotebookID,
+		&i.Language,
+	)
+	return i, err
+}
+
+const purgeDeletedNotes = `-- name: PurgeDeletedNotes :execrows
+DELETE FROM notes
+WHERE deleted_at < now() - make_interval(secs => $1::float8)
+`
+
+func (q *Queries) PurgeDeletedNotes(ctx context.Context, retentionSeconds float64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, purgeDeletedNotes, retentionSeconds)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const restoreNote = `-- name: RestoreNote :one
+UPDATE notes
+SET deleted_at = NULL
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL
+RETURNING id, user_id, title, content, created_at, updated_at, deleted_at, notebook_id, language
+`
+
+type RestoreNoteParams struct {
+	ID     int32
+	UserID int32
+}
+
+func (q *Queries) RestoreNote(ctx context.Context, arg RestoreNoteParams) (Note, error) {
+	row := q.db.QueryRowContext(ctx, restoreNote, arg.ID, arg.UserID)
+	var i Note
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Title,
+		&i.Content,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.NotebookID,
+		&i.Language,
+	)
+	return i, err
+}
+
+const revokeNoteShare = `-- name: RevokeNoteShare :execrows
+DELETE FROM shared_notes sn
+WHERE sn.note_id = $1 AND sn.shared_with_user_id = $2
+    AND EXISTS (SELECT 1 FROM notes n WHERE n.id = sn.note_id AND n.user_id = $3)
+`
+
+type RevokeNoteShareParams struct {
+	NoteID           int32
+	SharedWithUserID int32
+	UserID           int32
+}
+
+func (q *Queries) RevokeNoteShare(ctx context.Context, arg RevokeNoteShareParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeNoteShare, arg.NoteID, arg.SharedWithUserID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const shareNote = `-- name: ShareNote :one
-INSERT INTO shared_notes (note_id, shared_with_user_id)
-SELECT $1, users.id
+INSERT INTO shared_notes (note_id, shared_with_user_id, role)
+SELECT $1, users.id, $2
 FROM users
-WHERE email = $2 AND EXISTS (SELECT 1 FROM notes WHERE id = $1 AND user_id = $3)
-RETURNING note_id, shared_with_user_id
+WHERE email = $3 AND EXISTS (SELECT 1 FROM notes WHERE id = $1 AND user_id = $4 AND deleted_at IS NULL)
+ON CONFLICT (note_id, shared_with_user_id) DO UPDATE SET role = EXCLUDED.role
+RETURNING note_id, shared_with_user_id, role
 `
 
 type ShareNoteParams struct {
 	Noteid          int32
+	Role            string
 	Sharedwithemail string
 	Userid          int32
 }
 
 func (q *Queries) ShareNote(ctx context.Context, arg ShareNoteParams) (SharedNote, error) {
-	row := q.db.QueryRowContext(ctx, shareNote, arg.Noteid, arg.Sharedwithemail, arg.Userid)
+	row := q.db.QueryRowContext(ctx, shareNote,
+		arg.Noteid,
+		arg.Role,
+		arg.Sharedwithemail,
+		arg.Userid,
+	)
 	var i SharedNote
-	err := row.Scan(&i.NoteID, &i.SharedWithUserID)
+	err := row.Scan(&i.NoteID, &i.SharedWithUserID, &i.Role)
 	return i, err
 }
 
 const updateNote = `-- name: UpdateNote :one
 UPDATE notes
-SET title = $2, content = $3, updated_at = now()
-WHERE id = $1 AND user_id = $4
-RETURNING id, user_id, title, content, created_at, updated_at
+SET title = $1, content = $2, language = COALESCE($3::regconfig, language), updated_at = now()
+WHERE id = $4 AND deleted_at IS NULL
+    AND (user_id = $5 OR EXISTS (SELECT 1 FROM shared_notes sn WHERE sn.note_id = notes.id AND sn.shared_with_user_id = $5 AND sn.role = 'editor'))
+RETURNING id, user_id, title, content, created_at, updated_at, deleted_at, notebook_id, language
 `
 
 type UpdateNoteParams struct {
-	ID      int32
-	Title   string
-	Content string
-	UserID  int32
+	Title    string
+	Content  string
+	Language sql.NullString
+	ID       int32
+	UserID   int32
 }
 
 func (q *Queries) UpdateNote(ctx context.Context, arg UpdateNoteParams) (Note, error) {
 	row := q.db.QueryRowContext(ctx, updateNote,
-		arg.ID,
 		arg.Title,
 		arg.Content,
+		arg.Language,
+		arg.ID,
 		arg.UserID,
 	)
 	var i Note
@@ -216,6 +453,9 @@ func (q *Queries) UpdateNote(ctx context.Context, arg UpdateNoteParams) (Note, e
 		&i.Content,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.NotebookID,
+		&i.Language,
 	)
 	return i, err
 }
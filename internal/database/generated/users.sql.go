@@ -7,12 +7,13 @@ package generated
 
 import (
 	"context"
+	"database/sql"
 )
 
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (username, password_hash, email)
 VALUES ($1, $2, $3)
-RETURNING id, username, email, password_hash, created_at
+RETURNING id, username, email, password_hash, created_at, email_verified_at, role, disabled_at, search_language
 `
 
 type CreateUserParams struct {
@@ -30,12 +31,29 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.PasswordHash,
 		&i.CreatedAt,
+		&i.EmailVerifiedAt,
+		&i.Role,
+		&i.DisabledAt,
+		&i.SearchLanguage,
 	)
 	return i, err
 }
 
+const deleteUser = `-- name: DeleteUser :execrows
+DELETE FROM users
+WHERE id = $1
+`
+
+func (q *Queries) DeleteUser(ctx context.Context, id int32) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteUser, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, username, email, password_hash, created_at FROM users
+SELECT id, username, email, password_hash, created_at, email_verified_at, role, disabled_at, search_language FROM users
 WHERE email = $1
 `
 
@@ -48,21 +66,147 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Email,
 		&i.PasswordHash,
 		&i.CreatedAt,
+		&i.EmailVerifiedAt,
+		&i.Role,
+		&i.DisabledAt,
+		&i.SearchLanguage,
 	)
 	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT  FROM users
+SELECT id, username, email, password_hash, created_at, email_verified_at, role, disabled_at, search_language FROM users
 WHERE id = $1
 `
 
-type GetUserByIDRow struct {
+func (q *Queries) GetUserByID(ctx context.Context, id int32) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.PasswordHash,
+		&i.CreatedAt,
+		&i.EmailVerifiedAt,
+		&i.Role,
+		&i.DisabledAt,
+		&i.SearchLanguage,
+	)
+	return i, err
+}
+
+const transferSharedNotes = `-- name: TransferSharedNotes :exec
+WITH heirs AS (
+    SELECT DISTINCT ON (sn.note_id) sn.note_id, sn.shared_with_user_id
+    FROM shared_notes sn
+    JOIN notes n ON n.id = sn.note_id
+    WHERE n.user_id = $1 AND n.deleted_at IS NULL
+    ORDER BY sn.note_id,
+        CASE sn.role WHEN 'editor' THEN 0 WHEN 'commenter' THEN 1 ELSE 2 END,
+        sn.shared_with_user_id
+), transferred AS (
+    UPDATE notes n
+    SET user_id = heirs.shared_with_user_id, notebook_id = NULL
+    FROM heirs
+    WHERE n.id = heirs.note_id
+    RETURNING n.id, n.user_id
+)
+DELETE FROM shared_notes sn
+USING transferred
+WHERE sn.note_id = transferred.id AND sn.shared_with_user_id = transferred.user_id
+`
+
+func (q *Queries) TransferSharedNotes(ctx context.Context, userID int32) error {
+	_, err := q.db.ExecContext(ctx, transferSharedNotes, userID)
+	return err
 }
 
-func (q *Queries) GetUserByID(ctx context.Context, id int32) (GetUserByIDRow, error) {
-	row := q.db.QueryRowContext(ctx, getUserByID, id)
-	var i GetUserByIDRow
-	err := row.Scan()
+const updateProfile = `-- name: UpdateProfile :one
+UPDATE users
+SET username = $1, search_language = COALESCE($2::regconfig, search_language)
+WHERE id = $3
+RETURNING id, username, email, password_hash, created_at, email_verified_at, role, disabled_at, search_language
+`
+
+type UpdateProfileParams struct {
+	Username       string
+	SearchLanguage sql.NullString
+	ID             int32
+}
+
+func (q *Queries) UpdateProfile(ctx context.Context, arg UpdateProfileParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateProfile, arg.Username, arg.SearchLanguage, arg.ID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.PasswordHash,
+		&i.CreatedAt,
+		&i.EmailVerifiedAt,
+		&i.Role,
+		&i.DisabledAt,
+		&i.SearchLanguage,
+	)
+	return i, err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+UPDATE users
+SET password_hash = $1
+WHERE id = $2
+`
+
+type UpdateUserPasswordParams struct {
+	PasswordHash string
+	ID           int32
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserPassword, arg.PasswordHash, arg.ID)
+	return err
+}
+
+const usernameExists = `-- name: UsernameExists :one
+SELECT EXISTS (
+    SELECT 1 FROM users
+    WHERE username = $1
+)
+`
+
+func (q *Queries) UsernameExists(ctx context.Context, username string) (bool, error) {
+	row := q.db.QueryRowContext(ctx, usernameExists, username)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const verifyUserEmail = `-- name: VerifyUserEmail :one
+UPDATE users
+SET email = $1, email_verified_at = now()
+WHERE id = $2
+RETURNING id, username, email, password_hash, created_at, email_verified_at, role, disabled_at, search_language
+`
+
+type VerifyUserEmailParams struct {
+	Email string
+	ID    int32
+}
+
+func (q *Queries) VerifyUserEmail(ctx context.Context, arg VerifyUserEmailParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, verifyUserEmail, arg.Email, arg.ID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.PasswordHash,
+		&i.CreatedAt,
+		&i.EmailVerifiedAt,
+		&i.Role,
+		&i.DisabledAt,
+		&i.SearchLanguage,
+	)
 	return i, err
 }
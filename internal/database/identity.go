@@ -0,0 +1,148 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"notes/internal/database/generated"
+	"notes/internal/model"
+	"notes/internal/repository"
+)
+
+// how many random suffixes are tried to make a username unique
+const usernameAttempts = 5
+
+func (r *Repository) GetUserByIdentity(ctx context.Context, identity model.Identity, signUp bool) (*model.User, error) {
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	qtx := r.Queries.WithTx(tx)
+
+	dbUser, err := qtx.GetUserByIdentity(ctx, generated.GetUserByIdentityParams{
+		Issuer:  identity.Issuer,
+		Subject: identity.Subject,
+	})
+	if err == nil {
+		return dbUserToUser(dbUser), nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	dbUser, err = qtx.GetUserByEmail(ctx, identity.Email)
+	switch {
+	case err == nil:
+		// the email must be proven on both sides, or whoever signed up with
+		// someone else's email could get into their account, or the other
+		// way around
+		if !identity.EmailVerified || !dbUser.EmailVerifiedAt.Valid {
+			return nil, repository.ErrAlreadyExists
+		}
+	case err == sql.ErrNoRows:
+		if !signUp {
+			return nil, repository.ErrNotFound
+		}
+		if dbUser, err = r.createIdentityUser(ctx, qtx, identity); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	err = qtx.CreateUserIdentity(ctx, generated.CreateUserIdentityParams{
+		UserID:  dbUser.ID,
+		Issuer:  identity.Issuer,
+		Subject: identity.Subject,
+		Email:   sql.NullString{String: identity.Email, Valid: identity.Email != ""},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return dbUserToUser(dbUser), nil
+}
+
+// createIdentityUser signs up the user of an identity. They have no
+// password, but can set one with a password reset.
+func (r *Repository) createIdentityUser(ctx context.Context, qtx *generated.Queries, identity model.Identity) (generated.User, error) {
+	username, err := uniqueUsername(ctx, qtx, identity)
+	if err != nil {
+		return generated.User{}, err
+	}
+
+	dbUser, err := qtx.CreateUser(ctx, generated.CreateUserParams{
+		Username: username,
+		Email:    identity.Email,
+	})
+	if err != nil {
+		return generated.User{}, err
+	}
+
+	if identity.EmailVerified {
+		dbUser, err = qtx.VerifyUserEmail(ctx, generated.VerifyUserEmailParams{
+			Email: dbUser.Email,
+			ID:    dbUser.ID,
+		})
+		if err != nil {
+			return generated.User{}, err
+		}
+	}
+
+	// notes shared with the email before it signed up
+	if identity.EmailVerified || !r.RequireVerifiedEmail {
+		err = qtx.AcceptShareInvitations(ctx, generated.AcceptShareInvitationsParams{
+			Email:  dbUser.Email,
+			UserID: dbUser.ID,
+		})
+		if err != nil {
+			return generated.User{}, err
+		}
+	}
+
+	return dbUser, nil
+}
+
+// uniqueUsername returns the username suggested for the identity, or the
+// start of its email, followed by a random suffix if it is taken
+func uniqueUsername(ctx context.Context, qtx *generated.Queries, identity model.Identity) (string, error) {
+	base := identity.Username
+	if base == "" {
+		base, _, _ = strings.Cut(identity.Email, "@")
+	}
+	base = strings.TrimSpace(base)
+	if base == "" {
+		base = "user"
+	}
+	// leave room for the suffix in the 50 characters of a username
+	for utf8.RuneCountInString(base) > 40 {
+		_, size := utf8.DecodeLastRuneInString(base)
+		base = base[:len(base)-size]
+	}
+
+	username := base
+	for i := 0; i < usernameAttempts; i++ {
+		exists, err := qtx.UsernameExists(ctx, username)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return username, nil
+		}
+
+		suffix, err := newToken()
+		if err != nil {
+			return "", err
+		}
+		username = base + "-" + strings.ToLower(suffix[:6])
+	}
+	return "", fmt.Errorf("failed to find a free username for %q", base)
+}
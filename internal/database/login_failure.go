@@ -0,0 +1,122 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"notes/internal/database/generated"
+	"notes/internal/model"
+)
+
+// what failed logins are counted by
+const (
+	loginScopeAccount = "account"
+	loginScopeIP      = "ip"
+)
+
+// accountKey is the key failed logins to the account of email are counted
+// by. Emails are compared regardless of case, so that changing it does not
+// get around the lockout.
+func accountKey(email string) string {
+	return strings.ToLower(email)
+}
+
+func (r *Repository) LoginLockedUntil(ctx context.Context, email, ip string) (*time.Time, error) {
+	lockedUntil, err := r.Queries.GetLoginLockedUntil(ctx, generated.GetLoginLockedUntilParams{
+		Email: accountKey(email),
+		Ip:    ip,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if !lockedUntil.Valid {
+		return nil, nil
+	}
+	return &lockedUntil.Time, nil
+}
+
+func (r *Repository) RecordLoginFailure(ctx context.Context, email, ip string, lockout model.LoginLockout) (*time.Time, error) {
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	qtx := r.Queries.WithTx(tx)
+
+	var lockedUntil *time.Time
+	for _, failure := range []struct {
+		scope     string
+		key       string
+		threshold int
+	}{
+		{loginScopeAccount, accountKey(email), lockout.AccountThreshold},
+		{loginScopeIP, ip, lockout.IPThreshold},
+	} {
+		if failure.threshold <= 0 || failure.key == "" {
+			continue
+		}
+
+		failures, err := qtx.RecordLoginFailure(ctx, generated.RecordLoginFailureParams{
+			Scope:         failure.scope,
+			Key:           failure.key,
+			WindowSeconds: lockout.Window.Seconds(),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		delay := lockoutDelay(int(failures), failure.threshold, lockout)
+		if delay <= 0 {
+			continue
+		}
+
+		until, err := qtx.LockLogin(ctx, generated.LockLoginParams{
+			LockSeconds: delay.Seconds(),
+			Scope:       failure.scope,
+			Key:         failure.key,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if until.Valid && (lockedUntil == nil || until.Time.After(*lockedUntil)) {
+			lockedUntil = &until.Time
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return lockedUntil, nil
+}
+
+func (r *Repository) ClearLoginFailures(ctx context.Context, email, ip string) error {
+	return r.Queries.ClearLoginFailures(ctx, generated.ClearLoginFailuresParams{
+		Email: accountKey(email),
+		Ip:    ip,
+	})
+}
+
+func (r *Repository) PurgeLoginFailures(ctx context.Context, window time.Duration) (int64, error) {
+	return r.Queries.PurgeLoginFailures(ctx, window.Seconds())
+}
+
+// lockoutDelay returns how long logins are locked after the failures: not
+// at all below the threshold, then the delay of the lockout, doubled with
+// each further failure
+func lockoutDelay(failures, threshold int, lockout model.LoginLockout) time.Duration {
+	if failures < threshold {
+		return 0
+	}
+
+	delay := lockout.Delay
+	for i := threshold; i < failures && delay < lockout.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, lockout.MaxDelay)
+}
@@ -0,0 +1,31 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"notes/internal/model"
+)
+
+func TestLockoutDelay(t *testing.T) {
+	lockout := model.LoginLockout{Delay: 30 * time.Second, MaxDelay: 5 * time.Minute}
+
+	tests := []struct {
+		failures int
+		delay    time.Duration
+	}{
+		{1, 0},
+		{4, 0},
+		{5, 30 * time.Second},
+		{6, time.Minute},
+		{7, 2 * time.Minute},
+		{8, 4 * time.Minute},
+		{9, 5 * time.Minute},
+		{1000, 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.delay, lockoutDelay(tt.failures, 5, lockout), "%d failures", tt.failures)
+	}
+}
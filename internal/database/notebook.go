@@ -0,0 +1,234 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"notes/internal/database/generated"
+	"notes/internal/model"
+	"notes/internal/repository"
+)
+
+func dbNotebookToNotebook(dbNotebook generated.Notebook) *model.Notebook {
+	return &model.Notebook{
+		ID:        dbNotebook.ID,
+		UserID:    dbNotebook.UserID,
+		ParentID:  nullInt32ToPtr(dbNotebook.ParentID),
+		Name:      dbNotebook.Name,
+		CreatedAt: dbNotebook.CreatedAt,
+		UpdatedAt: dbNotebook.UpdatedAt,
+	}
+}
+
+func (r *Repository) CreateNotebook(ctx context.Context, notebook model.NotebookDTO) (*model.Notebook, error) {
+	if notebook.ParentID != nil {
+		_, err := r.Queries.GetOwnedNotebook(ctx, generated.GetOwnedNotebookParams{
+			ID:     *notebook.ParentID,
+			UserID: notebook.UserID,
+		})
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return nil, repository.ErrInvalidParent
+			}
+			return nil, err
+		}
+	}
+
+	dbNotebook, err := r.Queries.CreateNotebook(ctx, generated.CreateNotebookParams{
+		UserID:   notebook.UserID,
+		ParentID: ptrToNullInt32(notebook.ParentID),
+		Name:     notebook.Name,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return dbNotebookToNotebook(dbNotebook), nil
+}
+
+// ListNotebooks lists the notebooks owned by the user and the ones shared
+// with them, directly or through a parent notebook.
+func (r *Repository) ListNotebooks(ctx context.Context, userID int32) ([]model.Notebook, error) {
+	dbNotebooks, err := r.Queries.ListNotebooksByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	notebooks := make([]model.Notebook, 0, len(dbNotebooks))
+
+	for _, dbNotebook := range dbNotebooks {
+		notebooks = append(notebooks, *dbNotebookToNotebook(dbNotebook))
+	}
+
+	return notebooks, nil
+}
+
+func (r *Repository) GetNotebook(ctx context.Context, notebookID, userID int32) (*model.Notebook, error) {
+	dbNotebook, err := r.Queries.GetNotebookByUserID(ctx, generated.GetNotebookByUserIDParams{
+		ID:     notebookID,
+		UserID: userID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return dbNotebookToNotebook(dbNotebook), nil
+}
+
+// UpdateNotebook renames the notebook and moves it under ParentID. A
+// notebook cannot be moved into itself or into one of its descendants.
+func (r *Repository) UpdateNotebook(ctx context.Context, notebookID int32, notebook model.NotebookDTO) (*model.Notebook, error) {
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	qtx := r.Queries.WithTx(tx)
+
+	if notebook.ParentID != nil {
+		_, err := qtx.GetOwnedNotebook(ctx, generated.GetOwnedNotebookParams{
+			ID:     *notebook.ParentID,
+			UserID: notebook.UserID,
+		})
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return nil, repository.ErrInvalidParent
+			}
+			return nil, err
+		}
+
+		cycle, err := qtx.IsNotebookInSubtree(ctx, generated.IsNotebookInSubtreeParams{
+			RootID:     notebookID,
+			NotebookID: *notebook.ParentID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if cycle {
+			return nil, repository.ErrInvalidParent
+		}
+	}
+
+	dbNotebook, err := qtx.UpdateNotebook(ctx, generated.UpdateNotebookParams{
+		ID:       notebookID,
+		UserID:   notebook.UserID,
+		Name:     notebook.Name,
+		ParentID: ptrToNullInt32(notebook.ParentID),
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return dbNotebookToNotebook(dbNotebook), nil
+}
+
+// DeleteNotebook deletes the notebook along with its nested notebooks. The
+// notes inside them are kept and moved out of any notebook.
+func (r *Repository) DeleteNotebook(ctx context.Context, notebookID, userID int32) error {
+	deleted, err := r.Queries.DeleteNotebook(ctx, generated.DeleteNotebookParams{
+		ID:     notebookID,
+		UserID: userID,
+	})
+	if err != nil {
+		return err
+	}
+
+	if deleted == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *Repository) ShareNotebook(ctx context.Context, share model.NotebookShareDTO) error {
+	if r.RequireVerifiedEmail {
+		dbUser, err := r.Queries.GetUserByEmail(ctx, share.SharedWith)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		if err == sql.ErrNoRows || !dbUser.EmailVerifiedAt.Valid {
+			return repository.ErrNotFound
+		}
+	}
+
+	_, err := r.Queries.ShareNotebook(ctx, generated.ShareNotebookParams{
+		NotebookID:      share.NotebookID,
+		SharedWithEmail: share.SharedWith,
+		UserID:          share.UserID,
+	})
+
+	if err == sql.ErrNoRows {
+		return repository.ErrNotFound
+	}
+	if isUniqueViolation(err) {
+		return repository.ErrAlreadyExists
+	}
+
+	return err
+}
+
+func (r *Repository) ListNotebookShares(ctx context.Context, notebookID, userID int32) ([]model.NotebookShare, error) {
+	if _, err := r.GetNotebook(ctx, notebookID, userID); err != nil {
+		return nil, err
+	}
+
+	dbShares, err := r.Queries.ListNotebookShares(ctx, notebookID)
+	if err != nil {
+		return nil, err
+	}
+
+	shares := make([]model.NotebookShare, 0, len(dbShares))
+
+	for _, dbShare := range dbShares {
+		shares = append(shares, model.NotebookShare{
+			UserID:   dbShare.ID,
+			Username: dbShare.Username,
+			Email:    dbShare.Email,
+		})
+	}
+
+	return shares, nil
+}
+
+func (r *Repository) RevokeNotebookShare(ctx context.Context, notebookID, userID, sharedWithUserID int32) error {
+	revoked, err := r.Queries.RevokeNotebookShare(ctx, generated.RevokeNotebookShareParams{
+		NotebookID:       notebookID,
+		UserID:           userID,
+		SharedWithUserID: sharedWithUserID,
+	})
+	if err != nil {
+		return err
+	}
+
+	if revoked == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *Repository) LeaveNotebook(ctx context.Context, notebookID, userID int32) error {
+	left, err := r.Queries.LeaveNotebook(ctx, generated.LeaveNotebookParams{
+		NotebookID:       notebookID,
+		SharedWithUserID: userID,
+	})
+	if err != nil {
+		return err
+	}
+
+	if left == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,276 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"notes/internal/database/generated"
+	"notes/internal/model"
+	"notes/internal/repository"
+)
+
+// noteColumns must match the fields of generated.Note, see scanNotes
+const noteColumns = "n.id, n.user_id, n.title, n.content, n.created_at, n.updated_at, n.deleted_at, n.notebook_id, n.language"
+
+var sortColumns = map[string]string{
+	model.SortByCreatedAt: "n.created_at",
+	model.SortByUpdatedAt: "n.updated_at",
+	model.SortByTitle:     "n.title",
+}
+
+// noteQuery builds the statements listing the notes visible to a user.
+// Unlike the sqlc queries its sort order is chosen at runtime, but every
+// value is still passed as a parameter.
+type noteQuery struct {
+	where []string
+	args  []any
+	// score is an expression rating how well notes match a search, which
+	// is selected after the note columns and can be sorted by
+	score string
+	// snippets are the expressions of the title and content snippets of
+	// search results, selected after the score
+	snippets []string
+	// config is the text search config the words of searches are read
+	// with, the language of each note unless the search sets one
+	config string
+}
+
+func newNoteQuery(userID int32) *noteQuery {
+	q := &noteQuery{config: "n.language"}
+	user := q.arg(userID)
+	q.and(fmt.Sprintf("(n.user_id = %[1]s"+
+		" OR EXISTS (SELECT 1 FROM shared_notes sn WHERE sn.note_id = n.id AND sn.shared_with_user_id = %[1]s)"+
+		" OR n.notebook_id IN (SELECT na.notebook_id FROM notebook_access na WHERE na.user_id = %[1]s))", user))
+	q.and("n.deleted_at IS NULL")
+	return q
+}
+
+// arg adds a parameter and returns its placeholder
+func (q *noteQuery) arg(value any) string {
+	q.args = append(q.args, value)
+	return fmt.Sprintf("$%d", len(q.args))
+}
+
+func (q *noteQuery) and(condition string) {
+	q.where = append(q.where, condition)
+}
+
+func (q *noteQuery) filter(filter model.NoteFilter) error {
+	if len(filter.Tags) > 0 {
+		tags, err := json.Marshal(filter.Tags)
+		if err != nil {
+			return err
+		}
+
+		minMatches := len(filter.Tags)
+		if filter.MatchAny {
+			minMatches = 1
+		}
+
+		q.and(fmt.Sprintf("(SELECT COUNT(DISTINCT t.name) FROM note_tags nt JOIN tags t ON t.id = nt.tag_id"+
+			" WHERE nt.note_id = n.id AND t.name IN (SELECT jsonb_array_elements_text(%s::jsonb))) >= %s",
+			q.arg(string(tags)), q.arg(minMatches)))
+	}
+
+	if filter.NotebookID != nil {
+		if filter.Recursive {
+			q.and(fmt.Sprintf("n.notebook_id IN (WITH RECURSIVE subtree AS ("+
+				"SELECT nb.id FROM notebooks nb WHERE nb.id = %s"+
+				" UNION SELECT nb.id FROM notebooks nb JOIN subtree ON nb.parent_id = subtree.id"+
+				") SELECT id FROM subtree)", q.arg(*filter.NotebookID)))
+		} else {
+			q.and("n.notebook_id = " + q.arg(*filter.NotebookID))
+		}
+	}
+
+	return nil
+}
+
+// page restricts the query to the notes after the cursor and returns the
+// statement fetching one more note than the limit, which tells whether
+// there is a next page.
+func (q *noteQuery) page(page model.NotePage) (string, error) {
+	column, ok := sortColumns[page.SortBy]
+	if page.SortBy == model.SortByRelevance {
+		column, ok = q.score, q.score != ""
+	}
+	if !ok {
+		return "", fmt.Errorf("unknown sort field %q", page.SortBy)
+	}
+
+	direction, comparison := "ASC", ">"
+	if page.Desc {
+		direction, comparison = "DESC", "<"
+	}
+
+	if page.Cursor != "" {
+		c, err := decodeCursor(page)
+		if err != nil {
+			return "", err
+		}
+		q.and(fmt.Sprintf("(%s, n.id) %s (%s, %s)", column, comparison, q.arg(c.value), q.arg(c.ID)))
+	}
+
+	columns := noteColumns
+	if q.score != "" {
+		columns += ", " + q.score
+	}
+	for _, snippet := range q.snippets {
+		columns += ", " + snippet
+	}
+
+	return fmt.Sprintf("SELECT %s FROM notes n WHERE %s ORDER BY %s %s, n.id %s LIMIT %s",
+		columns, strings.Join(q.where, " AND "), column, direction, direction, q.arg(page.Limit+1)), nil
+}
+
+// count returns the statement counting the notes of the query
+func (q *noteQuery) count() string {
+	return "SELECT COUNT(*) FROM notes n WHERE " + strings.Join(q.where, " AND ")
+}
+
+type cursor struct {
+	SortBy string `json:"s"`
+	Desc   bool   `json:"d"`
+	Value  string `json:"v"`
+	ID     int32  `json:"id"`
+
+	value any
+}
+
+func encodeCursor(page model.NotePage, note model.Note) string {
+	return encodeScoredCursor(page, note, 0)
+}
+
+// encodeScoredCursor is encodeCursor for search results, which can also be
+// sorted by their score
+func encodeScoredCursor(page model.NotePage, note model.Note, score float64) string {
+	c := cursor{
+		SortBy: page.SortBy,
+		Desc:   page.Desc,
+		ID:     note.ID,
+	}
+
+	switch page.SortBy {
+	case model.SortByCreatedAt:
+		c.Value = note.CreatedAt.Format(time.RFC3339Nano)
+	case model.SortByUpdatedAt:
+		c.Value = note.UpdatedAt.Format(time.RFC3339Nano)
+	case model.SortByTitle:
+		c.Value = note.Title
+	case model.SortByRelevance:
+		c.Value = strconv.FormatFloat(score, 'g', -1, 64)
+	}
+
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(page model.NotePage) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(page.Cursor)
+	if err != nil {
+		return nil, repository.ErrInvalidCursor
+	}
+
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, repository.ErrInvalidCursor
+	}
+
+	// a cursor is only meaningful for the order it was issued for
+	if c.SortBy != page.SortBy || c.Desc != page.Desc {
+		return nil, repository.ErrInvalidCursor
+	}
+
+	switch c.SortBy {
+	case model.SortByCreatedAt, model.SortByUpdatedAt:
+		t, err := time.Parse(time.RFC3339Nano, c.Value)
+		if err != nil {
+			return nil, repository.ErrInvalidCursor
+		}
+		c.value = t
+	case model.SortByRelevance:
+		score, err := strconv.ParseFloat(c.Value, 64)
+		if err != nil {
+			return nil, repository.ErrInvalidCursor
+		}
+		c.value = score
+	default:
+		c.value = c.Value
+	}
+
+	return &c, nil
+}
+
+// listNotes runs the query for one page of notes
+func (r *Repository) listNotes(ctx context.Context, userID int32, q *noteQuery, page model.NotePage) (*model.NoteList, error) {
+	query, err := q.page(page)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.Db.QueryContext(ctx, query, q.args...)
+	if err != nil {
+		return nil, err
+	}
+
+	dbNotes, err := scanNotes(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &model.NoteList{
+		Notes: make([]model.Note, 0, len(dbNotes)),
+	}
+
+	for _, dbNote := range dbNotes {
+		list.Notes = append(list.Notes, *dbNoteToNote(dbNote))
+	}
+
+	if len(list.Notes) > int(page.Limit) {
+		list.Notes = list.Notes[:page.Limit]
+		list.NextCursor = encodeCursor(page, list.Notes[len(list.Notes)-1])
+	}
+
+	if err := r.attachTags(ctx, list.Notes); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// scanNotes reads rows selected with noteColumns
+func scanNotes(rows *sql.Rows) ([]generated.Note, error) {
+	defer rows.Close()
+
+	var items []generated.Note
+	for rows.Next() {
+		var i generated.Note
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Title,
+			&i.Content,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.NotebookID,
+			&i.Language,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
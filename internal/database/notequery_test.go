@@ -0,0 +1,135 @@
+package database
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"notes/internal/model"
+	"notes/internal/repository"
+	"notes/internal/searchquery"
+)
+
+func TestCursor(t *testing.T) {
+	createdAt := time.Date(2024, 3, 1, 12, 30, 0, 123456000, time.UTC)
+	note := model.Note{ID: 7, Title: "title", CreatedAt: createdAt}
+
+	page := model.NotePage{SortBy: model.SortByCreatedAt, Desc: true}
+	page.Cursor = encodeCursor(page, note)
+
+	c, err := decodeCursor(page)
+	require.NoError(t, err)
+	assert.Equal(t, int32(7), c.ID)
+	assert.Equal(t, createdAt, c.value)
+
+	page = model.NotePage{SortBy: model.SortByTitle}
+	page.Cursor = encodeCursor(page, note)
+
+	c, err = decodeCursor(page)
+	require.NoError(t, err)
+	assert.Equal(t, "title", c.value)
+
+	// Test cases
+	testCases := []model.NotePage{
+		{Cursor: page.Cursor, SortBy: model.SortByTitle, Desc: true},
+		{Cursor: page.Cursor, SortBy: model.SortByUpdatedAt},
+		{Cursor: "not a cursor", SortBy: model.SortByTitle},
+	}
+
+	for _, testCase := range testCases {
+		_, err := decodeCursor(testCase)
+		assert.ErrorIs(t, err, repository.ErrInvalidCursor)
+	}
+}
+
+func TestNoteQuery(t *testing.T) {
+	notebookID := int32(3)
+
+	q := newNoteQuery(1)
+	require.NoError(t, q.filter(model.NoteFilter{Tags: []string{"work"}, NotebookID: &notebookID}))
+
+	query, err := q.page(model.NotePage{Limit: 10, SortBy: model.SortByTitle})
+	require.NoError(t, err)
+	assert.Contains(t, query, "ORDER BY n.title ASC, n.id ASC LIMIT $5")
+	assert.Equal(t, []any{int32(1), `["work"]`, 1, int32(3), int32(11)}, q.args)
+}
+
+func TestScoredNoteQuery(t *testing.T) {
+	note := model.Note{ID: 7, Title: "title"}
+
+	page := model.NotePage{Limit: 10, SortBy: model.SortByRelevance, Desc: true}
+	page.Cursor = encodeScoredCursor(page, note, 0.4166666666666667)
+
+	c, err := decodeCursor(page)
+	require.NoError(t, err)
+	assert.Equal(t, 0.4166666666666667, c.value)
+
+	q := newNoteQuery(1)
+	_, err = q.page(page)
+	assert.Error(t, err, "relevance needs a score")
+
+	q = newNoteQuery(1)
+	q.score = "word_similarity($2, n.title)"
+	q.args = append(q.args, "query")
+	query, err := q.page(page)
+	require.NoError(t, err)
+	assert.Contains(t, query, "n.language, word_similarity($2, n.title) FROM")
+	assert.Contains(t, query, "(word_similarity($2, n.title), n.id) < ($3, $4)")
+	assert.Contains(t, query, "ORDER BY word_similarity($2, n.title) DESC, n.id DESC LIMIT $5")
+}
+
+func TestSearchCondition(t *testing.T) {
+	node, err := searchquery.Parse(`pancake OR "maple syrup" -burnt tag:food title:recipe after:2024-01-31`)
+	require.NoError(t, err)
+
+	q := newNoteQuery(1)
+	condition := q.searchCondition(1, node)
+	assert.Equal(t, "(EXISTS (SELECT 1 FROM note_tags nt JOIN tags t ON t.id = nt.tag_id WHERE nt.note_id = n.id AND t.name = $5)"+
+		" AND to_tsvector(n.language, n.title) @@ plainto_tsquery(n.language, $6)"+
+		" AND n.created_at >= $7"+
+		" AND "+documentVector+" @@ ((plainto_tsquery(n.language, $2) || phraseto_tsquery(n.language, $3)) && !!plainto_tsquery(n.language, $4)))",
+		condition)
+	assert.Equal(t, []any{int32(1), "pancake", "maple syrup", "burnt", "food", "recipe", time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)}, q.args)
+
+	// A search in a given language reads every word in it.
+	node, err = searchquery.Parse(`"Apfel Kuchen"`)
+	require.NoError(t, err)
+
+	q = newNoteQuery(1)
+	q.config = q.arg("german") + "::regconfig"
+	condition = q.searchCondition(1, node)
+	assert.Equal(t, documentVector+" @@ phraseto_tsquery($2::regconfig, $3)", condition)
+	assert.Equal(t, []any{int32(1), "german", "Apfel Kuchen"}, q.args)
+
+	node, err = searchquery.Parse("shared:no OR owner:user@example.com")
+	require.NoError(t, err)
+
+	q = newNoteQuery(1)
+	condition = q.searchCondition(1, node)
+	assert.Equal(t, "(NOT (n.user_id <> $2"+
+		" OR EXISTS (SELECT 1 FROM shared_notes sn WHERE sn.note_id = n.id)"+
+		" OR EXISTS (SELECT 1 FROM notebook_access na WHERE na.notebook_id = n.notebook_id))"+
+		" OR n.user_id = (SELECT u.id FROM users u WHERE u.email = $3))", condition)
+	assert.Equal(t, []any{int32(1), int32(1), "user@example.com"}, q.args)
+}
+
+func TestCountSearchQuery(t *testing.T) {
+	node, err := searchquery.Parse("pancake")
+	require.NoError(t, err)
+
+	// Counting leaves out the rank and snippets, and their parameters,
+	// which Postgres can't infer the type of when unused.
+	q := newNoteQuery(1)
+	q.matchSearch(1, model.NoteSearch{Mode: model.SearchModeFullText, Expr: node, Language: "german"})
+	assert.True(t, strings.HasPrefix(q.count(), "SELECT COUNT(*) FROM notes n WHERE "))
+	assert.True(t, strings.HasSuffix(q.count(), " AND n.deleted_at IS NULL AND n.language = $2::regconfig AND "+documentVector+" @@ plainto_tsquery($2::regconfig, $3)"))
+	assert.Equal(t, []any{int32(1), "german", "pancake"}, q.args)
+
+	q = newNoteQuery(1)
+	q.matchSearch(1, model.NoteSearch{Mode: model.SearchModeFuzzy, Query: "pancak"})
+	assert.True(t, strings.HasSuffix(q.count(), " AND (n.title %> $2 OR n.content %> $2)"))
+	assert.Equal(t, []any{int32(1), "pancak"}, q.args)
+}
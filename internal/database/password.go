@@ -0,0 +1,96 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"notes/internal/database/generated"
+	"notes/internal/repository"
+)
+
+// purpose of the user tokens mailed to reset a password
+const purposePasswordReset = "password_reset"
+
+func (r *Repository) CreatePasswordResetToken(ctx context.Context, email string, ttl time.Duration) (string, error) {
+	dbUser, err := r.Queries.GetUserByEmail(ctx, email)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", repository.ErrNotFound
+		}
+		return "", err
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = r.Queries.CreateUserToken(ctx, generated.CreateUserTokenParams{
+		UserID:     dbUser.ID,
+		Purpose:    purposePasswordReset,
+		TokenHash:  hashToken(token),
+		TtlSeconds: ttl.Seconds(),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (r *Repository) ResetPassword(ctx context.Context, token, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	qtx := r.Queries.WithTx(tx)
+
+	dbToken, err := qtx.GetUserTokenByHash(ctx, generated.GetUserTokenByHashParams{
+		TokenHash: hashToken(token),
+		Purpose:   purposePasswordReset,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return repository.ErrInvalidToken
+		}
+		return err
+	}
+
+	// using up every reset token of the user, not just this one, keeps
+	// older reset emails from working once the password was changed. If a
+	// concurrent reset got there first, nothing is left to use.
+	used, err := qtx.UseUserTokens(ctx, generated.UseUserTokensParams{
+		UserID:  dbToken.UserID,
+		Purpose: purposePasswordReset,
+	})
+	if err != nil {
+		return err
+	}
+	if used == 0 {
+		return repository.ErrInvalidToken
+	}
+
+	err = qtx.UpdateUserPassword(ctx, generated.UpdateUserPasswordParams{
+		PasswordHash: string(hash),
+		ID:           dbToken.UserID,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := qtx.RevokeUserRefreshTokens(ctx, dbToken.UserID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,131 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"notes/internal/database/generated"
+	"notes/internal/model"
+	"notes/internal/repository"
+)
+
+func dbPublicLinkToPublicLink(dbLink generated.PublicLink) *model.PublicLink {
+	link := &model.PublicLink{
+		ID:          dbLink.ID,
+		NoteID:      dbLink.NoteID,
+		Token:       dbLink.Token,
+		HasPassword: dbLink.PasswordHash.Valid,
+		CreatedAt:   dbLink.CreatedAt,
+	}
+	if dbLink.ExpiresAt.Valid {
+		link.ExpiresAt = &dbLink.ExpiresAt.Time
+	}
+	return link
+}
+
+func (r *Repository) CreatePublicLink(ctx context.Context, link model.PublicLinkDTO) (*model.PublicLink, error) {
+	token, err := newToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	var passwordHash sql.NullString
+	if link.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(link.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash password: %w", err)
+		}
+		passwordHash = sql.NullString{String: string(hash), Valid: true}
+	}
+
+	// timestamps are stored without a time zone, in UTC
+	var expiresAt sql.NullTime
+	if link.ExpiresAt != nil {
+		expiresAt = sql.NullTime{Time: link.ExpiresAt.UTC(), Valid: true}
+	}
+
+	dbLink, err := r.Queries.CreatePublicLink(ctx, generated.CreatePublicLinkParams{
+		Token:        token,
+		PasswordHash: passwordHash,
+		ExpiresAt:    expiresAt,
+		NoteID:       link.NoteID,
+		UserID:       link.UserID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return dbPublicLinkToPublicLink(dbLink), nil
+}
+
+func (r *Repository) ListPublicLinks(ctx context.Context, noteID, userID int32) ([]model.PublicLink, error) {
+	dbLinks, err := r.Queries.ListPublicLinks(ctx, generated.ListPublicLinksParams{
+		NoteID: noteID,
+		UserID: userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	links := make([]model.PublicLink, 0, len(dbLinks))
+
+	for _, dbLink := range dbLinks {
+		links = append(links, *dbPublicLinkToPublicLink(dbLink))
+	}
+
+	return links, nil
+}
+
+func (r *Repository) RevokePublicLink(ctx context.Context, noteID, userID, linkID int32) error {
+	revoked, err := r.Queries.RevokePublicLink(ctx, generated.RevokePublicLinkParams{
+		ID:     linkID,
+		NoteID: noteID,
+		UserID: userID,
+	})
+	if err != nil {
+		return err
+	}
+
+	if revoked == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *Repository) GetPublicNote(ctx context.Context, token, password string) (*model.PublicNote, error) {
+	dbLink, err := r.Queries.GetPublicLinkByToken(ctx, token)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+
+	if dbLink.PasswordHash.Valid {
+		if err := bcrypt.CompareHashAndPassword([]byte(dbLink.PasswordHash.String), []byte(password)); err != nil {
+			return nil, repository.ErrInvalidPassword
+		}
+	}
+
+	dbNote, err := r.Queries.GetPublicNote(ctx, dbLink.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &model.PublicNote{
+		Title:     dbNote.Title,
+		Content:   dbNote.Content,
+		CreatedAt: dbNote.CreatedAt,
+		UpdatedAt: dbNote.UpdatedAt,
+	}, nil
+}
@@ -0,0 +1,138 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"notes/internal/database/generated"
+	"notes/internal/model"
+	"notes/internal/repository"
+)
+
+func dbSavedSearchToSavedSearch(dbSearch generated.SavedSearch) *model.SavedSearch {
+	search := &model.SavedSearch{
+		ID:        dbSearch.ID,
+		UserID:    dbSearch.UserID,
+		Name:      dbSearch.Name,
+		Query:     dbSearch.Query,
+		Mode:      dbSearch.Mode,
+		Threshold: dbSearch.Threshold,
+		Language:  dbSearch.Language.String,
+		CreatedAt: dbSearch.CreatedAt,
+	}
+	if dbSearch.LastViewedAt.Valid {
+		search.LastViewedAt = &dbSearch.LastViewedAt.Time
+	}
+	return search
+}
+
+// savedSearchThreshold is the threshold of a saved search, which the
+// handlers default before it gets here
+func savedSearchThreshold(search model.SavedSearchDTO) float64 {
+	if search.Threshold == nil {
+		return 0
+	}
+	return *search.Threshold
+}
+
+func (r *Repository) CreateSavedSearch(ctx context.Context, search model.SavedSearchDTO) (*model.SavedSearch, error) {
+	dbSearch, err := r.Queries.CreateSavedSearch(ctx, generated.CreateSavedSearchParams{
+		UserID:    search.UserID,
+		Name:      search.Name,
+		Query:     search.Query,
+		Mode:      search.Mode,
+		Threshold: savedSearchThreshold(search),
+		Language:  sql.NullString{String: search.Language, Valid: search.Language != ""},
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, repository.ErrAlreadyExists
+		}
+		return nil, err
+	}
+
+	return dbSavedSearchToSavedSearch(dbSearch), nil
+}
+
+func (r *Repository) ListSavedSearches(ctx context.Context, userID int32) ([]model.SavedSearch, error) {
+	dbSearches, err := r.Queries.ListSavedSearches(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	searches := make([]model.SavedSearch, 0, len(dbSearches))
+	for _, dbSearch := range dbSearches {
+		searches = append(searches, *dbSavedSearchToSavedSearch(dbSearch))
+	}
+	return searches, nil
+}
+
+func (r *Repository) GetSavedSearch(ctx context.Context, searchID, userID int32) (*model.SavedSearch, error) {
+	dbSearch, err := r.Queries.GetSavedSearch(ctx, generated.GetSavedSearchParams{
+		ID:     searchID,
+		UserID: userID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return dbSavedSearchToSavedSearch(dbSearch), nil
+}
+
+func (r *Repository) UpdateSavedSearch(ctx context.Context, searchID int32, search model.SavedSearchDTO) (*model.SavedSearch, error) {
+	dbSearch, err := r.Queries.UpdateSavedSearch(ctx, generated.UpdateSavedSearchParams{
+		Name:      search.Name,
+		Query:     search.Query,
+		Mode:      search.Mode,
+		Threshold: savedSearchThreshold(search),
+		Language:  sql.NullString{String: search.Language, Valid: search.Language != ""},
+		ID:        searchID,
+		UserID:    search.UserID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrNotFound
+		}
+		if isUniqueViolation(err) {
+			return nil, repository.ErrAlreadyExists
+		}
+		return nil, err
+	}
+
+	return dbSavedSearchToSavedSearch(dbSearch), nil
+}
+
+func (r *Repository) DeleteSavedSearch(ctx context.Context, searchID, userID int32) error {
+	deleted, err := r.Queries.DeleteSavedSearch(ctx, generated.DeleteSavedSearchParams{
+		ID:     searchID,
+		UserID: userID,
+	})
+	if err != nil {
+		return err
+	}
+
+	if deleted == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *Repository) MarkSavedSearchViewed(ctx context.Context, searchID, userID int32, viewedAt time.Time) error {
+	updated, err := r.Queries.MarkSavedSearchViewed(ctx, generated.MarkSavedSearchViewedParams{
+		ViewedAt: sql.NullTime{Time: viewedAt.UTC(), Valid: true},
+		ID:       searchID,
+		UserID:   userID,
+	})
+	if err != nil {
+		return err
+	}
+
+	if updated == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
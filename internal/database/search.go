@@ -0,0 +1,356 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	"notes/internal/database/generated"
+	"notes/internal/model"
+	"notes/internal/searchquery"
+)
+
+// documentVector weighs the words of the title above those of the content,
+// read in the language of the note. It must match the expression of
+// idx_notes_search for the index to be used.
+const documentVector = "(setweight(to_tsvector(n.language, n.title), 'A') || setweight(to_tsvector(n.language, n.content), 'B'))"
+
+// ts_headline surrounds the matching words with these control characters,
+// which are replaced by the markers of the search once the rest of the
+// snippet is HTML-escaped. They are removed from the text beforehand.
+const (
+	headlineStart = "\x02"
+	headlineStop  = "\x03"
+)
+
+// how ts_headline cuts the content of full-text results into snippets
+const headlineOptions = "StartSel=\"" + headlineStart + "\", StopSel=\"" + headlineStop + "\", MaxFragments=3, MaxWords=35, MinWords=15, FragmentDelimiter=\" ... \""
+
+func (r *Repository) SearchNotes(ctx context.Context, userID int32, search model.NoteSearch, page model.NotePage) (*model.SearchResultList, error) {
+	q := newNoteQuery(userID)
+	q.matchSearch(userID, search)
+	q.rankSearch(search)
+
+	if search.Mode != model.SearchModeFuzzy {
+		return r.searchNotes(ctx, r.Db, userID, search, q, page)
+	}
+
+	tx, err := r.beginFuzzySearch(ctx, search.Threshold)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	list, err := r.searchNotes(ctx, tx, userID, search, q, page)
+	if err != nil {
+		return nil, err
+	}
+	return list, tx.Commit()
+}
+
+func (r *Repository) CountSearchNotes(ctx context.Context, userID int32, search model.NoteSearch, since *time.Time) (int64, error) {
+	q := newNoteQuery(userID)
+	q.matchSearch(userID, search)
+	if since != nil {
+		q.and("n.updated_at > " + q.arg(*since))
+	}
+
+	var count int64
+	if search.Mode != model.SearchModeFuzzy {
+		err := r.Db.QueryRowContext(ctx, q.count(), q.args...).Scan(&count)
+		return count, err
+	}
+
+	tx, err := r.beginFuzzySearch(ctx, search.Threshold)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRowContext(ctx, q.count(), q.args...).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, tx.Commit()
+}
+
+// beginFuzzySearch starts the transaction of a fuzzy search. The %>
+// operators use the trigram indexes, but take their threshold from a
+// setting, which only lasts for the transaction.
+func (r *Repository) beginFuzzySearch(ctx context.Context, threshold float64) (*sql.Tx, error) {
+	tx, err := r.Db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+
+	setting := strconv.FormatFloat(threshold, 'f', -1, 64)
+	if _, err := tx.ExecContext(ctx, "SELECT set_config('pg_trgm.word_similarity_threshold', $1, true)", setting); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return tx, nil
+}
+
+// matchSearch restricts the query to the notes matching the search
+func (q *noteQuery) matchSearch(userID int32, search model.NoteSearch) {
+	if search.Mode == model.SearchModeFuzzy {
+		q.and(fmt.Sprintf("(n.title %%> %[1]s OR n.content %%> %[1]s)", q.arg(search.Query)))
+		return
+	}
+
+	// a query read in a single language only matches the notes in that
+	// language, but then compares to the same tsquery on every row,
+	// which the index needs
+	if search.Language != "" {
+		q.config = q.arg(search.Language) + "::regconfig"
+		q.and("n.language = " + q.config)
+	}
+	if search.Expr != nil {
+		q.and(q.searchCondition(userID, search.Expr))
+	}
+}
+
+// rankSearch selects the score of the notes matching the search, and the
+// snippets of full-text results. It reads the words of the search in the
+// config set by matchSearch.
+func (q *noteQuery) rankSearch(search model.NoteSearch) {
+	if search.Mode == model.SearchModeFuzzy {
+		q.score = fmt.Sprintf("GREATEST(word_similarity(%[1]s, n.title), word_similarity(%[1]s, n.content))::float8", q.arg(search.Query))
+		return
+	}
+
+	// notes are ranked and highlighted by the words they should have
+	var matches []string
+	for _, match := range searchquery.Matches(search.Expr) {
+		matches = append(matches, q.tsquery(match))
+	}
+	query := "''::tsquery"
+	if len(matches) > 0 {
+		query = "(" + strings.Join(matches, " || ") + ")"
+	}
+	q.score = fmt.Sprintf("ts_rank_cd(%s, %s)::float8", documentVector, query)
+
+	text := func(column string) string {
+		return fmt.Sprintf("translate(%s, %s, '')", column, q.arg(headlineStart+headlineStop))
+	}
+	options := q.arg(headlineOptions)
+	q.snippets = []string{
+		fmt.Sprintf("ts_headline(n.language, %s, %s, %s || ', HighlightAll=true')", text("n.title"), query, options),
+		fmt.Sprintf("ts_headline(n.language, %s, %s, %s)", text("n.content"), query, options),
+	}
+}
+
+// highlightSnippet HTML-escapes a snippet selected by rankSearch and puts the
+// markers of the search around the words ts_headline highlighted. The markers
+// are not escaped, so they can be tags.
+func highlightSnippet(snippet string, search model.NoteSearch) string {
+	return strings.NewReplacer(headlineStart, search.HighlightStart, headlineStop, search.HighlightStop).Replace(html.EscapeString(snippet))
+}
+
+// searchNotes runs the query for one page of search results, along with
+// their score and snippets
+func (r *Repository) searchNotes(ctx context.Context, db generated.DBTX, userID int32, search model.NoteSearch, q *noteQuery, page model.NotePage) (*model.SearchResultList, error) {
+	query, err := q.page(page)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, query, q.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := &model.SearchResultList{Notes: []model.SearchResult{}}
+	for rows.Next() {
+		var i generated.Note
+		var score float64
+		var snippets model.SearchSnippets
+		dest := []any{
+			&i.ID,
+			&i.UserID,
+			&i.Title,
+			&i.Content,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.NotebookID,
+			&i.Language,
+			&score,
+		}
+		if len(q.snippets) > 0 {
+			dest = append(dest, &snippets.Title, &snippets.Content)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		result := model.SearchResult{Note: *dbNoteToNote(i)}
+		if search.Mode == model.SearchModeFuzzy {
+			result.Score = score
+		} else {
+			result.Rank = score
+			snippets.Title = highlightSnippet(snippets.Title, search)
+			snippets.Content = highlightSnippet(snippets.Content, search)
+			result.Snippets = &snippets
+		}
+		list.Notes = append(list.Notes, result)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(list.Notes) > int(page.Limit) {
+		list.Notes = list.Notes[:page.Limit]
+		last := list.Notes[len(list.Notes)-1]
+		list.NextCursor = encodeScoredCursor(page, last.Note, max(last.Score, last.Rank))
+	}
+
+	notes := make([]model.Note, len(list.Notes))
+	for i, result := range list.Notes {
+		notes[i] = result.Note
+	}
+	if err := r.attachTags(ctx, notes); err != nil {
+		return nil, err
+	}
+	for i := range list.Notes {
+		list.Notes[i].Tags = notes[i].Tags
+	}
+
+	return list, nil
+}
+
+// dateColumns are the columns of the dates searches filter on
+var dateColumns = map[string]string{
+	searchquery.CreatedAt: "n.created_at",
+	searchquery.UpdatedAt: "n.updated_at",
+}
+
+// tsquery returns the text search query of a match
+func (q *noteQuery) tsquery(match searchquery.Match) string {
+	if match.Phrase {
+		return fmt.Sprintf("phraseto_tsquery(%s, %s)", q.config, q.arg(match.Value))
+	}
+	return fmt.Sprintf("plainto_tsquery(%s, %s)", q.config, q.arg(match.Value))
+}
+
+// isTextQuery reports whether the node is only made of matches on titles
+// and contents, which textQuery combines into a single text search query.
+// Matching a single query uses the index, and ignores the stop words of
+// each match rather than failing on them.
+func isTextQuery(node searchquery.Node) bool {
+	switch node := node.(type) {
+	case searchquery.Match:
+		return !node.TitleOnly
+	case searchquery.Not:
+		return isTextQuery(node.Node)
+	case searchquery.And:
+		return allTextQueries(node)
+	case searchquery.Or:
+		return allTextQueries(node)
+	}
+	return false
+}
+
+func allTextQueries(nodes []searchquery.Node) bool {
+	for _, node := range nodes {
+		if !isTextQuery(node) {
+			return false
+		}
+	}
+	return true
+}
+
+func (q *noteQuery) textQuery(node searchquery.Node) string {
+	switch node := node.(type) {
+	case searchquery.Match:
+		return q.tsquery(node)
+	case searchquery.Not:
+		return "!!" + q.textQuery(node.Node)
+	case searchquery.And:
+		return q.textQueries(node, " && ")
+	case searchquery.Or:
+		return q.textQueries(node, " || ")
+	}
+	return ""
+}
+
+func (q *noteQuery) textQueries(nodes []searchquery.Node, operator string) string {
+	queries := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		queries = append(queries, q.textQuery(node))
+	}
+	return "(" + strings.Join(queries, operator) + ")"
+}
+
+// searchCondition compiles a parsed search query into a condition on the
+// notes n, passing every value as a parameter
+func (q *noteQuery) searchCondition(userID int32, node searchquery.Node) string {
+	if isTextQuery(node) {
+		return fmt.Sprintf("%s @@ %s", documentVector, q.textQuery(node))
+	}
+
+	switch node := node.(type) {
+	case searchquery.Match:
+		// only title matches get here
+		return fmt.Sprintf("to_tsvector(n.language, n.title) @@ %s", q.tsquery(node))
+	case searchquery.Not:
+		return fmt.Sprintf("NOT (%s)", q.searchCondition(userID, node.Node))
+	case searchquery.And:
+		return q.searchConditions(userID, node, " AND ")
+	case searchquery.Or:
+		return q.searchConditions(userID, node, " OR ")
+	case searchquery.Tag:
+		return fmt.Sprintf("EXISTS (SELECT 1 FROM note_tags nt JOIN tags t ON t.id = nt.tag_id"+
+			" WHERE nt.note_id = n.id AND t.name = %s)", q.arg(string(node)))
+	case searchquery.Shared:
+		shared := fmt.Sprintf("(n.user_id <> %s"+
+			" OR EXISTS (SELECT 1 FROM shared_notes sn WHERE sn.note_id = n.id)"+
+			" OR EXISTS (SELECT 1 FROM notebook_access na WHERE na.notebook_id = n.notebook_id))", q.arg(userID))
+		if !node {
+			return "NOT " + shared
+		}
+		return shared
+	case searchquery.Owner:
+		if node == searchquery.OwnerMe {
+			return "n.user_id = " + q.arg(userID)
+		}
+		return fmt.Sprintf("n.user_id = (SELECT u.id FROM users u WHERE u.email = %s)", q.arg(string(node)))
+	case searchquery.Date:
+		comparison := ">="
+		if node.Before {
+			comparison = "<"
+		}
+		return fmt.Sprintf("%s %s %s", dateColumns[node.Field], comparison, q.arg(node.Time))
+	}
+	panic(fmt.Sprintf("unknown search query node %T", node))
+}
+
+// searchConditions joins the conditions of the nodes with the operator.
+// The text queries among them are combined into a single condition.
+func (q *noteQuery) searchConditions(userID int32, nodes []searchquery.Node, operator string) string {
+	var textQueries []string
+	var conditions []string
+	for _, node := range nodes {
+		if isTextQuery(node) {
+			textQueries = append(textQueries, q.textQuery(node))
+		} else {
+			conditions = append(conditions, q.searchCondition(userID, node))
+		}
+	}
+
+	if len(textQueries) > 0 {
+		tsOperator := " && "
+		if operator == " OR " {
+			tsOperator = " || "
+		}
+		conditions = append(conditions, fmt.Sprintf("%s @@ (%s)", documentVector, strings.Join(textQueries, tsOperator)))
+	}
+	return "(" + strings.Join(conditions, operator) + ")"
+}
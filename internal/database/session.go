@@ -0,0 +1,125 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"notes/internal/database/generated"
+	"notes/internal/model"
+	"notes/internal/repository"
+)
+
+// newSessionToken adds a refresh token, and the id of the access token
+// issued with it, to a session
+func newSessionToken(ctx context.Context, q *generated.Queries, userID int32, familyID string, ttl time.Duration) (*model.Session, error) {
+	refreshToken, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	tokenID, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = q.CreateRefreshToken(ctx, generated.CreateRefreshTokenParams{
+		UserID:        userID,
+		FamilyID:      familyID,
+		TokenHash:     hashToken(refreshToken),
+		AccessTokenID: tokenID,
+		TtlSeconds:    ttl.Seconds(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.Session{
+		UserID:       userID,
+		TokenID:      tokenID,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+func (r *Repository) CreateSession(ctx context.Context, userID int32, ttl time.Duration) (*model.Session, error) {
+	familyID, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	return newSessionToken(ctx, r.Queries, userID, familyID, ttl)
+}
+
+func (r *Repository) RefreshSession(ctx context.Context, refreshToken string, ttl time.Duration) (*model.Session, error) {
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	qtx := r.Queries.WithTx(tx)
+
+	dbToken, err := qtx.GetRefreshTokenByHash(ctx, hashToken(refreshToken))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	if dbToken.RevokedAt.Valid {
+		return nil, repository.ErrInvalidToken
+	}
+
+	used, err := qtx.UseRefreshToken(ctx, dbToken.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// a refresh token that was already used has leaked, so the session it
+	// belongs to can no longer be trusted
+	if used == 0 {
+		if err := qtx.RevokeRefreshTokenFamily(ctx, dbToken.FamilyID); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+		return nil, repository.ErrInvalidToken
+	}
+
+	session, err := newSessionToken(ctx, qtx, dbToken.UserID, dbToken.FamilyID, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (r *Repository) RevokeSession(ctx context.Context, userID int32, tokenID string) error {
+	return r.Queries.RevokeSessionByAccessTokenID(ctx, generated.RevokeSessionByAccessTokenIDParams{
+		UserID:        userID,
+		AccessTokenID: tokenID,
+	})
+}
+
+func (r *Repository) RevokeAllSessions(ctx context.Context, userID int32) error {
+	return r.Queries.RevokeUserRefreshTokens(ctx, userID)
+}
+
+func (r *Repository) RevokeOtherSessions(ctx context.Context, userID int32, tokenID string) error {
+	return r.Queries.RevokeOtherUserRefreshTokens(ctx, generated.RevokeOtherUserRefreshTokensParams{
+		UserID:        userID,
+		AccessTokenID: tokenID,
+	})
+}
+
+func (r *Repository) IsTokenActive(ctx context.Context, tokenID string) (bool, error) {
+	return r.Queries.IsAccessTokenActive(ctx, tokenID)
+}
+
+func (r *Repository) PurgeExpiredSessions(ctx context.Context) (int64, error) {
+	return r.Queries.PurgeExpiredRefreshTokens(ctx)
+}
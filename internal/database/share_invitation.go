@@ -0,0 +1,72 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"notes/internal/database/generated"
+	"notes/internal/model"
+	"notes/internal/repository"
+)
+
+func dbShareInvitationToShareInvitation(dbInvitation generated.ShareInvitation) *model.ShareInvitation {
+	return &model.ShareInvitation{
+		ID:        dbInvitation.ID,
+		NoteID:    dbInvitation.NoteID,
+		Email:     dbInvitation.Email,
+		Role:      dbInvitation.Role,
+		CreatedAt: dbInvitation.CreatedAt,
+	}
+}
+
+func (r *Repository) inviteToNote(ctx context.Context, share model.NoteShareDTO) (*model.ShareInvitation, error) {
+	dbInvitation, err := r.Queries.CreateShareInvitation(ctx, generated.CreateShareInvitationParams{
+		Email:  share.SharedWith,
+		Role:   share.Role,
+		NoteID: share.NoteID,
+		UserID: share.UserID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return dbShareInvitationToShareInvitation(dbInvitation), nil
+}
+
+func (r *Repository) ListShareInvitations(ctx context.Context, noteID, userID int32) ([]model.ShareInvitation, error) {
+	dbInvitations, err := r.Queries.ListShareInvitations(ctx, generated.ListShareInvitationsParams{
+		NoteID: noteID,
+		UserID: userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	invitations := make([]model.ShareInvitation, 0, len(dbInvitations))
+
+	for _, dbInvitation := range dbInvitations {
+		invitations = append(invitations, *dbShareInvitationToShareInvitation(dbInvitation))
+	}
+
+	return invitations, nil
+}
+
+func (r *Repository) CancelShareInvitation(ctx context.Context, noteID, userID, invitationID int32) error {
+	cancelled, err := r.Queries.CancelShareInvitation(ctx, generated.CancelShareInvitationParams{
+		ID:     invitationID,
+		NoteID: noteID,
+		UserID: userID,
+	})
+	if err != nil {
+		return err
+	}
+
+	if cancelled == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
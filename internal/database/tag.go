@@ -0,0 +1,164 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"notes/internal/database/generated"
+	"notes/internal/model"
+	"notes/internal/repository"
+)
+
+func (r *Repository) ListTags(ctx context.Context, userID int32) ([]model.Tag, error) {
+	dbTags, err := r.Queries.ListTagsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make([]model.Tag, 0, len(dbTags))
+
+	for _, dbTag := range dbTags {
+		tags = append(tags, model.Tag{
+			Name:      dbTag.Name,
+			NoteCount: dbTag.NoteCount,
+		})
+	}
+
+	return tags, nil
+}
+
+func (r *Repository) RenameTag(ctx context.Context, userID int32, from, to string) error {
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	qtx := r.Queries.WithTx(tx)
+
+	fromTag, err := qtx.GetTagByName(ctx, generated.GetTagByNameParams{
+		UserID: userID,
+		Name:   from,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return repository.ErrNotFound
+		}
+		return err
+	}
+
+	toTag, err := qtx.GetTagByName(ctx, generated.GetTagByNameParams{
+		UserID: userID,
+		Name:   to,
+	})
+	switch {
+	case err == sql.ErrNoRows:
+		err = qtx.RenameTag(ctx, generated.RenameTagParams{
+			ID:   fromTag.ID,
+			Name: to,
+		})
+	case err != nil:
+		return err
+	case toTag.ID != fromTag.ID:
+		err = qtx.MergeNoteTags(ctx, generated.MergeNoteTagsParams{
+			ToTagID:   toTag.ID,
+			FromTagID: fromTag.ID,
+		})
+		if err == nil {
+			err = qtx.DeleteTag(ctx, fromTag.ID)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// setNoteTags replaces the tags of the note, creating the owner's missing
+// tags and dropping the ones no longer used by any note.
+func setNoteTags(ctx context.Context, q *generated.Queries, ownerID, noteID int32, tags []string) error {
+	if err := q.DeleteNoteTags(ctx, noteID); err != nil {
+		return err
+	}
+
+	for _, name := range tags {
+		tag, err := q.UpsertTag(ctx, generated.UpsertTagParams{
+			UserID: ownerID,
+			Name:   name,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = q.AddNoteTag(ctx, generated.AddNoteTagParams{
+			NoteID: noteID,
+			TagID:  tag.ID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return q.DeleteUnusedTags(ctx, ownerID)
+}
+
+func listNoteTags(ctx context.Context, q *generated.Queries, noteID int32) ([]string, error) {
+	tags, err := q.ListNoteTagNames(ctx, noteID)
+	if err != nil {
+		return nil, err
+	}
+	if tags == nil {
+		return []string{}, nil
+	}
+	return tags, nil
+}
+
+// noteTagNamesQuery selects the tag names of the notes with the IDs in $1.
+// It is not generated by sqlc, which binds arrays with lib/pq, while the
+// pgx driver takes the slice as it is.
+const noteTagNamesQuery = `SELECT nt.note_id, t.name
+FROM note_tags nt
+JOIN tags t ON t.id = nt.tag_id
+WHERE nt.note_id = ANY($1::int[])
+ORDER BY t.name`
+
+// attachTags fills in the tags of notes already selected for the user,
+// loading only the tags of those notes
+func (r *Repository) attachTags(ctx context.Context, notes []model.Note) error {
+	if len(notes) == 0 {
+		return nil
+	}
+
+	noteIDs := make([]int32, 0, len(notes))
+	for _, note := range notes {
+		noteIDs = append(noteIDs, note.ID)
+	}
+
+	rows, err := r.Db.QueryContext(ctx, noteTagNamesQuery, noteIDs)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	tagsByNote := make(map[int32][]string)
+	for rows.Next() {
+		var noteID int32
+		var name string
+		if err := rows.Scan(&noteID, &name); err != nil {
+			return err
+		}
+		tagsByNote[noteID] = append(tagsByNote[noteID], name)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range notes {
+		if tags, ok := tagsByNote[notes[i].ID]; ok {
+			notes[i].Tags = tags
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,28 @@
+package database
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// number of random bytes in generated tokens
+const tokenSize = 32
+
+// newToken returns an unguessable URL-safe token
+func newToken() (string, error) {
+	b := make([]byte, tokenSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns the hash under which a token is stored. Tokens are
+// random, so unlike passwords a fast hash is enough and lets them be looked
+// up by hash.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
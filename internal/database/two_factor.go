@@ -0,0 +1,257 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"strings"
+	"time"
+
+	"notes/internal/database/generated"
+	"notes/internal/model"
+	"notes/internal/repository"
+)
+
+// purpose of the user tokens completing a login with a second factor
+const purposeLoginChallenge = "login_challenge"
+
+// how many recovery codes users get, and how many random bytes each has
+const (
+	recoveryCodeCount = 10
+	recoveryCodeSize  = 5
+)
+
+func (r *Repository) GetTOTP(ctx context.Context, userID int32) (*model.TOTP, error) {
+	dbTOTP, err := r.Queries.GetUserTOTP(ctx, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+
+	totp := &model.TOTP{
+		UserID: dbTOTP.UserID,
+		Secret: dbTOTP.Secret,
+	}
+	if dbTOTP.EnabledAt.Valid {
+		totp.EnabledAt = &dbTOTP.EnabledAt.Time
+	}
+	return totp, nil
+}
+
+func (r *Repository) StartTOTPEnrollment(ctx context.Context, userID int32, password, secret string) error {
+	if _, err := getUserWithPassword(ctx, r.Queries, userID, password); err != nil {
+		return err
+	}
+
+	// the secret of an enrollment that wasn't confirmed is replaced, but
+	// not the one of an enabled two-factor authentication
+	created, err := r.Queries.CreatePendingTOTP(ctx, generated.CreatePendingTOTPParams{
+		UserID: userID,
+		Secret: secret,
+	})
+	if err != nil {
+		return err
+	}
+	if created == 0 {
+		return repository.ErrAlreadyExists
+	}
+	return nil
+}
+
+func (r *Repository) EnableTOTP(ctx context.Context, userID int32, counter int64) ([]string, error) {
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	qtx := r.Queries.WithTx(tx)
+
+	enabled, err := qtx.EnableUserTOTP(ctx, generated.EnableUserTOTPParams{
+		Counter: sql.NullInt64{Int64: counter, Valid: true},
+		UserID:  userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if enabled == 0 {
+		return nil, repository.ErrNotFound
+	}
+
+	codes, err := createRecoveryCodes(ctx, qtx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (r *Repository) UseTOTP(ctx context.Context, userID int32, counter int64) error {
+	used, err := r.Queries.UseTOTPCounter(ctx, generated.UseTOTPCounterParams{
+		Counter: sql.NullInt64{Int64: counter, Valid: true},
+		UserID:  userID,
+	})
+	if err != nil {
+		return err
+	}
+	if used == 0 {
+		return repository.ErrInvalidToken
+	}
+	return nil
+}
+
+func (r *Repository) UseRecoveryCode(ctx context.Context, userID int32, code string) error {
+	used, err := r.Queries.UseRecoveryCode(ctx, generated.UseRecoveryCodeParams{
+		UserID:   userID,
+		CodeHash: hashToken(normalizeRecoveryCode(code)),
+	})
+	if err != nil {
+		return err
+	}
+	if used == 0 {
+		return repository.ErrInvalidToken
+	}
+	return nil
+}
+
+func (r *Repository) RegenerateRecoveryCodes(ctx context.Context, userID int32) ([]string, error) {
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	qtx := r.Queries.WithTx(tx)
+
+	if err := qtx.DeleteRecoveryCodes(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	codes, err := createRecoveryCodes(ctx, qtx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (r *Repository) DisableTOTP(ctx context.Context, userID int32) error {
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	qtx := r.Queries.WithTx(tx)
+
+	deleted, err := qtx.DeleteUserTOTP(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return repository.ErrNotFound
+	}
+
+	if err := qtx.DeleteRecoveryCodes(ctx, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *Repository) CreateLoginChallenge(ctx context.Context, userID int32, ttl time.Duration) (string, error) {
+	token, err := newToken()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = r.Queries.CreateUserToken(ctx, generated.CreateUserTokenParams{
+		UserID:     userID,
+		Purpose:    purposeLoginChallenge,
+		TokenHash:  hashToken(token),
+		TtlSeconds: ttl.Seconds(),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (r *Repository) UseLoginChallenge(ctx context.Context, token string) (int32, error) {
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	qtx := r.Queries.WithTx(tx)
+
+	dbToken, err := qtx.GetUserTokenByHash(ctx, generated.GetUserTokenByHashParams{
+		TokenHash: hashToken(token),
+		Purpose:   purposeLoginChallenge,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, repository.ErrInvalidToken
+		}
+		return 0, err
+	}
+
+	// a challenge allows a single guess of the code, guessing again takes
+	// another login with the password
+	used, err := qtx.UseUserTokens(ctx, generated.UseUserTokensParams{
+		UserID:  dbToken.UserID,
+		Purpose: purposeLoginChallenge,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if used == 0 {
+		return 0, repository.ErrInvalidToken
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return dbToken.UserID, nil
+}
+
+// createRecoveryCodes stores the hashes of new recovery codes for the user
+// and returns the codes
+func createRecoveryCodes(ctx context.Context, qtx *generated.Queries, userID int32) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, recoveryCodeSize)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(base32.StdEncoding.EncodeToString(b))
+		// split in two halves to be easier to copy
+		codes[i] = code[:len(code)/2] + "-" + code[len(code)/2:]
+
+		err := qtx.CreateRecoveryCode(ctx, generated.CreateRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: hashToken(normalizeRecoveryCode(codes[i])),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return codes, nil
+}
+
+// normalizeRecoveryCode ignores the case and separators of a code typed by
+// the user
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
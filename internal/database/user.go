@@ -0,0 +1,120 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"golang.org/x/crypto/bcrypt"
+
+	"notes/internal/database/generated"
+	"notes/internal/model"
+	"notes/internal/repository"
+)
+
+// isUniqueViolation reports whether err comes from a unique constraint
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// getUserWithPassword returns the user if the password is theirs
+func getUserWithPassword(ctx context.Context, q *generated.Queries, userID int32, password string) (generated.User, error) {
+	dbUser, err := q.GetUserByID(ctx, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return generated.User{}, repository.ErrNotFound
+		}
+		return generated.User{}, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(dbUser.PasswordHash), []byte(password)); err != nil {
+		return generated.User{}, repository.ErrInvalidPassword
+	}
+	return dbUser, nil
+}
+
+func (r *Repository) CheckPassword(ctx context.Context, userID int32, password string) error {
+	_, err := getUserWithPassword(ctx, r.Queries, userID, password)
+	return err
+}
+
+func (r *Repository) GetUser(ctx context.Context, userID int32) (*model.User, error) {
+	dbUser, err := r.Queries.GetUserByID(ctx, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return dbUserToUser(dbUser), nil
+}
+
+func (r *Repository) UpdateProfile(ctx context.Context, userID int32, profile model.UpdateUserDTO) (*model.User, error) {
+	dbUser, err := r.Queries.UpdateProfile(ctx, generated.UpdateProfileParams{
+		Username:       profile.Username,
+		SearchLanguage: sql.NullString{String: profile.SearchLanguage, Valid: profile.SearchLanguage != ""},
+		ID:             userID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrNotFound
+		}
+		if isUniqueViolation(err) {
+			return nil, repository.ErrAlreadyExists
+		}
+		return nil, err
+	}
+	return dbUserToUser(dbUser), nil
+}
+
+func (r *Repository) ChangePassword(ctx context.Context, userID int32, currentPassword, newPassword string) error {
+	if _, err := getUserWithPassword(ctx, r.Queries, userID, currentPassword); err != nil {
+		return err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	return r.Queries.UpdateUserPassword(ctx, generated.UpdateUserPasswordParams{
+		PasswordHash: string(hash),
+		ID:           userID,
+	})
+}
+
+func (r *Repository) DeleteUser(ctx context.Context, userID int32, password string, transferSharedNotes bool) error {
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	qtx := r.Queries.WithTx(tx)
+
+	if _, err := getUserWithPassword(ctx, qtx, userID, password); err != nil {
+		return err
+	}
+
+	// transferred notes leave the notebooks of the user, and lose their
+	// tags since those belong to the user
+	if transferSharedNotes {
+		if err := qtx.TransferSharedNotes(ctx, userID); err != nil {
+			return err
+		}
+	}
+
+	// everything else of the user goes with them
+	deleted, err := qtx.DeleteUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return repository.ErrNotFound
+	}
+
+	return tx.Commit()
+}
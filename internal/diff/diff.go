@@ -0,0 +1,274 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Op string
+
+const (
+	Equal  Op = "equal"
+	Insert Op = "insert"
+	Delete Op = "delete"
+)
+
+type Line struct {
+	Op   Op     `json:"op"`
+	Text string `json:"text"`
+}
+
+type Hunk struct {
+	OldStart int    `json:"old_start"`
+	OldLines int    `json:"old_lines"`
+	NewStart int    `json:"new_start"`
+	NewLines int    `json:"new_lines"`
+	Lines    []Line `json:"lines"`
+}
+
+// splits the text into lines, ignoring a single trailing newline
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// how many changed lines the search for a shortest edit script goes through
+// before giving up, so that diffing large texts that share little doesn't
+// take forever. The lines that are left are then deleted and inserted whole.
+const maxSearch = 1000
+
+// computes the line-level edit script that turns a into b, which is one of
+// the shortest unless they differ in too many lines. It takes O((N+M)D) time
+// and O(N+M) memory for N and M lines and D changed ones, so large notes that
+// change little stay cheap.
+func Lines(a, b string) []Line {
+	oldLines, newLines := splitLines(a), splitLines(b)
+	return appendLines(make([]Line, 0, len(oldLines)+len(newLines)), oldLines, newLines)
+}
+
+// appends the edit script that turns x into y, splitting it in two at the
+// middle of a shortest one as described by Myers in "An O(ND) Difference
+// Algorithm and Its Variations"
+func appendLines(lines []Line, x, y []string) []Line {
+	// common prefix and suffix never change, so keep them out of the search
+	prefix := 0
+	for prefix < len(x) && prefix < len(y) && x[prefix] == y[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(x)-prefix && suffix < len(y)-prefix && x[len(x)-1-suffix] == y[len(y)-1-suffix] {
+		suffix++
+	}
+
+	for _, text := range x[:prefix] {
+		lines = append(lines, Line{Op: Equal, Text: text})
+	}
+
+	common := x[len(x)-suffix:]
+	x, y = x[prefix:len(x)-suffix], y[prefix:len(y)-suffix]
+	if i, j, ok := middle(x, y); ok {
+		lines = appendLines(lines, x[:i], y[:j])
+		lines = appendLines(lines, x[i:], y[j:])
+	} else {
+		for _, text := range x {
+			lines = append(lines, Line{Op: Delete, Text: text})
+		}
+		for _, text := range y {
+			lines = append(lines, Line{Op: Insert, Text: text})
+		}
+	}
+
+	for _, text := range common {
+		lines = append(lines, Line{Op: Equal, Text: text})
+	}
+	return lines
+}
+
+// finds the point at which a shortest edit script of x into y is split in
+// two, by searching forward from the start and backward from the end until
+// the paths overlap. x and y must not start or end with the same line. It
+// returns false if they have no line in common, only one of them has lines,
+// or they differ in more than maxSearch lines, in which case all of x is
+// deleted and all of y inserted.
+func middle(x, y []string) (int, int, bool) {
+	n, m := len(x), len(y)
+	if n == 0 || m == 0 {
+		return 0, 0, false
+	}
+
+	maxD := (n + m + 1) / 2
+	offset := maxD
+	// forward[offset+k] is the furthest x reached on diagonal k = x-y from
+	// the start, backward[offset+k] the same from the end
+	forward, backward := make([]int, 2*maxD+2), make([]int, 2*maxD+2)
+	for i := range forward {
+		forward[i], backward[i] = -1, -1
+	}
+	forward[offset+1], backward[offset+1] = 0, 0
+
+	delta := n - m
+	// with an odd delta the paths can only meet on a forward step
+	odd := delta%2 != 0
+	// the diagonals that ran off the edges are skipped
+	forwardStart, forwardEnd, backwardStart, backwardEnd := 0, 0, 0, 0
+	for d := 0; d < min(maxD, maxSearch); d++ {
+		for k := -d + forwardStart; k <= d-forwardEnd; k += 2 {
+			i := offset + k
+			var x1 int
+			if k == -d || (k != d && forward[i-1] < forward[i+1]) {
+				x1 = forward[i+1]
+			} else {
+				x1 = forward[i-1] + 1
+			}
+			y1 := x1 - k
+			for x1 < n && y1 < m && x[x1] == y[y1] {
+				x1++
+				y1++
+			}
+			forward[i] = x1
+
+			switch {
+			case x1 > n:
+				forwardEnd += 2
+			case y1 > m:
+				forwardStart += 2
+			case odd:
+				j := offset + delta - k
+				if j >= 0 && j < len(backward) && backward[j] != -1 && x1 >= n-backward[j] {
+					return x1, y1, true
+				}
+			}
+		}
+
+		for k := -d + backwardStart; k <= d-backwardEnd; k += 2 {
+			i := offset + k
+			var x2 int
+			if k == -d || (k != d && backward[i-1] < backward[i+1]) {
+				x2 = backward[i+1]
+			} else {
+				x2 = backward[i-1] + 1
+			}
+			y2 := x2 - k
+			for x2 < n && y2 < m && x[n-x2-1] == y[m-y2-1] {
+				x2++
+				y2++
+			}
+			backward[i] = x2
+
+			switch {
+			case x2 > n:
+				backwardEnd += 2
+			case y2 > m:
+				backwardStart += 2
+			case !odd:
+				j := offset + delta - k
+				if j >= 0 && j < len(forward) && forward[j] != -1 && forward[j] >= n-x2 {
+					x1 := forward[j]
+					return x1, x1 - (delta - k), true
+				}
+			}
+		}
+	}
+
+	return 0, 0, false
+}
+
+// groups the changed lines of an edit script into hunks, keeping up to
+// context unchanged lines around each change
+func Hunks(lines []Line, context int) []Hunk {
+	// line numbers (0-based) in the old and new text at which each line starts
+	oldPos, newPos := make([]int, len(lines)+1), make([]int, len(lines)+1)
+	for i, line := range lines {
+		oldPos[i+1], newPos[i+1] = oldPos[i], newPos[i]
+		if line.Op != Insert {
+			oldPos[i+1]++
+		}
+		if line.Op != Delete {
+			newPos[i+1]++
+		}
+	}
+
+	hunks := []Hunk{}
+	i := 0
+	for i < len(lines) {
+		for i < len(lines) && lines[i].Op == Equal {
+			i++
+		}
+		if i == len(lines) {
+			break
+		}
+
+		start, end := max(i-context, 0), i
+		for {
+			for end < len(lines) && lines[end].Op != Equal {
+				end++
+			}
+			next := end
+			for next < len(lines) && lines[next].Op == Equal {
+				next++
+			}
+			// merge with the next change unless the gap is wider than
+			// the context on both sides of it
+			if next == len(lines) || next-end > 2*context {
+				end = min(end+context, len(lines))
+				break
+			}
+			end = next
+		}
+
+		hunk := Hunk{
+			OldStart: oldPos[start] + 1,
+			OldLines: oldPos[end] - oldPos[start],
+			NewStart: newPos[start] + 1,
+			NewLines: newPos[end] - newPos[start],
+			Lines:    lines[start:end],
+		}
+		// an empty range refers to the line just before it
+		if hunk.OldLines == 0 {
+			hunk.OldStart--
+		}
+		if hunk.NewLines == 0 {
+			hunk.NewStart--
+		}
+		hunks = append(hunks, hunk)
+		i = end
+	}
+
+	return hunks
+}
+
+// formats the hunks in the unified diff format, or returns an empty
+// string if there are no changes
+func Unified(fromName, toName string, hunks []Hunk) string {
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+	for _, hunk := range hunks {
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", unifiedRange(hunk.OldStart, hunk.OldLines), unifiedRange(hunk.NewStart, hunk.NewLines))
+		for _, line := range hunk.Lines {
+			switch line.Op {
+			case Equal:
+				sb.WriteString(" ")
+			case Delete:
+				sb.WriteString("-")
+			case Insert:
+				sb.WriteString("+")
+			}
+			sb.WriteString(line.Text)
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
+
+func unifiedRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
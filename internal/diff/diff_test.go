@@ -0,0 +1,101 @@
+package diff
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLines(t *testing.T) {
+	assertions := assert.New(t)
+
+	lines := Lines("a\nb\nc\n", "a\nc\nd\n")
+	assertions.Equal([]Line{
+		{Op: Equal, Text: "a"},
+		{Op: Delete, Text: "b"},
+		{Op: Equal, Text: "c"},
+		{Op: Insert, Text: "d"},
+	}, lines)
+
+	assertions.Empty(Lines("", ""))
+	assertions.Equal([]Line{{Op: Insert, Text: "a"}}, Lines("", "a"))
+}
+
+func TestLinesShortest(t *testing.T) {
+	assertions := assert.New(t)
+
+	// lcs is the length of the longest common subsequence of a and b
+	lcs := func(a, b []string) int {
+		row := make([]int, len(b)+1)
+		for i := range a {
+			prev := 0
+			for j := range b {
+				next := row[j+1]
+				if a[i] == b[j] {
+					row[j+1] = prev + 1
+				} else {
+					row[j+1] = max(row[j+1], row[j])
+				}
+				prev = next
+			}
+		}
+		return row[len(b)]
+	}
+
+	random := rand.New(rand.NewSource(1))
+	text := func() string {
+		lines := make([]string, random.Intn(30))
+		for i := range lines {
+			lines[i] = string(rune('a' + random.Intn(4)))
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	for i := 0; i < 500; i++ {
+		a, b := text(), text()
+		var oldLines, newLines []string
+		changes := 0
+		for _, line := range Lines(a, b) {
+			if line.Op != Insert {
+				oldLines = append(oldLines, line.Text)
+			}
+			if line.Op != Delete {
+				newLines = append(newLines, line.Text)
+			}
+			if line.Op != Equal {
+				changes++
+			}
+		}
+		assertions.Equal(splitLines(a), oldLines, "Wrong old lines for %q -> %q", a, b)
+		assertions.Equal(splitLines(b), newLines, "Wrong new lines for %q -> %q", a, b)
+		assertions.Equal(len(oldLines)+len(newLines)-2*lcs(oldLines, newLines), changes, "Not a shortest diff for %q -> %q", a, b)
+	}
+}
+
+func TestUnified(t *testing.T) {
+	assertions := assert.New(t)
+
+	// Test cases
+	testCases := []struct {
+		old    string
+		new    string
+		expect string
+	}{
+		{"same", "same", ""},
+		{"a\nb\nc", "a\nB\nc", "--- old\n+++ new\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n"},
+		{"", "a\nb", "--- old\n+++ new\n@@ -0,0 +1,2 @@\n+a\n+b\n"},
+		{"1\n2\n3\n4\n5\n6\n7\n8\n9\n10", "0\n2\n3\n4\n5\n6\n7\n8\n9\n10", "--- old\n+++ new\n@@ -1,4 +1,4 @@\n-1\n+0\n 2\n 3\n 4\n"},
+		{
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n10",
+			"0\n2\n3\n4\n5\n6\n7\n8\n9\n11",
+			"--- old\n+++ new\n@@ -1,4 +1,4 @@\n-1\n+0\n 2\n 3\n 4\n@@ -7,4 +7,4 @@\n 7\n 8\n 9\n-10\n+11\n",
+		},
+	}
+
+	for _, testCase := range testCases {
+		unified := Unified("old", "new", Hunks(Lines(testCase.old, testCase.new), 3))
+		assertions.Equal(testCase.expect, unified, "Unexpected diff for %q -> %q", testCase.old, testCase.new)
+	}
+}
@@ -0,0 +1,258 @@
+// Package jwtkeys manages the keys the JWTs of the service are signed and
+// verified with. Tokens are signed with a single key and carry its ID in
+// the kid header, while older keys keep verifying the tokens they signed
+// until those expire, so keys can be rotated without logging users out.
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// RSA keys shorter than this are rejected
+const minRSABits = 2048
+
+// Key is a key tokens are signed or verified with
+type Key struct {
+	// ID is the kid header of the tokens it signs, empty for the HMAC
+	// secret of tokens issued before keys had IDs
+	ID     string
+	Method jwt.SigningMethod
+	// private signs tokens, and is only known for the signing key
+	private any
+	// public verifies tokens, it is the secret itself for HMAC
+	public any
+}
+
+// KeySet is the signing key and the keys tokens are verified with
+type KeySet struct {
+	signing *Key
+	keys    map[string]*Key
+}
+
+// NewHMAC returns a key set signing and verifying with a shared secret.
+// The secret can't be published, so services can't verify the tokens
+// without knowing it.
+func NewHMAC(secret string) *KeySet {
+	key := &Key{
+		Method:  jwt.SigningMethodHS256,
+		private: []byte(secret),
+		public:  []byte(secret),
+	}
+	return &KeySet{signing: key, keys: map[string]*Key{"": key}}
+}
+
+// Load returns a key set signing with the RSA or Ed25519 private key in the
+// PEM file, and verifying with it and the keys of the other files, which
+// can hold public or private keys. If hmacSecret is set, tokens signed with
+// it before are still verified.
+func Load(signingKeyFile string, verificationKeyFiles []string, hmacSecret string) (*KeySet, error) {
+	signing, err := loadKey(signingKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	if signing.private == nil {
+		return nil, fmt.Errorf("signing key %s is not a private key", signingKeyFile)
+	}
+
+	keySet := &KeySet{signing: signing, keys: map[string]*Key{signing.ID: signing}}
+	for _, file := range verificationKeyFiles {
+		key, err := loadKey(file)
+		if err != nil {
+			return nil, err
+		}
+		// only the public key of old signing keys is needed
+		key.private = nil
+		if _, ok := keySet.keys[key.ID]; !ok {
+			keySet.keys[key.ID] = key
+		}
+	}
+
+	if hmacSecret != "" {
+		keySet.keys[""] = &Key{Method: jwt.SigningMethodHS256, public: []byte(hmacSecret)}
+	}
+	return keySet, nil
+}
+
+func loadKey(file string) (*Key, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM key in %s", file)
+	}
+
+	var parsed any
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q in %s", block.Type, file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse key in %s: %w", file, err)
+	}
+
+	key, err := newKey(parsed)
+	if err != nil {
+		return nil, fmt.Errorf("key in %s: %w", file, err)
+	}
+	return key, nil
+}
+
+// newKey returns the key of a parsed private or public key
+func newKey(parsed any) (*Key, error) {
+	key := &Key{}
+	if signer, ok := parsed.(crypto.Signer); ok {
+		key.private = signer
+		parsed = signer.Public()
+	}
+
+	switch public := parsed.(type) {
+	case *rsa.PublicKey:
+		if public.N.BitLen() < minRSABits {
+			return nil, fmt.Errorf("RSA key must have at least %d bits", minRSABits)
+		}
+		key.Method = jwt.SigningMethodRS256
+		key.public = public
+	case ed25519.PublicKey:
+		key.Method = jwt.SigningMethodEdDSA
+		key.public = public
+	default:
+		return nil, fmt.Errorf("unsupported key type %T, must be RSA or Ed25519", parsed)
+	}
+
+	key.ID = thumbprint(key.JWK())
+	return key, nil
+}
+
+// Sign returns the token of the claims, signed with the signing key
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.signing.Method, claims)
+	if ks.signing.ID != "" {
+		token.Header["kid"] = ks.signing.ID
+	}
+	return token.SignedString(ks.signing.private)
+}
+
+// Keyfunc returns the key to verify the token with, chosen by its kid
+// header. The algorithm of the token must be the one of the key, so a
+// public key can't be passed off as an HMAC secret.
+func (ks *KeySet) Keyfunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s for key %q", token.Method.Alg(), kid)
+	}
+	return key.public, nil
+}
+
+// Methods returns the algorithms of the keys, to be passed to
+// jwt.WithValidMethods
+func (ks *KeySet) Methods() []string {
+	var methods []string
+	seen := make(map[string]bool)
+	for _, key := range ks.keys {
+		if alg := key.Method.Alg(); !seen[alg] {
+			seen[alg] = true
+			methods = append(methods, alg)
+		}
+	}
+	return methods
+}
+
+// JWK is a public key in the JSON Web Key format of RFC 7517
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS is the document other services verify our tokens with
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWK returns the public key as a JWK, or an empty one for HMAC secrets
+func (k *Key) JWK() JWK {
+	jwk := JWK{Kid: k.ID, Use: "sig", Alg: k.Method.Alg()}
+	switch public := k.public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	default:
+		return JWK{}
+	}
+	return jwk
+}
+
+// JWKS returns the public keys, the signing key first. HMAC secrets are
+// left out.
+func (ks *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	if jwk := ks.signing.JWK(); jwk.Kty != "" {
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	var ids []string
+	for id := range ks.keys {
+		if id != ks.signing.ID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if jwk := ks.keys[id].JWK(); jwk.Kty != "" {
+			jwks.Keys = append(jwks.Keys, jwk)
+		}
+	}
+	return jwks
+}
+
+// thumbprint returns the JWK thumbprint of RFC 7638, which identifies a
+// key by its public parts
+func thumbprint(jwk JWK) string {
+	var canonical string
+	switch jwk.Kty {
+	case "RSA":
+		canonical = fmt.Sprintf(`{"e":%q,"kty":"RSA","n":%q}`, jwk.E, jwk.N)
+	case "OKP":
+		canonical = fmt.Sprintf(`{"crv":%q,"kty":"OKP","x":%q}`, jwk.Crv, jwk.X)
+	}
+	sum := sha256.Sum256([]byte(canonical))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
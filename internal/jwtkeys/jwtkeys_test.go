@@ -0,0 +1,112 @@
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeKey writes the key to a PEM file, as a private key or only its
+// public key
+func writeKey(t *testing.T, key any, public bool) string {
+	var block *pem.Block
+	if public {
+		der, err := x509.MarshalPKIXPublicKey(key)
+		require.NoError(t, err)
+		block = &pem.Block{Type: "PUBLIC KEY", Bytes: der}
+	} else {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		require.NoError(t, err)
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	}
+
+	file, err := os.CreateTemp(t.TempDir(), "*.pem")
+	require.NoError(t, err)
+	defer file.Close()
+	require.NoError(t, pem.Encode(file, block))
+	return file.Name()
+}
+
+func parse(t *testing.T, keySet *KeySet, token string) error {
+	_, err := jwt.Parse(token, keySet.Keyfunc, jwt.WithValidMethods(keySet.Methods()))
+	return err
+}
+
+func TestRotation(t *testing.T) {
+	_, oldKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	claims := jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))}
+
+	hmac := NewHMAC("secret")
+	hmacToken, err := hmac.Sign(claims)
+	require.NoError(t, err)
+	assert.Empty(t, hmac.JWKS().Keys)
+
+	oldKeys, err := Load(writeKey(t, oldKey, false), nil, "secret")
+	require.NoError(t, err)
+	oldToken, err := oldKeys.Sign(claims)
+	require.NoError(t, err)
+	assert.NoError(t, parse(t, oldKeys, hmacToken))
+
+	// once the new key signs, tokens of the old key are still valid
+	newKeys, err := Load(writeKey(t, newKey, false), []string{writeKey(t, oldKey.Public(), true)}, "")
+	require.NoError(t, err)
+	newToken, err := newKeys.Sign(claims)
+	require.NoError(t, err)
+
+	assert.NoError(t, parse(t, newKeys, newToken))
+	assert.NoError(t, parse(t, newKeys, oldToken))
+	assert.Error(t, parse(t, newKeys, hmacToken))
+	assert.Error(t, parse(t, oldKeys, newToken))
+
+	token, _, err := jwt.NewParser().ParseUnverified(newToken, &jwt.RegisteredClaims{})
+	require.NoError(t, err)
+	assert.Equal(t, "RS256", token.Header["alg"])
+
+	jwks := newKeys.JWKS()
+	require.Len(t, jwks.Keys, 2)
+	assert.Equal(t, token.Header["kid"], jwks.Keys[0].Kid)
+	assert.Equal(t, "RSA", jwks.Keys[0].Kty)
+	assert.Equal(t, "AQAB", jwks.Keys[0].E)
+	assert.Equal(t, "OKP", jwks.Keys[1].Kty)
+	assert.Equal(t, "EdDSA", jwks.Keys[1].Alg)
+
+	// a public key is not a signing key
+	_, err = Load(writeKey(t, oldKey.Public(), true), nil, "")
+	assert.Error(t, err)
+
+	small, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	_, err = Load(writeKey(t, small, false), nil, "")
+	assert.Error(t, err)
+
+	_, err = Load(filepath.Join(t.TempDir(), "missing.pem"), nil, "")
+	assert.Error(t, err)
+}
+
+// the examples of RFC 7638 and RFC 8037
+func TestThumbprint(t *testing.T) {
+	assert.Equal(t, "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs", thumbprint(JWK{
+		Kty: "RSA",
+		N:   "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+		E:   "AQAB",
+	}))
+	assert.Equal(t, "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k", thumbprint(JWK{
+		Kty: "OKP",
+		Crv: "Ed25519",
+		X:   "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo",
+	}))
+}
@@ -0,0 +1,55 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails to users
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+const (
+	DriverSMTP = "smtp"
+	DriverFile = "file"
+	DriverLog  = "log"
+)
+
+type Config struct {
+	// Driver is one of smtp, file or log
+	Driver string
+	From   string
+	// SMTP server, used by the smtp driver
+	Host     string
+	Port     int
+	Username string
+	Password string
+	// Dir is where the file driver writes emails
+	Dir string
+}
+
+// New returns the mailer for the configured driver
+func New(config Config) (Mailer, error) {
+	switch config.Driver {
+	case DriverSMTP:
+		return NewSMTPMailer(config.Host, config.Port, config.Username, config.Password, config.From), nil
+	case DriverFile:
+		return NewFileMailer(config.Dir, config.From), nil
+	case DriverLog, "":
+		return NewLogMailer(), nil
+	}
+	return nil, fmt.Errorf("unknown mail driver %q", config.Driver)
+}
+
+// formats the message as an RFC 5322 email
+func format(from string, msg Message) []byte {
+	return []byte(fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%s\r\n",
+		from, msg.To, msg.Subject, msg.Body))
+}
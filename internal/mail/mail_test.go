@@ -0,0 +1,31 @@
+package mail
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	mailer, err := New(Config{Driver: DriverFile, Dir: dir, From: "notes@example.com"})
+	require.NoError(t, err)
+
+	err = mailer.Send(context.Background(), Message{To: "user@example.com", Subject: "Hello", Body: "body"})
+	require.NoError(t, err)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	content, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	assert.Equal(t, "From: notes@example.com\r\nTo: user@example.com\r\nSubject: Hello\r\nContent-Type: text/plain; charset=utf-8\r\n\r\nbody\r\n", string(content))
+
+	_, err = New(Config{Driver: "pigeon"})
+	assert.Error(t, err)
+}
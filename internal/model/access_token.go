@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+)
+
+// AccessTokenPrefix starts every personal access token, telling them apart
+// from JWTs
+const AccessTokenPrefix = "pat_"
+
+// scopes of personal access tokens
+const (
+	ScopeNotesRead  = "notes:read"
+	ScopeNotesWrite = "notes:write"
+	ScopeNotesShare = "notes:share"
+)
+
+type PersonalAccessToken struct {
+	ID         int32      `json:"id"`
+	UserID     int32      `json:"-"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	// Token is only known when the token is created
+	Token string `json:"token,omitempty"`
+}
+
+type PersonalAccessTokenDTO struct {
+	UserID    int32      `json:"-"`
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
@@ -0,0 +1,63 @@
+package model
+
+import "time"
+
+// UserWithUsage is a user as admins see them, with how much they store
+type UserWithUsage struct {
+	User
+	// NoteCount doesn't include notes in the trash
+	NoteCount int64 `json:"note_count"`
+	// StorageBytes is the size of the titles and contents of all the notes
+	// of the user, including the trash and past revisions
+	StorageBytes int64 `json:"storage_bytes"`
+}
+
+type UserList struct {
+	Users []UserWithUsage `json:"users"`
+	// NextCursor is empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// AdminPage is a page of an admin listing
+type AdminPage struct {
+	// Cursor is the opaque next_cursor of the previous page, empty for the
+	// first page
+	Cursor string
+	Limit  int32
+}
+
+// AuditEntry records a request an admin made to the admin API
+type AuditEntry struct {
+	ID int32 `json:"id"`
+	// ActorID is nil once the admin is deleted
+	ActorID *int32 `json:"actor_id"`
+	// Action is the method and route of the request, e.g.
+	// "POST /api/admin/users/:id/disable"
+	Action       string `json:"action"`
+	TargetUserID *int32 `json:"target_user_id"`
+	Details      string `json:"details"`
+	IP           string `json:"ip"`
+	// Status is the status of the response to the request
+	Status    int32     `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type AuditEntryDTO struct {
+	ActorID      int32
+	Action       string
+	TargetUserID *int32
+	Details      string
+	IP           string
+	Status       int32
+}
+
+type AuditFilter struct {
+	ActorID      *int32
+	TargetUserID *int32
+}
+
+type AuditLog struct {
+	Entries []AuditEntry `json:"entries"`
+	// NextCursor is empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
@@ -2,6 +2,9 @@ package model
 
 import (
 	"time"
+
+	"notes/internal/diff"
+	"notes/internal/searchquery"
 )
 
 type Note struct {
@@ -11,16 +14,176 @@ type Note struct {
 	Content   string    `json:"content"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	// DeletedAt is set while the note is in the trash
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"`
+	Tags       []string   `json:"tags"`
+	NotebookID *int32     `json:"notebook_id"`
+	// Language is the text search config the note is searched with
+	Language string `json:"language"`
 }
 
 type NoteDTO struct {
 	UserID  int32  `json:"-"`
 	Title   string `json:"title"`
 	Content string `json:"content"`
+	// Tags replaces the tags of the note. When nil, an update leaves the
+	// existing tags untouched.
+	Tags []string `json:"tags"`
+	// NotebookID places a new note in a notebook. It is ignored on update,
+	// notes are moved between notebooks with NoteMoveDTO.
+	NotebookID *int32 `json:"notebook_id"`
+	// Language is the text search config of the note. A new note defaults
+	// to the search language of the user, an update keeps the language
+	// when empty.
+	Language string `json:"language"`
+}
+
+type NoteMoveDTO struct {
+	// NotebookID is nil to move the note out of any notebook
+	NotebookID *int32 `json:"notebook_id"`
+}
+
+type NoteFilter struct {
+	// Tags restricts the listing to notes carrying these tags
+	Tags []string
+	// MatchAny selects notes with at least one of Tags instead of all of them
+	MatchAny bool
+	// NotebookID restricts the listing to notes in this notebook
+	NotebookID *int32
+	// Recursive also includes the notes of notebooks nested in NotebookID
+	Recursive bool
 }
 
+// Share roles, from least to most privileged. Only the owner can delete,
+// share or change the roles of a note.
+const (
+	RoleViewer    = "viewer"
+	RoleCommenter = "commenter"
+	RoleEditor    = "editor"
+)
+
 type NoteShareDTO struct {
 	NoteID     int32  `param:"note_id" json:"-"`
 	UserID     int32  `json:"-"`
 	SharedWith string `json:"shared_with"`
+	// Role defaults to viewer, sharing again changes the role
+	Role string `json:"role"`
+}
+
+// ShareInvitation is a note shared with an email that has not signed up
+// yet. It turns into a share when the email signs up.
+type ShareInvitation struct {
+	ID        int32     `json:"id"`
+	NoteID    int32     `json:"note_id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NoteShare is a collaborator of a note
+type NoteShare struct {
+	UserID   int32  `json:"user_id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Role     string `json:"role"`
+}
+
+const (
+	SortByCreatedAt = "created_at"
+	SortByUpdatedAt = "updated_at"
+	SortByTitle     = "title"
+	// SortByRelevance orders search results by their score
+	SortByRelevance = "relevance"
+)
+
+type NotePage struct {
+	// Cursor is the opaque next_cursor of the previous page, empty for the
+	// first page
+	Cursor string
+	Limit  int32
+	SortBy string
+	Desc   bool
+}
+
+type NoteList struct {
+	Notes []Note `json:"notes"`
+	// NextCursor is empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// how the query of a search matches notes
+const (
+	// SearchModeFullText matches the words of the query, and their other
+	// forms, in the title and content
+	SearchModeFullText = "fulltext"
+	// SearchModeFuzzy matches titles and contents similar to the query,
+	// despite typos and partial words
+	SearchModeFuzzy = "fuzzy"
+)
+
+type NoteSearch struct {
+	Query string
+	// Expr is the parsed Query of a full-text search, nil to match every
+	// note
+	Expr searchquery.Node
+	Mode string
+	// Threshold is how similar, from 0 to 1, notes must be to the query to
+	// match in fuzzy mode
+	Threshold float64
+	// HighlightStart and HighlightStop surround the words matching the
+	// query in the snippets of full-text results
+	HighlightStart string
+	HighlightStop  string
+	// Language is the text search config the query of a full-text search
+	// is read with, restricting it to the notes in that language. When
+	// empty, the query is read in the language of each note.
+	Language string
+}
+
+type SearchResult struct {
+	Note
+	// Score is how similar, from 0 to 1, the note is to the query of a
+	// fuzzy search
+	Score float64 `json:"score,omitempty"`
+	// Rank is how well the note matches the query of a full-text search,
+	// matches in the title counting more than in the content
+	Rank float64 `json:"rank,omitempty"`
+	// Snippets are the parts of the note matching a full-text search
+	Snippets *SearchSnippets `json:"snippets,omitempty"`
+}
+
+// SearchSnippets are the title and excerpts of the content of a note, with
+// the words matching the query highlighted. Their text is HTML-escaped, the
+// highlight markers are not.
+type SearchSnippets struct {
+	Title   string `json:"title"`
+	Content string `json:"content"`
+}
+
+type SearchResultList struct {
+	Notes []SearchResult `json:"notes"`
+	// NextCursor is empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+type NoteRevision struct {
+	NoteID    int32     `json:"note_id"`
+	Revision  int32     `json:"revision"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type NoteDiff struct {
+	NoteID int32 `json:"note_id"`
+	From   int32 `json:"from"`
+	// To is nil when the diff is against the current version of the note
+	To      *int32        `json:"to"`
+	Title   NoteFieldDiff `json:"title"`
+	Content NoteFieldDiff `json:"content"`
+}
+
+type NoteFieldDiff struct {
+	Unified string      `json:"unified"`
+	Hunks   []diff.Hunk `json:"hunks"`
 }
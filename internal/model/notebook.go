@@ -0,0 +1,33 @@
+package model
+
+import (
+	"time"
+)
+
+type Notebook struct {
+	ID        int32     `json:"id"`
+	UserID    int32     `json:"user_id"`
+	ParentID  *int32    `json:"parent_id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type NotebookDTO struct {
+	UserID   int32  `json:"-"`
+	ParentID *int32 `json:"parent_id"`
+	Name     string `json:"name"`
+}
+
+// NotebookShare is a user a notebook is shared with
+type NotebookShare struct {
+	UserID   int32  `json:"user_id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+}
+
+type NotebookShareDTO struct {
+	NotebookID int32  `json:"-"`
+	UserID     int32  `json:"-"`
+	SharedWith string `json:"shared_with"`
+}
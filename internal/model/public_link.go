@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+)
+
+type PublicLink struct {
+	ID          int32      `json:"id"`
+	NoteID      int32      `json:"note_id"`
+	Token       string     `json:"token"`
+	HasPassword bool       `json:"has_password"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type PublicLinkDTO struct {
+	NoteID int32 `json:"-"`
+	UserID int32 `json:"-"`
+	// Password is optional, visitors must provide it to see the note
+	Password  string     `json:"password"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// PublicNote is what visitors of a public link see of a note
+type PublicNote struct {
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
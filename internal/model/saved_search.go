@@ -0,0 +1,37 @@
+package model
+
+import (
+	"time"
+)
+
+// SavedSearch is a search the user runs again by its ID, with the same
+// parameters as GET /api/notes/search
+type SavedSearch struct {
+	ID        int32   `json:"id"`
+	UserID    int32   `json:"user_id"`
+	Name      string  `json:"name"`
+	Query     string  `json:"query"`
+	Mode      string  `json:"mode"`
+	Threshold float64 `json:"threshold"`
+	// Language is empty to read the query in the search language the user
+	// has when it runs
+	Language  string    `json:"language"`
+	CreatedAt time.Time `json:"created_at"`
+	// LastViewedAt is when the results of the search were last viewed, nil
+	// if they never were
+	LastViewedAt *time.Time `json:"last_viewed_at"`
+	// Unread is the number of notes matching the search updated since
+	// LastViewedAt. It is only counted when asked for.
+	Unread *int64 `json:"unread,omitempty"`
+}
+
+type SavedSearchDTO struct {
+	UserID int32  `json:"-"`
+	Name   string `json:"name"`
+	Query  string `json:"query"`
+	// Mode defaults to fulltext
+	Mode string `json:"mode"`
+	// Threshold defaults to the one of searches when nil
+	Threshold *float64 `json:"threshold"`
+	Language  string   `json:"language"`
+}
@@ -0,0 +1,10 @@
+package model
+
+type Tag struct {
+	Name      string `json:"name"`
+	NoteCount int64  `json:"note_count"`
+}
+
+type TagRenameDTO struct {
+	Name string `json:"name"`
+}
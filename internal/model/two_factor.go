@@ -0,0 +1,49 @@
+package model
+
+import (
+	"time"
+)
+
+// TOTP is the authenticator app secret of a user. Two-factor
+// authentication is enabled once the first code was confirmed.
+type TOTP struct {
+	UserID    int32
+	Secret    string
+	EnabledAt *time.Time
+}
+
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	// URI is shown as a QR code for authenticator apps to scan
+	URI string `json:"otpauth_uri"`
+}
+
+type RecoveryCodes struct {
+	Codes []string `json:"recovery_codes"`
+}
+
+// LoginChallenge is returned by a login with the right password when the
+// user has two-factor authentication, to be completed with a code
+type LoginChallenge struct {
+	ChallengeToken string    `json:"challenge_token"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+type EnrollTOTPDTO struct {
+	Password string `json:"password"`
+}
+
+type TOTPCodeDTO struct {
+	Code string `json:"code"`
+}
+
+type DisableTOTPDTO struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+type LogInTOTPDTO struct {
+	ChallengeToken string `json:"challenge_token"`
+	// Code is a code of the authenticator app or a recovery code
+	Code string `json:"code"`
+}
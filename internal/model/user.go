@@ -5,13 +5,26 @@ import (
 )
 
 type User struct {
-	ID           int32     `json:"id"`
-	Username     string    `json:"username"`
-	Email        string    `json:"email"`
-	CreatedAt    time.Time `json:"created_at"`
-	PasswordHash string    `json:"-"`
+	ID              int32      `json:"id"`
+	Username        string     `json:"username"`
+	Email           string     `json:"email"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	PasswordHash    string     `json:"-"`
+	Role            string     `json:"role"`
+	// DisabledAt is set while an admin keeps the user from logging in
+	DisabledAt *time.Time `json:"disabled_at,omitempty"`
+	// SearchLanguage is the text search config new notes of the user
+	// default to
+	SearchLanguage string `json:"search_language"`
 }
 
+// roles of users, admins can manage other users through the admin API
+const (
+	UserRoleUser  = "user"
+	UserRoleAdmin = "admin"
+)
+
 type UserCreateDTO struct {
 	Username string `json:"username"`
 	Email    string `json:"email"`
@@ -22,3 +35,87 @@ type LogInDTO struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 }
+
+// Session identifies the tokens issued to a logged in user. TokenID is the
+// jti of the access token, RefreshToken is only known when it is issued.
+type Session struct {
+	UserID       int32
+	TokenID      string
+	RefreshToken string
+}
+
+// LoginLockout is how failed logins lock out further ones. Once an account
+// or an IP reaches its threshold of failures, logins are locked for Delay,
+// doubling with each further failure up to MaxDelay. Failures are forgotten
+// after Window without any. A threshold that isn't positive disables the
+// lockout of accounts or IPs.
+type LoginLockout struct {
+	AccountThreshold int
+	IPThreshold      int
+	Delay            time.Duration
+	MaxDelay         time.Duration
+	Window           time.Duration
+}
+
+type AuthTokens struct {
+	Token        string    `json:"token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+type RefreshDTO struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// DefaultSearchLanguage is the search language of new users
+const DefaultSearchLanguage = "english"
+
+type UpdateUserDTO struct {
+	Username string `json:"username"`
+	// SearchLanguage is left unchanged when empty
+	SearchLanguage string `json:"search_language"`
+}
+
+type ChangePasswordDTO struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type ChangeEmailDTO struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// what happens to the notes a deleted user shared with others
+const (
+	SharedNotesDelete   = "delete"
+	SharedNotesTransfer = "transfer"
+)
+
+type DeleteUserDTO struct {
+	Password    string `json:"password"`
+	SharedNotes string `json:"shared_notes"`
+}
+
+type VerifyEmailDTO struct {
+	Email string `json:"email"`
+}
+
+type ForgotPasswordDTO struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordDTO struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// Identity is who an identity provider says the user is
+type Identity struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	// Username is suggested by the provider for new users
+	Username string
+}
@@ -0,0 +1,60 @@
+package oidc_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"notes/internal/oidc"
+	"notes/internal/oidc/oidctest"
+)
+
+func TestProvider(t *testing.T) {
+	issuer := oidctest.NewIssuer("notes")
+	defer issuer.Close()
+	issuer.SetUser(oidctest.User{Subject: "1234", Email: "test@example.com", EmailVerified: true})
+
+	ctx := context.Background()
+	provider, err := oidc.Discover(ctx, oidc.Config{
+		Issuer:      issuer.URL,
+		ClientID:    "notes",
+		RedirectURL: "http://localhost:8080/callback",
+	})
+	require.NoError(t, err)
+
+	// authorize returns the code the issuer redirects back with
+	authorize := func(nonce, verifier string) string {
+		client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+		resp, err := client.Get(provider.AuthCodeURL("state", nonce, verifier))
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusFound, resp.StatusCode)
+
+		redirect, err := url.Parse(resp.Header.Get("Location"))
+		require.NoError(t, err)
+		assert.Equal(t, "state", redirect.Query().Get("state"))
+		return redirect.Query().Get("code")
+	}
+
+	verifier, err := oidc.NewCodeVerifier()
+	require.NoError(t, err)
+
+	claims, err := provider.Exchange(ctx, authorize("nonce", verifier), verifier, "nonce")
+	require.NoError(t, err)
+	assert.Equal(t, "1234", claims.Subject)
+	assert.Equal(t, "test@example.com", claims.Email)
+	assert.True(t, claims.EmailVerified)
+
+	_, err = provider.Exchange(ctx, authorize("nonce", verifier), "wrong verifier", "nonce")
+	assert.Error(t, err)
+
+	_, err = provider.Exchange(ctx, authorize("nonce", verifier), verifier, "other nonce")
+	assert.Error(t, err)
+
+	_, err = oidc.Discover(ctx, oidc.Config{Issuer: issuer.URL + "/other", ClientID: "notes"})
+	assert.Error(t, err)
+}
@@ -5,3 +5,27 @@ import (
 )
 
 var ErrNotFound = errors.New("not found")
+
+// ErrForbidden is returned when the user can see a note but their share role
+// does not allow the operation.
+var ErrForbidden = errors.New("forbidden")
+
+// ErrInvalidParent is returned when a notebook would be nested inside
+// itself or inside a notebook that does not belong to the same user.
+var ErrInvalidParent = errors.New("invalid parent notebook")
+
+// ErrInvalidCursor is returned when a pagination cursor is malformed or was
+// issued for a different sort order.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ErrInvalidPassword is returned when a password protected resource is
+// accessed with a missing or wrong password.
+var ErrInvalidPassword = errors.New("invalid password")
+
+// ErrInvalidToken is returned when a token is unknown, expired, revoked or
+// already used.
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// ErrAlreadyExists is returned when a username or email is already taken by
+// another user.
+var ErrAlreadyExists = errors.New("already exists")
@@ -2,26 +2,235 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"notes/internal/model"
 )
 
 type UserRepository interface {
 	CreateUser(context.Context, model.UserCreateDTO) (*model.User, error)
+	// GetUserByEmailAndPassword returns ErrInvalidPassword both for unknown
+	// emails and wrong passwords, so logins don't tell which emails have
+	// an account.
 	GetUserByEmailAndPassword(context.Context, model.LogInDTO) (*model.User, error)
+	// CreateEmailVerificationToken returns a token, valid for ttl, to verify
+	// the email of a user. It returns ErrNotFound if no user has the email
+	// or it is already verified.
+	CreateEmailVerificationToken(ctx context.Context, email string, ttl time.Duration) (string, error)
+	// VerifyEmail sets the email the token was sent to as the verified email
+	// of its user. It returns ErrAlreadyExists if another user has taken
+	// the email since.
+	VerifyEmail(ctx context.Context, token string) (*model.User, error)
+	// GetUserByIdentity returns the user the identity is linked to. The
+	// first time, the identity is linked to the user with the same email if
+	// both sides verified it, or to a new user if signUp is allowed.
+	// Otherwise it returns ErrAlreadyExists if the email is taken, or
+	// ErrNotFound.
+	GetUserByIdentity(ctx context.Context, identity model.Identity, signUp bool) (*model.User, error)
+	GetUser(ctx context.Context, userID int32) (*model.User, error)
+	UpdateProfile(ctx context.Context, userID int32, profile model.UpdateUserDTO) (*model.User, error)
+	// CheckPassword returns ErrInvalidPassword if the password of the user
+	// is wrong.
+	CheckPassword(ctx context.Context, userID int32, password string) error
+	// ChangePassword sets a new password after checking the current one,
+	// returning ErrInvalidPassword if it is wrong.
+	ChangePassword(ctx context.Context, userID int32, currentPassword, newPassword string) error
+	// CreateEmailChangeToken checks the password and returns a token, valid
+	// for ttl, that changes the email of the user once it is verified.
+	CreateEmailChangeToken(ctx context.Context, userID int32, password, email string, ttl time.Duration) (string, error)
+	// DeleteUser deletes the user and their notes after checking the
+	// password. With transferSharedNotes, each note shared with others is
+	// given to the collaborator with the highest role instead.
+	DeleteUser(ctx context.Context, userID int32, password string, transferSharedNotes bool) error
+	// CreatePasswordResetToken returns a token, valid for ttl, to reset the
+	// password of the user with the email.
+	CreatePasswordResetToken(ctx context.Context, email string, ttl time.Duration) (string, error)
+	// ResetPassword sets the password of the user the token was issued to
+	// and logs out their sessions. The user's reset tokens are used up.
+	ResetPassword(ctx context.Context, token, password string) error
+}
+
+type SessionRepository interface {
+	// CreateSession starts a session for the user, issuing a refresh token
+	// valid for ttl.
+	CreateSession(ctx context.Context, userID int32, ttl time.Duration) (*model.Session, error)
+	// RefreshSession exchanges a refresh token for a new one in the same
+	// session. Presenting a refresh token twice revokes the whole session.
+	RefreshSession(ctx context.Context, refreshToken string, ttl time.Duration) (*model.Session, error)
+	RevokeSession(ctx context.Context, userID int32, tokenID string) error
+	RevokeAllSessions(ctx context.Context, userID int32) error
+	// RevokeOtherSessions ends all the sessions of the user but the one the
+	// access token belongs to.
+	RevokeOtherSessions(ctx context.Context, userID int32, tokenID string) error
+	// IsTokenActive reports whether the session of the access token has not
+	// ended and its user is not disabled.
+	IsTokenActive(ctx context.Context, tokenID string) (bool, error)
+	PurgeExpiredSessions(ctx context.Context) (int64, error)
+}
+
+type LoginFailureRepository interface {
+	// LoginLockedUntil returns until when logins with the email, or from the
+	// IP, are locked, or nil if they are allowed.
+	LoginLockedUntil(ctx context.Context, email, ip string) (*time.Time, error)
+	// RecordLoginFailure counts a failed login with the email from the IP,
+	// whether or not a user has the email. It returns until when logins are
+	// now locked according to lockout, or nil.
+	RecordLoginFailure(ctx context.Context, email, ip string, lockout model.LoginLockout) (*time.Time, error)
+	// ClearLoginFailures forgets the failed logins with the email, and from
+	// the IP, after a successful one, so that the occasional typos of users
+	// sharing an IP don't add up to a lockout.
+	ClearLoginFailures(ctx context.Context, email, ip string) error
+	// PurgeLoginFailures removes failures older than window which no longer
+	// lock logins.
+	PurgeLoginFailures(ctx context.Context, window time.Duration) (int64, error)
+}
+
+type AccessTokenRepository interface {
+	CreateAccessToken(context.Context, model.PersonalAccessTokenDTO) (*model.PersonalAccessToken, error)
+	ListAccessTokens(ctx context.Context, userID int32) ([]model.PersonalAccessToken, error)
+	RevokeAccessToken(ctx context.Context, userID, tokenID int32) error
+	// UseAccessToken returns the unexpired personal access token and records
+	// that it was used. It returns ErrInvalidToken for unknown tokens.
+	UseAccessToken(ctx context.Context, token string) (*model.PersonalAccessToken, error)
+}
+
+type TwoFactorRepository interface {
+	// GetTOTP returns the TOTP secret of the user, enabled or not. It
+	// returns ErrNotFound if the user never enrolled.
+	GetTOTP(ctx context.Context, userID int32) (*model.TOTP, error)
+	// StartTOTPEnrollment checks the password and keeps the secret until
+	// EnableTOTP. It returns ErrAlreadyExists if two-factor authentication
+	// is already enabled.
+	StartTOTPEnrollment(ctx context.Context, userID int32, password, secret string) error
+	// EnableTOTP enables two-factor authentication once the code of the
+	// period counter was confirmed, and returns new recovery codes.
+	EnableTOTP(ctx context.Context, userID int32, counter int64) ([]string, error)
+	// UseTOTP records that the code of the period counter was used. It
+	// returns ErrInvalidToken if the code of that period or a later one
+	// already was, so codes can't be replayed.
+	UseTOTP(ctx context.Context, userID int32, counter int64) error
+	// UseRecoveryCode uses up a recovery code of the user, returning
+	// ErrInvalidToken if it is unknown or already used.
+	UseRecoveryCode(ctx context.Context, userID int32, code string) error
+	// RegenerateRecoveryCodes replaces the recovery codes of the user.
+	RegenerateRecoveryCodes(ctx context.Context, userID int32) ([]string, error)
+	// DisableTOTP removes the TOTP secret and recovery codes of the user.
+	DisableTOTP(ctx context.Context, userID int32) error
+	// CreateLoginChallenge returns a token, valid for ttl, to complete the
+	// login of the user with a code.
+	CreateLoginChallenge(ctx context.Context, userID int32, ttl time.Duration) (string, error)
+	// UseLoginChallenge returns the user the challenge was issued to. A
+	// challenge can only be used once.
+	UseLoginChallenge(ctx context.Context, token string) (int32, error)
+}
+
+type AdminRepository interface {
+	// ListUsers returns the users whose username or email contains search,
+	// or all users if it is empty, ordered by ID.
+	ListUsers(ctx context.Context, search string, page model.AdminPage) (*model.UserList, error)
+	GetUserWithUsage(ctx context.Context, userID int32) (*model.UserWithUsage, error)
+	// SetUserDisabled disables or enables the user. Disabling logs out the
+	// sessions of the user, and keeps their personal access tokens from
+	// being used until they are enabled again.
+	SetUserDisabled(ctx context.Context, userID int32, disabled bool) (*model.User, error)
+	// ForcePasswordReset removes the password of the user and logs out
+	// their sessions. It returns a token, valid for ttl, to choose a new
+	// password with ResetPassword.
+	ForcePasswordReset(ctx context.Context, userID int32, ttl time.Duration) (*model.User, string, error)
+	CreateAuditEntry(context.Context, model.AuditEntryDTO) error
+	// ListAuditEntries returns the audit log, newest first.
+	ListAuditEntries(ctx context.Context, filter model.AuditFilter, page model.AdminPage) (*model.AuditLog, error)
 }
 
 type NoteRepository interface {
 	CreateNote(context.Context, model.NoteDTO) (*model.Note, error)
-	ListNotesByUserID(context.Context, int32) ([]model.Note, error)
+	ListNotesByUserID(ctx context.Context, userID int32, filter model.NoteFilter, page model.NotePage) (*model.NoteList, error)
 	GetNoteByUserID(ctx context.Context, noteID, userID int32) (*model.Note, error)
+	// UpdateNote is allowed for the owner and editors, other collaborators
+	// get ErrForbidden.
 	UpdateNote(context.Context, int32, model.NoteDTO) (*model.Note, error)
 	DeleteNote(ctx context.Context, noteID, userID int32) error
-	ShareNote(context.Context, model.NoteShareDTO) error
-	SearchNotes(ctx context.Context, userID int32, query string) ([]model.Note, error)
+	ListDeletedNotes(ctx context.Context, userID int32) ([]model.Note, error)
+	RestoreNote(ctx context.Context, noteID, userID int32) (*model.Note, error)
+	MoveNote(ctx context.Context, noteID, userID int32, notebookID *int32) (*model.Note, error)
+	PurgeDeletedNotes(ctx context.Context, retention time.Duration) (int64, error)
+	// ShareNote shares a note of the owner with the given role, or changes
+	// the role if the note is already shared with that user. If no user has
+	// the email yet, the share is kept as a pending invitation, which is
+	// returned.
+	ShareNote(context.Context, model.NoteShareDTO) (*model.ShareInvitation, error)
+	ListNoteShares(ctx context.Context, noteID, userID int32) ([]model.NoteShare, error)
+	RevokeNoteShare(ctx context.Context, noteID, userID, sharedWithUserID int32) error
+	// LeaveNote removes a note shared with the user from their notes.
+	LeaveNote(ctx context.Context, noteID, userID int32) error
+	ListShareInvitations(ctx context.Context, noteID, userID int32) ([]model.ShareInvitation, error)
+	CancelShareInvitation(ctx context.Context, noteID, userID, invitationID int32) error
+	// SearchNotes returns the notes visible to the user matching the search,
+	// which can be sorted by their rank or score as relevance.
+	SearchNotes(ctx context.Context, userID int32, search model.NoteSearch, page model.NotePage) (*model.SearchResultList, error)
+	// CountSearchNotes counts the notes visible to the user matching the
+	// search, only those updated after since when it isn't nil.
+	CountSearchNotes(ctx context.Context, userID int32, search model.NoteSearch, since *time.Time) (int64, error)
+	ListNoteRevisions(ctx context.Context, noteID, userID int32) ([]model.NoteRevision, error)
+	GetNoteRevision(ctx context.Context, noteID, userID, revision int32) (*model.NoteRevision, error)
+	RestoreNoteRevision(ctx context.Context, noteID, userID, revision int32) (*model.Note, error)
+}
+
+type TagRepository interface {
+	ListTags(ctx context.Context, userID int32) ([]model.Tag, error)
+	// RenameTag renames a tag of the user. If the user already has a tag
+	// with the new name, the two tags are merged.
+	RenameTag(ctx context.Context, userID int32, from, to string) error
+}
+
+type NotebookRepository interface {
+	CreateNotebook(context.Context, model.NotebookDTO) (*model.Notebook, error)
+	ListNotebooks(ctx context.Context, userID int32) ([]model.Notebook, error)
+	GetNotebook(ctx context.Context, notebookID, userID int32) (*model.Notebook, error)
+	UpdateNotebook(context.Context, int32, model.NotebookDTO) (*model.Notebook, error)
+	DeleteNotebook(ctx context.Context, notebookID, userID int32) error
+	// ShareNotebook returns ErrAlreadyExists if the notebook is already shared
+	// with the user.
+	ShareNotebook(context.Context, model.NotebookShareDTO) error
+	ListNotebookShares(ctx context.Context, notebookID, userID int32) ([]model.NotebookShare, error)
+	RevokeNotebookShare(ctx context.Context, notebookID, userID, sharedWithUserID int32) error
+	// LeaveNotebook removes a notebook shared with the user from their
+	// notebooks.
+	LeaveNotebook(ctx context.Context, notebookID, userID int32) error
+}
+
+type SavedSearchRepository interface {
+	// CreateSavedSearch returns ErrAlreadyExists if the user already has a
+	// saved search with the name, and so does UpdateSavedSearch.
+	CreateSavedSearch(context.Context, model.SavedSearchDTO) (*model.SavedSearch, error)
+	ListSavedSearches(ctx context.Context, userID int32) ([]model.SavedSearch, error)
+	GetSavedSearch(ctx context.Context, searchID, userID int32) (*model.SavedSearch, error)
+	UpdateSavedSearch(context.Context, int32, model.SavedSearchDTO) (*model.SavedSearch, error)
+	DeleteSavedSearch(ctx context.Context, searchID, userID int32) error
+	// MarkSavedSearchViewed records that the user viewed the results of the
+	// search as of viewedAt, so that only notes updated after it are unread.
+	MarkSavedSearchViewed(ctx context.Context, searchID, userID int32, viewedAt time.Time) error
+}
+
+type PublicLinkRepository interface {
+	CreatePublicLink(context.Context, model.PublicLinkDTO) (*model.PublicLink, error)
+	ListPublicLinks(ctx context.Context, noteID, userID int32) ([]model.PublicLink, error)
+	RevokePublicLink(ctx context.Context, noteID, userID, linkID int32) error
+	// GetPublicNote returns the note behind an unexpired link, checking the
+	// password if the link has one.
+	GetPublicNote(ctx context.Context, token, password string) (*model.PublicNote, error)
 }
 
 type Repository interface {
 	UserRepository
+	SessionRepository
+	LoginFailureRepository
+	AccessTokenRepository
+	TwoFactorRepository
+	AdminRepository
 	NoteRepository
+	TagRepository
+	NotebookRepository
+	SavedSearchRepository
+	PublicLinkRepository
 }
@@ -0,0 +1,378 @@
+// Package searchquery parses the queries of note searches, such as
+//
+//	pancake OR "waffle recipe" -burnt tag:food after:2024-01-01
+//
+// Terms are matched together unless separated by OR, which binds tighter
+// than the implicit AND, and can be grouped with parentheses. A leading -
+// excludes a term.
+package searchquery
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Node is a parsed query, one of And, Or, Not, Match, Tag, Shared, Owner
+// or Date
+type Node interface {
+	node()
+}
+
+// And matches notes matching all of its nodes
+type And []Node
+
+// Or matches notes matching any of its nodes
+type Or []Node
+
+// Not matches notes not matching its node
+type Not struct {
+	Node Node
+}
+
+// Match matches notes with the words of Value in their title or content,
+// or only their title with TitleOnly. With Phrase, the words must follow
+// each other.
+type Match struct {
+	Value     string
+	Phrase    bool
+	TitleOnly bool
+}
+
+// Tag matches notes with the tag
+type Tag string
+
+// Shared matches notes shared with others, or with the user, or notes that
+// aren't when false
+type Shared bool
+
+// Owner matches notes owned by the user with the email, or by the user
+// searching if it is "me"
+type Owner string
+
+// OwnerMe is the Owner of the notes of the user searching
+const OwnerMe = "me"
+
+// dates notes can be filtered on
+const (
+	CreatedAt = "created_at"
+	UpdatedAt = "updated_at"
+)
+
+// Date matches notes whose Field is before Time, or at or after it when
+// not Before
+type Date struct {
+	Field  string
+	Before bool
+	Time   time.Time
+}
+
+func (And) node()    {}
+func (Or) node()     {}
+func (Not) node()    {}
+func (Match) node()  {}
+func (Tag) node()    {}
+func (Shared) node() {}
+func (Owner) node()  {}
+func (Date) node()   {}
+
+// Error is a query that can't be parsed. Position is the offset of the
+// problem in characters from the start of the query.
+type Error struct {
+	Position int    `json:"position"`
+	Message  string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Message, e.Position)
+}
+
+// dateFilters are the fields filtering notes by date, and what they match
+var dateFilters = map[string]Date{
+	"before":         {Field: CreatedAt, Before: true},
+	"after":          {Field: CreatedAt},
+	"updated_before": {Field: UpdatedAt, Before: true},
+	"updated_after":  {Field: UpdatedAt},
+}
+
+func isField(name string) bool {
+	switch name {
+	case "title", "tag", "shared", "owner":
+		return true
+	}
+	_, ok := dateFilters[name]
+	return ok
+}
+
+type parser struct {
+	query []rune
+	pos   int
+}
+
+// Parse parses a query. An empty query returns a nil Node, which matches
+// every note.
+func Parse(query string) (Node, error) {
+	p := &parser{query: []rune(query)}
+
+	p.skipSpace()
+	if p.done() {
+		return nil, nil
+	}
+
+	node, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		// only a closing parenthesis ends an expression early
+		return nil, p.errorf("unexpected )")
+	}
+	return node, nil
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.query)
+}
+
+func (p *parser) peek() rune {
+	return p.query[p.pos]
+}
+
+func (p *parser) skipSpace() {
+	for !p.done() && unicode.IsSpace(p.peek()) {
+		p.pos++
+	}
+}
+
+func (p *parser) errorf(format string, args ...any) *Error {
+	return &Error{Position: p.pos, Message: fmt.Sprintf(format, args...)}
+}
+
+// atOr reports whether the next word is the OR operator
+func (p *parser) atOr() bool {
+	end := p.pos + 2
+	return end <= len(p.query) && string(p.query[p.pos:end]) == "OR" &&
+		(end == len(p.query) || unicode.IsSpace(p.query[end]) || p.query[end] == '(' || p.query[end] == '"')
+}
+
+// and parses terms, or groups of terms separated by OR, up to the end of
+// the query or of the parentheses
+func (p *parser) and() (Node, error) {
+	var nodes And
+	for !p.done() && p.peek() != ')' {
+		node, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+		p.skipSpace()
+	}
+
+	switch len(nodes) {
+	case 0:
+		if !p.done() {
+			return nil, p.errorf("unexpected )")
+		}
+		return nil, p.errorf("expected a term")
+	case 1:
+		return nodes[0], nil
+	}
+	return nodes, nil
+}
+
+func (p *parser) or() (Node, error) {
+	if p.atOr() {
+		return nil, p.errorf("expected a term before OR")
+	}
+
+	var nodes Or
+	for {
+		node, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+
+		p.skipSpace()
+		if !p.atOr() {
+			break
+		}
+		p.pos += 2
+		p.skipSpace()
+		if p.done() || p.peek() == ')' || p.atOr() {
+			return nil, p.errorf("expected a term after OR")
+		}
+	}
+
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return nodes, nil
+}
+
+func (p *parser) unary() (Node, error) {
+	if p.peek() != '-' {
+		return p.primary()
+	}
+
+	p.pos++
+	if p.done() || unicode.IsSpace(p.peek()) || p.peek() == ')' {
+		return nil, p.errorf("expected a term after -")
+	}
+	node, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	return Not{Node: node}, nil
+}
+
+func (p *parser) primary() (Node, error) {
+	if p.peek() != '(' {
+		return p.term()
+	}
+
+	open := p.pos
+	p.pos++
+	p.skipSpace()
+	if p.done() {
+		return nil, &Error{Position: open, Message: "missing )"}
+	}
+	if p.peek() == ')' {
+		return nil, p.errorf("expected a term")
+	}
+
+	node, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	if p.done() {
+		return nil, &Error{Position: open, Message: "missing )"}
+	}
+	p.pos++
+	return node, nil
+}
+
+// term parses a word, a phrase, or a field with its value
+func (p *parser) term() (Node, error) {
+	start := p.pos
+	if p.peek() == '"' {
+		phrase, err := p.phrase()
+		if err != nil {
+			return nil, err
+		}
+		return Match{Value: phrase, Phrase: true}, nil
+	}
+
+	word := p.word()
+	name, value, found := strings.Cut(word, ":")
+	if !found || !isField(name) {
+		return Match{Value: word}, nil
+	}
+
+	valueStart := start + len([]rune(name)) + 1
+	phrase := false
+	if value == "" && !p.done() && p.peek() == '"' {
+		var err error
+		if value, err = p.phrase(); err != nil {
+			return nil, err
+		}
+		phrase = true
+	}
+	if value == "" {
+		return nil, &Error{Position: valueStart, Message: fmt.Sprintf("missing value for %s:", name)}
+	}
+
+	switch name {
+	case "title":
+		return Match{Value: value, Phrase: phrase, TitleOnly: true}, nil
+	case "tag":
+		return Tag(value), nil
+	case "owner":
+		return Owner(value), nil
+	case "shared":
+		switch value {
+		case "yes":
+			return Shared(true), nil
+		case "no":
+			return Shared(false), nil
+		}
+		return nil, &Error{Position: valueStart, Message: "shared: must be yes or no"}
+	}
+
+	date := dateFilters[name]
+	t, err := parseDate(value)
+	if err != nil {
+		return nil, &Error{Position: valueStart, Message: fmt.Sprintf("%s: must be a date like 2024-01-31", name)}
+	}
+	date.Time = t
+	return date, nil
+}
+
+// word reads up to the next space, quote or parenthesis. A colon followed
+// by a quote ends the word too, for fields with a phrase as value.
+func (p *parser) word() string {
+	start := p.pos
+	for !p.done() {
+		r := p.peek()
+		if unicode.IsSpace(r) || r == '"' || r == '(' || r == ')' {
+			break
+		}
+		p.pos++
+		if r == ':' && !p.done() && p.peek() == '"' {
+			break
+		}
+	}
+	return string(p.query[start:p.pos])
+}
+
+// phrase reads the text between double quotes
+func (p *parser) phrase() (string, error) {
+	open := p.pos
+	p.pos++
+	end := p.pos
+	for end < len(p.query) && p.query[end] != '"' {
+		end++
+	}
+	if end == len(p.query) {
+		return "", &Error{Position: open, Message: "missing closing quote"}
+	}
+
+	phrase := strings.TrimSpace(string(p.query[p.pos:end]))
+	if phrase == "" {
+		return "", &Error{Position: open, Message: "empty phrase"}
+	}
+	p.pos = end + 1
+	return phrase, nil
+}
+
+// parseDate parses a day, which starts at midnight UTC, or a time
+func parseDate(value string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// Matches returns the matches of the query that aren't excluded, which
+// are what notes are ranked and highlighted by
+func Matches(node Node) []Match {
+	var matches []Match
+	var walk func(Node)
+	walk = func(node Node) {
+		switch node := node.(type) {
+		case And:
+			for _, n := range node {
+				walk(n)
+			}
+		case Or:
+			for _, n := range node {
+				walk(n)
+			}
+		case Match:
+			matches = append(matches, node)
+		}
+	}
+	walk(node)
+	return matches
+}
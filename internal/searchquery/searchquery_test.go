@@ -0,0 +1,87 @@
+package searchquery
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		query  string
+		expect Node
+	}{
+		{"", nil},
+		{"   ", nil},
+		{"pancake", Match{Value: "pancake"}},
+		{"pancake recipe", And{Match{Value: "pancake"}, Match{Value: "recipe"}}},
+		{`"waffle recipe"`, Match{Value: "waffle recipe", Phrase: true}},
+		{"-burnt", Not{Node: Match{Value: "burnt"}}},
+		{`-"burnt waffle"`, Not{Node: Match{Value: "burnt waffle", Phrase: true}}},
+		{"pancake OR waffle", Or{Match{Value: "pancake"}, Match{Value: "waffle"}}},
+		{"pancake OR waffle syrup", And{Or{Match{Value: "pancake"}, Match{Value: "waffle"}}, Match{Value: "syrup"}}},
+		{"pancake OR waffle OR -crepe", Or{Match{Value: "pancake"}, Match{Value: "waffle"}, Not{Node: Match{Value: "crepe"}}}},
+		{"(pancake syrup) OR waffle", Or{And{Match{Value: "pancake"}, Match{Value: "syrup"}}, Match{Value: "waffle"}}},
+		{"-(pancake OR waffle)", Not{Node: Or{Match{Value: "pancake"}, Match{Value: "waffle"}}}},
+		{"ORANGE or", And{Match{Value: "ORANGE"}, Match{Value: "or"}}},
+		{"title:pancake", Match{Value: "pancake", TitleOnly: true}},
+		{`title:"pancake recipe"`, Match{Value: "pancake recipe", Phrase: true, TitleOnly: true}},
+		{"tag:food -tag:old", And{Tag("food"), Not{Node: Tag("old")}}},
+		{"shared:yes", Shared(true)},
+		{"shared:no", Shared(false)},
+		{"owner:me owner:user@example.com", And{Owner(OwnerMe), Owner("user@example.com")}},
+		{"after:2024-01-31", Date{Field: CreatedAt, Time: time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)}},
+		{"updated_before:2024-01-31T12:00:00Z", Date{Field: UpdatedAt, Before: true, Time: time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)}},
+		{"http://example.com 10:30", And{Match{Value: "http://example.com"}, Match{Value: "10:30"}}},
+	}
+
+	for _, testCase := range testCases {
+		node, err := Parse(testCase.query)
+		require.NoError(t, err, testCase.query)
+		assert.Equal(t, testCase.expect, node, testCase.query)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	testCases := []struct {
+		query    string
+		position int
+		message  string
+	}{
+		{`pancake "waffle`, 8, "missing closing quote"},
+		{`""`, 0, "empty phrase"},
+		{"pancake -", 9, "expected a term after -"},
+		{"pancake - waffle", 9, "expected a term after -"},
+		{"OR pancake", 0, "expected a term before OR"},
+		{"pancake OR", 10, "expected a term after OR"},
+		{"pancake OR OR waffle", 11, "expected a term after OR"},
+		{"(pancake", 0, "missing )"},
+		{"pancake)", 7, "unexpected )"},
+		{"()", 1, "expected a term"},
+		{"title: pancake", 6, "missing value for title:"},
+		{"shared:maybe", 7, "shared: must be yes or no"},
+		{"pancake before:yesterday", 15, "before: must be a date like 2024-01-31"},
+		{"crêpe tag:", 10, "missing value for tag:"},
+	}
+
+	for _, testCase := range testCases {
+		_, err := Parse(testCase.query)
+		var queryErr *Error
+		require.ErrorAs(t, err, &queryErr, testCase.query)
+		assert.Equal(t, testCase.position, queryErr.Position, testCase.query)
+		assert.Equal(t, testCase.message, queryErr.Message, testCase.query)
+	}
+}
+
+func TestMatches(t *testing.T) {
+	node, err := Parse(`(pancake OR title:waffle) -burnt "maple syrup" tag:food`)
+	require.NoError(t, err)
+
+	assert.Equal(t, []Match{
+		{Value: "pancake"},
+		{Value: "waffle", TitleOnly: true},
+		{Value: "maple syrup", Phrase: true},
+	}, Matches(node))
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+
+	"notes/internal/model"
+	"notes/internal/repository"
+	"notes/internal/validator"
+)
+
+func (s *Server) CreateAccessToken(c echo.Context) error {
+	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+
+	var tokenDTO model.PersonalAccessTokenDTO
+	if err := c.Bind(&tokenDTO); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	if err := validator.AccessTokenName(tokenDTO.Name); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	if err := validator.AccessTokenScopes(tokenDTO.Scopes); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	if tokenDTO.ExpiresAt != nil && !tokenDTO.ExpiresAt.After(time.Now()) {
+		return c.String(http.StatusBadRequest, "expires_at must be in the future")
+	}
+
+	tokenDTO.UserID = userID
+
+	token, err := s.Repository.CreateAccessToken(c.Request().Context(), tokenDTO)
+	if err != nil {
+		c.Logger().Error(fmt.Errorf("failed to create access token for user[%d]: %w", userID, err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusCreated, token)
+}
+
+func (s *Server) ListAccessTokens(c echo.Context) error {
+	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+
+	tokens, err := s.Repository.ListAccessTokens(c.Request().Context(), userID)
+	if err != nil {
+		c.Logger().Error(fmt.Errorf("failed to list access tokens of user[%d]: %w", userID, err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, tokens)
+}
+
+func (s *Server) RevokeAccessToken(c echo.Context) error {
+	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+	tokenID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	if err := s.Repository.RevokeAccessToken(c.Request().Context(), userID, int32(tokenID)); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return echo.ErrNotFound
+		}
+		c.Logger().Error(fmt.Errorf("failed to revoke access token[%d] of user[%d]: %w", tokenID, userID, err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+// requiredScope returns the scope a personal access token needs for a route
+// of the notes group. Managing who can access a note needs notes:share,
+// otherwise reading needs notes:read and everything else notes:write.
+func requiredScope(c echo.Context) string {
+	route := strings.TrimPrefix(c.Path(), "/api/notes/:id/")
+	switch strings.Split(route, "/")[0] {
+	case "share", "shares", "leave", "invitations", "links":
+		return model.ScopeNotesShare
+	}
+	if c.Request().Method == http.MethodGet {
+		return model.ScopeNotesRead
+	}
+	return model.ScopeNotesWrite
+}
+
+// allowAccessTokens lets personal access tokens authenticate the requests
+// otherwise authenticated by the JWT middleware
+func (s *Server) allowAccessTokens(jwtMiddleware echo.MiddlewareFunc) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		withJWT := jwtMiddleware(next)
+
+		return func(c echo.Context) error {
+			token, ok := strings.CutPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+			if !ok || !strings.HasPrefix(token, model.AccessTokenPrefix) {
+				return withJWT(c)
+			}
+
+			accessToken, err := s.Repository.UseAccessToken(c.Request().Context(), token)
+			if err != nil {
+				if errors.Is(err, repository.ErrInvalidToken) {
+					return echo.ErrUnauthorized
+				}
+				c.Logger().Error(fmt.Errorf("failed to check access token: %w", err))
+				return echo.ErrInternalServerError
+			}
+
+			scope := requiredScope(c)
+			if !slices.Contains(accessToken.Scopes, scope) {
+				return c.String(http.StatusForbidden, "token is missing the "+scope+" scope")
+			}
+
+			// handlers get the user from the claims of the JWT
+			c.Set("user", &jwt.Token{Claims: &jwtClaim{ID: accessToken.UserID}, Valid: true})
+
+			return next(c)
+		}
+	}
+}
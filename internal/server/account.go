@@ -0,0 +1,164 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+
+	"notes/internal/model"
+	"notes/internal/repository"
+	"notes/internal/validator"
+)
+
+func (s *Server) GetProfile(c echo.Context) error {
+	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+
+	user, err := s.Repository.GetUser(c.Request().Context(), userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return echo.ErrNotFound
+		}
+		c.Logger().Error(fmt.Errorf("failed to get user[%d]: %w", userID, err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, user)
+}
+
+func (s *Server) UpdateProfile(c echo.Context) error {
+	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+
+	var userDTO model.UpdateUserDTO
+	if err := c.Bind(&userDTO); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	if err := validator.Username(userDTO.Username); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	if userDTO.SearchLanguage != "" {
+		if err := validator.SearchLanguage(userDTO.SearchLanguage); err != nil {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+	}
+
+	user, err := s.Repository.UpdateProfile(c.Request().Context(), userID, userDTO)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return echo.ErrNotFound
+		}
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			return c.String(http.StatusConflict, "username is already taken")
+		}
+		c.Logger().Error(fmt.Errorf("failed to update user[%d]: %w", userID, err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, user)
+}
+
+// ChangePassword sets a new password and logs out the other sessions of
+// the user
+func (s *Server) ChangePassword(c echo.Context) error {
+	claims := c.Get("user").(*jwt.Token).Claims.(*jwtClaim)
+
+	var passwordDTO model.ChangePasswordDTO
+	if err := c.Bind(&passwordDTO); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	if err := validator.Password(passwordDTO.NewPassword); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	err := s.Repository.ChangePassword(c.Request().Context(), claims.ID, passwordDTO.CurrentPassword, passwordDTO.NewPassword)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidPassword) {
+			return c.String(http.StatusForbidden, err.Error())
+		}
+		if errors.Is(err, repository.ErrNotFound) {
+			return echo.ErrNotFound
+		}
+		c.Logger().Error(fmt.Errorf("failed to change password of user[%d]: %w", claims.ID, err))
+		return echo.ErrInternalServerError
+	}
+
+	if err := s.Repository.RevokeOtherSessions(c.Request().Context(), claims.ID, claims.RegisteredClaims.ID); err != nil {
+		c.Logger().Error(fmt.Errorf("failed to revoke sessions of user[%d]: %w", claims.ID, err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+// ChangeEmail sends a verification link to the new email. The email of the
+// user only changes once the link is opened.
+func (s *Server) ChangeEmail(c echo.Context) error {
+	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+
+	var emailDTO model.ChangeEmailDTO
+	if err := c.Bind(&emailDTO); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	if err := validator.Email(emailDTO.Email); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	token, err := s.Repository.CreateEmailChangeToken(c.Request().Context(), userID, emailDTO.Password, emailDTO.Email, emailVerificationTTL)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidPassword) {
+			return c.String(http.StatusForbidden, err.Error())
+		}
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			return c.String(http.StatusConflict, "email is already taken")
+		}
+		if errors.Is(err, repository.ErrNotFound) {
+			return echo.ErrNotFound
+		}
+		c.Logger().Error(fmt.Errorf("failed to change email of user[%d]: %w", userID, err))
+		return echo.ErrInternalServerError
+	}
+
+	if err := s.sendVerificationLink(c, emailDTO.Email, token); err != nil {
+		c.Logger().Error(fmt.Errorf("failed to send verification email to user[%d]: %w", userID, err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.NoContent(http.StatusAccepted)
+}
+
+// DeleteAccount deletes the user along with their notes. Notes shared with
+// others are deleted too, unless shared_notes is transfer.
+func (s *Server) DeleteAccount(c echo.Context) error {
+	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+
+	var deleteDTO model.DeleteUserDTO
+	if err := c.Bind(&deleteDTO); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	if deleteDTO.SharedNotes == "" {
+		deleteDTO.SharedNotes = model.SharedNotesDelete
+	}
+	if deleteDTO.SharedNotes != model.SharedNotesDelete && deleteDTO.SharedNotes != model.SharedNotesTransfer {
+		return c.String(http.StatusBadRequest, "shared_notes must be delete or transfer")
+	}
+
+	transfer := deleteDTO.SharedNotes == model.SharedNotesTransfer
+	if err := s.Repository.DeleteUser(c.Request().Context(), userID, deleteDTO.Password, transfer); err != nil {
+		if errors.Is(err, repository.ErrInvalidPassword) {
+			return c.String(http.StatusForbidden, err.Error())
+		}
+		if errors.Is(err, repository.ErrNotFound) {
+			return echo.ErrNotFound
+		}
+		c.Logger().Error(fmt.Errorf("failed to delete user[%d]: %w", userID, err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.NoContent(http.StatusOK)
+}
@@ -0,0 +1,291 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+
+	"notes/internal/mail"
+	"notes/internal/model"
+	"notes/internal/repository"
+)
+
+// sizes of the pages of admin listings
+const (
+	defaultAdminPageLimit = 50
+	maxAdminPageLimit     = 100
+)
+
+// requireAdmin rejects users who aren't admins. The role in the token is
+// confirmed with the user, so demoted admins lose access right away
+// rather than when their token expires.
+func (s *Server) requireAdmin(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		claims := c.Get("user").(*jwt.Token).Claims.(*jwtClaim)
+		if claims.Role != model.UserRoleAdmin {
+			return echo.ErrForbidden
+		}
+
+		user, err := s.Repository.GetUser(c.Request().Context(), claims.ID)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return echo.ErrForbidden
+			}
+			c.Logger().Error(fmt.Errorf("failed to get user[%d]: %w", claims.ID, err))
+			return echo.ErrInternalServerError
+		}
+		if user.Role != model.UserRoleAdmin || user.DisabledAt != nil {
+			return echo.ErrForbidden
+		}
+
+		return next(c)
+	}
+}
+
+// auditAdminAction records every request to the admin API with the status
+// of its response. The response is held back until the request is
+// recorded, and requests that can't be are refused, so nothing an admin
+// does goes unaudited.
+func (s *Server) auditAdminAction(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		actorID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+
+		entry := model.AuditEntryDTO{
+			ActorID: actorID,
+			Action:  c.Request().Method + " " + c.Path(),
+			Details: c.QueryString(),
+			IP:      c.RealIP(),
+		}
+		if id, err := strconv.Atoi(c.Param("id")); err == nil {
+			targetUserID := int32(id)
+			entry.TargetUserID = &targetUserID
+		}
+
+		res := c.Response()
+		held := &heldResponse{ResponseWriter: res.Writer}
+		res.Writer = held
+		err := next(c)
+		res.Writer = held.ResponseWriter
+
+		// errors are responded to by echo once they are returned
+		entry.Status = int32(res.Status)
+		if err != nil && !res.Committed {
+			entry.Status = http.StatusInternalServerError
+			var httpErr *echo.HTTPError
+			if errors.As(err, &httpErr) {
+				entry.Status = int32(httpErr.Code)
+			}
+		}
+
+		if auditErr := s.Repository.CreateAuditEntry(c.Request().Context(), entry); auditErr != nil {
+			c.Logger().Error(fmt.Errorf("failed to audit action of user[%d]: %w", actorID, auditErr))
+			res.Committed = false
+			return echo.ErrInternalServerError
+		}
+
+		if res.Committed {
+			if err := held.release(); err != nil {
+				return err
+			}
+		}
+		return err
+	}
+}
+
+// heldResponse holds back the status and body a handler writes until they
+// are released
+type heldResponse struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *heldResponse) WriteHeader(status int) {
+	w.status = status
+}
+
+func (w *heldResponse) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+// release writes the status and body held back to the response
+func (w *heldResponse) release() error {
+	w.ResponseWriter.WriteHeader(w.status)
+	_, err := w.ResponseWriter.Write(w.body.Bytes())
+	return err
+}
+
+// parses the pagination of an admin listing, e.g. ?limit=20&cursor=...
+func adminPageFromQuery(c echo.Context) (model.AdminPage, error) {
+	page := model.AdminPage{
+		Cursor: c.QueryParam("cursor"),
+		Limit:  defaultAdminPageLimit,
+	}
+
+	if limit := c.QueryParam("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > maxAdminPageLimit {
+			return page, fmt.Errorf("limit must be between 1 and %d", maxAdminPageLimit)
+		}
+		page.Limit = int32(n)
+	}
+
+	return page, nil
+}
+
+// ListUsers lists the users whose username or email contains ?q=, with
+// their usage
+func (s *Server) ListUsers(c echo.Context) error {
+	page, err := adminPageFromQuery(c)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	users, err := s.Repository.ListUsers(c.Request().Context(), c.QueryParam("q"), page)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		c.Logger().Error(fmt.Errorf("failed to list users: %w", err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, users)
+}
+
+func (s *Server) GetUser(c echo.Context) error {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	user, err := s.Repository.GetUserWithUsage(c.Request().Context(), int32(userID))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return echo.ErrNotFound
+		}
+		c.Logger().Error(fmt.Errorf("failed to get user[%d]: %w", userID, err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, user)
+}
+
+// DisableUser keeps a user from logging in and logs out their sessions
+func (s *Server) DisableUser(c echo.Context) error {
+	adminID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	// an admin disabling themselves could leave no one to enable them
+	if int32(userID) == adminID {
+		return c.String(http.StatusBadRequest, "admins can't disable themselves")
+	}
+
+	return s.setUserDisabled(c, int32(userID), true)
+}
+
+func (s *Server) EnableUser(c echo.Context) error {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	return s.setUserDisabled(c, int32(userID), false)
+}
+
+func (s *Server) setUserDisabled(c echo.Context, userID int32, disabled bool) error {
+	user, err := s.Repository.SetUserDisabled(c.Request().Context(), userID, disabled)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return echo.ErrNotFound
+		}
+		c.Logger().Error(fmt.Errorf("failed to set user[%d] disabled to %t: %w", userID, disabled, err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, user)
+}
+
+// ForcePasswordReset removes the password of a user, logs out their
+// sessions and emails them a link to choose a new one
+func (s *Server) ForcePasswordReset(c echo.Context) error {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	user, token, err := s.Repository.ForcePasswordReset(c.Request().Context(), int32(userID), passwordResetTTL)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return echo.ErrNotFound
+		}
+		c.Logger().Error(fmt.Errorf("failed to force password reset of user[%d]: %w", userID, err))
+		return echo.ErrInternalServerError
+	}
+
+	// the password is reset even if the email can't be sent, the user can
+	// ask for another one with /api/auth/password/forgot
+	err = s.Mailer.Send(c.Request().Context(), mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("An administrator reset the password of your account.\n\n"+
+			"To choose a new password, send this token to /api/auth/password/reset within %s:\n\n%s\n",
+			passwordResetTTL, token),
+	})
+	if err != nil {
+		c.Logger().Error(fmt.Errorf("failed to send password reset email to user[%d]: %w", userID, err))
+	}
+
+	return c.NoContent(http.StatusAccepted)
+}
+
+// ListAuditEntries lists the audit log, newest first, optionally only the
+// actions of ?actor_id= or on ?target_user_id=
+func (s *Server) ListAuditEntries(c echo.Context) error {
+	page, err := adminPageFromQuery(c)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	var filter model.AuditFilter
+	if filter.ActorID, err = userIDFromQuery(c, "actor_id"); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	if filter.TargetUserID, err = userIDFromQuery(c, "target_user_id"); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	log, err := s.Repository.ListAuditEntries(c.Request().Context(), filter, page)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		c.Logger().Error(fmt.Errorf("failed to list audit entries: %w", err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, log)
+}
+
+// parses an optional user id in the query, nil when it is missing
+func userIDFromQuery(c echo.Context, param string) (*int32, error) {
+	value := c.QueryParam(param)
+	if value == "" {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a user id", param)
+	}
+	userID := int32(n)
+	return &userID, nil
+}
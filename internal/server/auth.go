@@ -0,0 +1,168 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+
+	"notes/internal/model"
+	"notes/internal/repository"
+)
+
+// how often expired sessions are removed
+const sessionPurgeInterval = time.Hour
+
+// issueTokens responds with a new access token for the session, along with
+// its refresh token. The token carries the current role of the user.
+func (s *Server) issueTokens(c echo.Context, session *model.Session) error {
+	user, err := s.Repository.GetUser(c.Request().Context(), session.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return echo.ErrUnauthorized
+		}
+		c.Logger().Error(fmt.Errorf("failed to get user[%d]: %w", session.UserID, err))
+		return echo.ErrInternalServerError
+	}
+	if user.DisabledAt != nil {
+		return c.String(http.StatusForbidden, "account is disabled")
+	}
+
+	expiresAt := time.Now().Add(s.Config.AccessTokenTTL)
+	claims := &jwtClaim{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        session.TokenID,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+		ID:   session.UserID,
+		Role: user.Role,
+	}
+
+	tokenString, err := s.Config.Keys.Sign(claims)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, model.AuthTokens{
+		Token:        tokenString,
+		RefreshToken: session.RefreshToken,
+		ExpiresAt:    expiresAt,
+	})
+}
+
+// startSession logs in the user whose password or identity was checked.
+// Users with two-factor authentication get a challenge to complete with a
+// code instead.
+func (s *Server) startSession(c echo.Context, user *model.User) error {
+	if user.DisabledAt != nil {
+		return c.String(http.StatusForbidden, "account is disabled")
+	}
+	if s.Config.RequireVerifiedEmail && user.EmailVerifiedAt == nil {
+		return c.String(http.StatusForbidden, "email is not verified")
+	}
+
+	userTOTP, err := s.Repository.GetTOTP(c.Request().Context(), user.ID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		c.Logger().Error(fmt.Errorf("failed to get totp of user[%d]: %w", user.ID, err))
+		return echo.ErrInternalServerError
+	}
+	if err == nil && userTOTP.EnabledAt != nil {
+		return s.issueLoginChallenge(c, user.ID)
+	}
+
+	if err := s.Repository.ClearLoginFailures(c.Request().Context(), user.Email, c.RealIP()); err != nil {
+		c.Logger().Error(fmt.Errorf("failed to clear login failures of user[%d]: %w", user.ID, err))
+		return echo.ErrInternalServerError
+	}
+
+	session, err := s.Repository.CreateSession(c.Request().Context(), user.ID, s.Config.RefreshTokenTTL)
+	if err != nil {
+		c.Logger().Error(fmt.Errorf("failed to create session for user[%d]: %w", user.ID, err))
+		return echo.ErrInternalServerError
+	}
+
+	return s.issueTokens(c, session)
+}
+
+// JWKS publishes the public keys access tokens are signed with, for other
+// services to verify them
+func (s *Server) JWKS(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", "public, max-age=300")
+	return c.JSON(http.StatusOK, s.Config.Keys.JWKS())
+}
+
+func (s *Server) RefreshToken(c echo.Context) error {
+	var refreshDTO model.RefreshDTO
+	if err := c.Bind(&refreshDTO); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	if refreshDTO.RefreshToken == "" {
+		return c.String(http.StatusBadRequest, "missing refresh_token")
+	}
+
+	session, err := s.Repository.RefreshSession(c.Request().Context(), refreshDTO.RefreshToken, s.Config.RefreshTokenTTL)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidToken) {
+			return echo.ErrUnauthorized
+		}
+		c.Logger().Error(fmt.Errorf("failed to refresh session: %w", err))
+		return echo.ErrInternalServerError
+	}
+
+	return s.issueTokens(c, session)
+}
+
+func (s *Server) LogOut(c echo.Context) error {
+	claims := c.Get("user").(*jwt.Token).Claims.(*jwtClaim)
+
+	if err := s.Repository.RevokeSession(c.Request().Context(), claims.ID, claims.RegisteredClaims.ID); err != nil {
+		c.Logger().Error(fmt.Errorf("failed to revoke session of user[%d]: %w", claims.ID, err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+func (s *Server) LogOutAll(c echo.Context) error {
+	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+
+	if err := s.Repository.RevokeAllSessions(c.Request().Context(), userID); err != nil {
+		c.Logger().Error(fmt.Errorf("failed to revoke sessions of user[%d]: %w", userID, err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+// requireActiveSession rejects access tokens whose session was logged out.
+// It runs after the JWT middleware, which checks the signature and expiry.
+func (s *Server) requireActiveSession(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		claims := c.Get("user").(*jwt.Token).Claims.(*jwtClaim)
+
+		active, err := s.Repository.IsTokenActive(c.Request().Context(), claims.RegisteredClaims.ID)
+		if err != nil {
+			c.Logger().Error(fmt.Errorf("failed to check session of user[%d]: %w", claims.ID, err))
+			return echo.ErrInternalServerError
+		}
+		if !active {
+			return echo.ErrUnauthorized
+		}
+
+		return next(c)
+	}
+}
+
+// PurgeSessions removes sessions whose refresh token has expired. It
+// blocks until ctx is done.
+func (s *Server) PurgeSessions(ctx context.Context) {
+	runPeriodically(ctx, sessionPurgeInterval, "purge sessions", func(ctx context.Context) error {
+		_, err := s.Repository.PurgeExpiredSessions(ctx)
+		return err
+	})
+}
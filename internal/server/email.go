@@ -0,0 +1,87 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"notes/internal/mail"
+	"notes/internal/model"
+	"notes/internal/repository"
+	"notes/internal/validator"
+)
+
+// how long an email verification link can be used
+const emailVerificationTTL = 24 * time.Hour
+
+// sendVerificationEmail mails a link verifying the email to its unverified
+// user, if there is one
+func (s *Server) sendVerificationEmail(c echo.Context, email string) error {
+	token, err := s.Repository.CreateEmailVerificationToken(c.Request().Context(), email, emailVerificationTTL)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	return s.sendVerificationLink(c, email, token)
+}
+
+// sendVerificationLink mails the link verifying the email with the token
+func (s *Server) sendVerificationLink(c echo.Context, email, token string) error {
+	link := s.Config.BaseURL + "/api/auth/verify?token=" + url.QueryEscape(token)
+
+	return s.Mailer.Send(c.Request().Context(), mail.Message{
+		To:      email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf("Open this link within %s to verify the email of your account:\n\n%s\n\n"+
+			"If you didn't ask for it, ignore this email.\n",
+			emailVerificationTTL, link),
+	})
+}
+
+func (s *Server) VerifyEmail(c echo.Context) error {
+	token := c.QueryParam("token")
+	if token == "" {
+		return c.String(http.StatusBadRequest, "missing token")
+	}
+
+	user, err := s.Repository.VerifyEmail(c.Request().Context(), token)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidToken) {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			return c.String(http.StatusConflict, "email is already taken")
+		}
+		c.Logger().Error(fmt.Errorf("failed to verify email: %w", err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, user)
+}
+
+// ResendVerificationEmail sends another verification link, for instance
+// when the first one expired. Like ForgotPassword, it doesn't tell whether
+// the email belongs to a user.
+func (s *Server) ResendVerificationEmail(c echo.Context) error {
+	var verify model.VerifyEmailDTO
+	if err := c.Bind(&verify); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	if err := validator.Email(verify.Email); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	if err := s.sendVerificationEmail(c, verify.Email); err != nil {
+		c.Logger().Error(fmt.Errorf("failed to send verification email to user[%s]: %w", verify.Email, err))
+	}
+
+	return c.NoContent(http.StatusAccepted)
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// how often failed logins that no longer count are removed
+const loginFailurePurgeInterval = time.Hour
+
+// checkLoginLockout responds with 429 Too Many Requests, and returns true,
+// if logins with the email or from the IP of the client are locked
+func (s *Server) checkLoginLockout(c echo.Context, email string) (bool, error) {
+	lockedUntil, err := s.Repository.LoginLockedUntil(c.Request().Context(), email, c.RealIP())
+	if err != nil {
+		c.Logger().Error(fmt.Errorf("failed to check login lockout of [%s]: %w", email, err))
+		return true, echo.ErrInternalServerError
+	}
+	if lockedUntil == nil {
+		return false, nil
+	}
+	return true, loginLocked(c, *lockedUntil)
+}
+
+// loginFailed counts a failed login with the email and responds with 401
+// Unauthorized, or 429 Too Many Requests if logins are now locked. Unknown
+// emails are counted like the others, so the response doesn't tell which
+// have an account.
+func (s *Server) loginFailed(c echo.Context, email string) error {
+	lockedUntil, err := s.Repository.RecordLoginFailure(c.Request().Context(), email, c.RealIP(), s.Config.LoginLockout)
+	if err != nil {
+		c.Logger().Error(fmt.Errorf("failed to record login failure of [%s]: %w", email, err))
+		return echo.ErrInternalServerError
+	}
+	if lockedUntil != nil {
+		return loginLocked(c, *lockedUntil)
+	}
+	return echo.ErrUnauthorized
+}
+
+func loginLocked(c echo.Context, until time.Time) error {
+	retryAfter := int(math.Ceil(time.Until(until).Seconds()))
+	c.Response().Header().Set("Retry-After", strconv.Itoa(max(retryAfter, 1)))
+	return c.String(http.StatusTooManyRequests, "too many failed logins, try again later")
+}
+
+// PurgeLoginFailures removes failed logins that no longer lock logins. It
+// blocks until ctx is done.
+func (s *Server) PurgeLoginFailures(ctx context.Context) {
+	runPeriodically(ctx, loginFailurePurgeInterval, "purge login failures", func(ctx context.Context) error {
+		_, err := s.Repository.PurgeLoginFailures(ctx, s.Config.LoginLockout.Window)
+		return err
+	})
+}
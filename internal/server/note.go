@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -11,20 +12,40 @@ import (
 
 	"notes/internal/model"
 	"notes/internal/repository"
+	"notes/internal/searchquery"
 	"notes/internal/validator"
 )
 
+const (
+	defaultNotePageLimit = 50
+	maxNotePageLimit     = 100
+	// how similar notes must be to the query of a fuzzy search by default
+	defaultSearchThreshold = 0.3
+	// what surrounds the matching words in search snippets by default
+	defaultHighlightStart = "<b>"
+	defaultHighlightStop  = "</b>"
+)
+
 func (s *Server) ListNotes(c echo.Context) error {
 	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
 
-	notes, err := s.Repository.ListNotesByUserID(c.Request().Context(), userID)
+	filter, err := noteFilterFromQuery(c)
 	if err != nil {
-		c.Logger().Error(fmt.Errorf("failed to list notes for user[%d]: %w", userID, err))
-		return echo.ErrInternalServerError
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	page, err := notePageFromQuery(c, false)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
 	}
 
-	if len(notes) == 0 {
-		return c.JSON(http.StatusOK, []model.Note{})
+	notes, err := s.Repository.ListNotesByUserID(c.Request().Context(), userID, filter, page)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		c.Logger().Error(fmt.Errorf("failed to list notes for user[%d]: %w", userID, err))
+		return echo.ErrInternalServerError
 	}
 
 	return c.JSON(http.StatusOK, notes)
@@ -57,10 +78,22 @@ func (s *Server) CreateNote(c echo.Context) error {
 		return c.String(http.StatusBadRequest, err.Error())
 	}
 
+	if err := validateTags(noteDTO.Tags); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	if noteDTO.Language != "" {
+		if err := validator.SearchLanguage(noteDTO.Language); err != nil {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+	}
+
 	noteDTO.UserID = userID
 
 	note, err := s.Repository.CreateNote(c.Request().Context(), noteDTO)
 	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return c.String(http.StatusBadRequest, "notebook not found")
+		}
 		c.Logger().Error(fmt.Errorf("failed to create note: %w", err))
 		return echo.ErrInternalServerError
 	}
@@ -80,6 +113,15 @@ func (s *Server) UpdateNote(c echo.Context) error {
 		return c.String(http.StatusBadRequest, err.Error())
 	}
 
+	if err := validateTags(noteDTO.Tags); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	if noteDTO.Language != "" {
+		if err := validator.SearchLanguage(noteDTO.Language); err != nil {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+	}
+
 	noteDTO.UserID = userID
 
 	note, err := s.Repository.UpdateNote(c.Request().Context(), int32(noteID), noteDTO)
@@ -87,6 +129,9 @@ func (s *Server) UpdateNote(c echo.Context) error {
 		if errors.Is(err, repository.ErrNotFound) {
 			return echo.ErrNotFound
 		}
+		if errors.Is(err, repository.ErrForbidden) {
+			return echo.ErrForbidden
+		}
 		c.Logger().Error(fmt.Errorf("failed to update note[%d]: %w", noteID, err))
 		return echo.ErrInternalServerError
 	}
@@ -114,6 +159,30 @@ func (s *Server) DeleteNote(c echo.Context) error {
 	return c.NoContent(http.StatusOK)
 }
 
+func (s *Server) MoveNote(c echo.Context) error {
+	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+	noteID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	var noteMoveDTO model.NoteMoveDTO
+	if err := c.Bind(&noteMoveDTO); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	note, err := s.Repository.MoveNote(c.Request().Context(), int32(noteID), userID, noteMoveDTO.NotebookID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return echo.ErrNotFound
+		}
+		c.Logger().Error(fmt.Errorf("failed to move note[%d]: %w", noteID, err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, note)
+}
+
 func (s *Server) ShareNote(c echo.Context) error {
 	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
 	noteID, err := strconv.Atoi(c.Param("id"))
@@ -129,10 +198,17 @@ func (s *Server) ShareNote(c echo.Context) error {
 	if err := validator.Email(noteShareDTO.SharedWith); err != nil {
 		return c.String(http.StatusBadRequest, err.Error())
 	}
+
+	if noteShareDTO.Role == "" {
+		noteShareDTO.Role = model.RoleViewer
+	}
+	if err := validator.ShareRole(noteShareDTO.Role); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
 	noteShareDTO.NoteID = int32(noteID)
 	noteShareDTO.UserID = userID
 
-	err = s.Repository.ShareNote(c.Request().Context(), noteShareDTO)
+	invitation, err := s.Repository.ShareNote(c.Request().Context(), noteShareDTO)
 	if err != nil {
 		if errors.Is(err, repository.ErrNotFound) {
 			return echo.ErrForbidden
@@ -141,17 +217,223 @@ func (s *Server) ShareNote(c echo.Context) error {
 		return echo.ErrInternalServerError
 	}
 
+	// the email has no account yet, the note is shared once it signs up
+	if invitation != nil {
+		return c.JSON(http.StatusAccepted, invitation)
+	}
+
 	return nil
 }
 
 func (s *Server) SearchNotes(c echo.Context) error {
 	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
-	query := c.QueryParam("q")
 
-	notes, err := s.Repository.SearchNotes(c.Request().Context(), userID, query)
+	search, err := noteSearchFromQuery(c)
+	if err != nil {
+		return badSearch(c, err)
+	}
+
+	page, err := notePageFromQuery(c, true)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	if err := s.defaultSearchLanguage(c.Request().Context(), userID, &search); err != nil {
+		c.Logger().Error(fmt.Errorf("failed to get the search language of user[%d]: %w", userID, err))
+		return echo.ErrInternalServerError
+	}
+
+	notes, err := s.Repository.SearchNotes(c.Request().Context(), userID, search, page)
 	if err != nil {
-		c.Logger().Error(fmt.Errorf("failed to search notes for user[%d], query[%s]: %w", userID, query, err))
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		c.Logger().Error(fmt.Errorf("failed to search notes for user[%d], query[%s]: %w", userID, search.Query, err))
 		return echo.ErrInternalServerError
 	}
 	return c.JSON(http.StatusOK, notes)
 }
+
+// badSearch responds to an invalid search, sending along the position of
+// the problem in its query
+func badSearch(c echo.Context, err error) error {
+	var queryErr *searchquery.Error
+	if errors.As(err, &queryErr) {
+		return c.JSON(http.StatusBadRequest, queryErr)
+	}
+	return c.String(http.StatusBadRequest, err.Error())
+}
+
+// parses a search, e.g. ?q=recipe&mode=fuzzy&threshold=0.5 or
+// ?q=recipe&highlight_start=<mark>&highlight_stop=</mark>
+func noteSearchFromQuery(c echo.Context) (model.NoteSearch, error) {
+	search := model.NoteSearch{
+		Query:     c.QueryParam("q"),
+		Mode:      c.QueryParam("mode"),
+		Threshold: defaultSearchThreshold,
+		Language:  c.QueryParam("language"),
+	}
+
+	if threshold := c.QueryParam("threshold"); threshold != "" {
+		t, err := strconv.ParseFloat(threshold, 64)
+		if err != nil {
+			return search, errors.New("threshold must be between 0 and 1")
+		}
+		search.Threshold = t
+	}
+
+	if err := highlightFromQuery(c, &search); err != nil {
+		return search, err
+	}
+	return search, prepareNoteSearch(&search)
+}
+
+// parses the markers surrounding the matching words in the snippets of a
+// search
+func highlightFromQuery(c echo.Context, search *model.NoteSearch) error {
+	search.HighlightStart = defaultHighlightStart
+	search.HighlightStop = defaultHighlightStop
+	if c.QueryParams().Has("highlight_start") {
+		search.HighlightStart = c.QueryParam("highlight_start")
+	}
+	if c.QueryParams().Has("highlight_stop") {
+		search.HighlightStop = c.QueryParam("highlight_stop")
+	}
+	for _, marker := range []string{search.HighlightStart, search.HighlightStop} {
+		if err := validator.HighlightMarker(marker); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// defaultSearchLanguage reads the query of a search without a language in
+// the search language of the user, which their notes default to. A single
+// language is also what lets the search use its index.
+func (s *Server) defaultSearchLanguage(ctx context.Context, userID int32, search *model.NoteSearch) error {
+	if search.Mode != model.SearchModeFullText || search.Language != "" {
+		return nil
+	}
+
+	user, err := s.Repository.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	search.Language = user.SearchLanguage
+	return nil
+}
+
+// prepareNoteSearch defaults and checks the mode, threshold and language of
+// a search, and parses its query
+func prepareNoteSearch(search *model.NoteSearch) error {
+	switch search.Mode {
+	case "":
+		search.Mode = model.SearchModeFullText
+	case model.SearchModeFullText, model.SearchModeFuzzy:
+	default:
+		return errors.New("mode must be either fulltext or fuzzy")
+	}
+
+	if !(search.Threshold >= 0 && search.Threshold <= 1) {
+		return errors.New("threshold must be between 0 and 1")
+	}
+
+	if search.Language != "" {
+		if err := validator.SearchLanguage(search.Language); err != nil {
+			return err
+		}
+	}
+
+	// fuzzy searches compare the query as a whole to notes
+	if search.Mode == model.SearchModeFullText {
+		expr, err := searchquery.Parse(search.Query)
+		if err != nil {
+			return err
+		}
+		search.Expr = expr
+	}
+
+	return nil
+}
+
+// parses the tag filter of a note listing, e.g. ?tag=work&tag=urgent&match=any
+func noteFilterFromQuery(c echo.Context) (model.NoteFilter, error) {
+	var filter model.NoteFilter
+
+	seen := make(map[string]bool)
+	for _, tag := range c.QueryParams()["tag"] {
+		if err := validator.Tag(tag); err != nil {
+			return filter, err
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			filter.Tags = append(filter.Tags, tag)
+		}
+	}
+
+	switch c.QueryParam("match") {
+	case "", "all":
+	case "any":
+		filter.MatchAny = true
+	default:
+		return filter, errors.New("match must be either all or any")
+	}
+
+	return filter, nil
+}
+
+// parses the pagination of a note listing, e.g. ?limit=20&sort=title&order=desc&cursor=...
+// Ranked listings, like searches, can also be sorted by relevance,
+// which is their default.
+func notePageFromQuery(c echo.Context, ranked bool) (model.NotePage, error) {
+	page := model.NotePage{
+		Cursor: c.QueryParam("cursor"),
+		Limit:  defaultNotePageLimit,
+		SortBy: model.SortByCreatedAt,
+	}
+	if ranked {
+		page.SortBy = model.SortByRelevance
+	}
+
+	if limit := c.QueryParam("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > maxNotePageLimit {
+			return page, fmt.Errorf("limit must be between 1 and %d", maxNotePageLimit)
+		}
+		page.Limit = int32(n)
+	}
+
+	switch sortBy := c.QueryParam("sort"); {
+	case sortBy == "":
+	case sortBy == model.SortByCreatedAt, sortBy == model.SortByUpdatedAt, sortBy == model.SortByTitle,
+		sortBy == model.SortByRelevance && ranked:
+		page.SortBy = sortBy
+	case ranked:
+		return page, errors.New("sort must be one of relevance, created_at, updated_at or title")
+	default:
+		return page, errors.New("sort must be one of created_at, updated_at or title")
+	}
+
+	// best matches and newest first, alphabetical for titles
+	page.Desc = page.SortBy != model.SortByTitle
+	switch c.QueryParam("order") {
+	case "":
+	case "asc":
+		page.Desc = false
+	case "desc":
+		page.Desc = true
+	default:
+		return page, errors.New("order must be either asc or desc")
+	}
+
+	return page, nil
+}
+
+func validateTags(tags []string) error {
+	for _, tag := range tags {
+		if err := validator.Tag(tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
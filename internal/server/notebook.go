@@ -0,0 +1,261 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+
+	"notes/internal/model"
+	"notes/internal/repository"
+	"notes/internal/validator"
+)
+
+func (s *Server) ListNotebooks(c echo.Context) error {
+	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+
+	notebooks, err := s.Repository.ListNotebooks(c.Request().Context(), userID)
+	if err != nil {
+		c.Logger().Error(fmt.Errorf("failed to list notebooks for user[%d]: %w", userID, err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, notebooks)
+}
+
+func (s *Server) GetNotebook(c echo.Context) error {
+	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+	notebookID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	notebook, err := s.Repository.GetNotebook(c.Request().Context(), int32(notebookID), userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return echo.ErrNotFound
+		}
+		c.Logger().Error(fmt.Errorf("failed to get notebook[%d]: %w", notebookID, err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, notebook)
+}
+
+func (s *Server) CreateNotebook(c echo.Context) error {
+	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+
+	var notebookDTO model.NotebookDTO
+	if err := c.Bind(&notebookDTO); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	if err := validator.NotebookName(notebookDTO.Name); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	notebookDTO.UserID = userID
+
+	notebook, err := s.Repository.CreateNotebook(c.Request().Context(), notebookDTO)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidParent) {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		c.Logger().Error(fmt.Errorf("failed to create notebook: %w", err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusCreated, notebook)
+}
+
+func (s *Server) UpdateNotebook(c echo.Context) error {
+	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+	notebookID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	var notebookDTO model.NotebookDTO
+	if err := c.Bind(&notebookDTO); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	if err := validator.NotebookName(notebookDTO.Name); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	notebookDTO.UserID = userID
+
+	notebook, err := s.Repository.UpdateNotebook(c.Request().Context(), int32(notebookID), notebookDTO)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return echo.ErrNotFound
+		}
+		if errors.Is(err, repository.ErrInvalidParent) {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		c.Logger().Error(fmt.Errorf("failed to update notebook[%d]: %w", notebookID, err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, notebook)
+}
+
+func (s *Server) DeleteNotebook(c echo.Context) error {
+	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+	notebookID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	err = s.Repository.DeleteNotebook(c.Request().Context(), int32(notebookID), userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return echo.ErrForbidden
+		}
+		c.Logger().Error(fmt.Errorf("failed to delete notebook[%d]: %w", notebookID, err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+func (s *Server) ShareNotebook(c echo.Context) error {
+	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+	notebookID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	var notebookShareDTO model.NotebookShareDTO
+	if err := c.Bind(&notebookShareDTO); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	if err := validator.Email(notebookShareDTO.SharedWith); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	notebookShareDTO.NotebookID = int32(notebookID)
+	notebookShareDTO.UserID = userID
+
+	err = s.Repository.ShareNotebook(c.Request().Context(), notebookShareDTO)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return echo.ErrForbidden
+		}
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			return c.String(http.StatusConflict, "notebook is already shared with this user")
+		}
+		c.Logger().Error(fmt.Errorf("failed to share notebook[%d]: %w", notebookID, err))
+		return echo.ErrInternalServerError
+	}
+
+	return nil
+}
+
+func (s *Server) ListNotebookShares(c echo.Context) error {
+	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+	notebookID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	shares, err := s.Repository.ListNotebookShares(c.Request().Context(), int32(notebookID), userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return echo.ErrNotFound
+		}
+		c.Logger().Error(fmt.Errorf("failed to list shares of notebook[%d]: %w", notebookID, err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, shares)
+}
+
+func (s *Server) RevokeNotebookShare(c echo.Context) error {
+	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+	notebookID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+	sharedWithUserID, err := strconv.Atoi(c.Param("userID"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	err = s.Repository.RevokeNotebookShare(c.Request().Context(), int32(notebookID), userID, int32(sharedWithUserID))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return echo.ErrNotFound
+		}
+		c.Logger().Error(fmt.Errorf("failed to revoke share of notebook[%d] with user[%d]: %w", notebookID, sharedWithUserID, err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+func (s *Server) LeaveNotebook(c echo.Context) error {
+	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+	notebookID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	err = s.Repository.LeaveNotebook(c.Request().Context(), int32(notebookID), userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return echo.ErrNotFound
+		}
+		c.Logger().Error(fmt.Errorf("failed to leave notebook[%d]: %w", notebookID, err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+// ListNotebookNotes lists the notes of a notebook, including the ones of
+// nested notebooks when recursive=true. The tag filter of ListNotes applies.
+func (s *Server) ListNotebookNotes(c echo.Context) error {
+	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+	notebookID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	filter, err := noteFilterFromQuery(c)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	page, err := notePageFromQuery(c, false)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	if _, err := s.Repository.GetNotebook(c.Request().Context(), int32(notebookID), userID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return echo.ErrNotFound
+		}
+		c.Logger().Error(fmt.Errorf("failed to get notebook[%d]: %w", notebookID, err))
+		return echo.ErrInternalServerError
+	}
+
+	id := int32(notebookID)
+	filter.NotebookID = &id
+	filter.Recursive = c.QueryParam("recursive") == "true"
+
+	notes, err := s.Repository.ListNotesByUserID(c.Request().Context(), userID, filter, page)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		c.Logger().Error(fmt.Errorf("failed to list notes of notebook[%d]: %w", notebookID, err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, notes)
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+
+	"notes/internal/model"
+	"notes/internal/oidc"
+	"notes/internal/repository"
+)
+
+const (
+	// cookie keeping the state of a login until the identity provider
+	// redirects back
+	oidcLoginCookie = "oidc_login"
+	// how long users have to log in at the identity provider
+	oidcLoginTTL = 10 * time.Minute
+	// audience of the login state, so it can't pass for an access token
+	oidcLoginAudience = "oidc-login"
+)
+
+// oidcLoginClaims is the state of a login, signed so the client can't
+// change it
+type oidcLoginClaims struct {
+	jwt.RegisteredClaims
+	State        string `json:"state"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+}
+
+// OIDCLogIn sends the user to the identity provider to log in
+func (s *Server) OIDCLogIn(c echo.Context) error {
+	if s.OIDC == nil {
+		return echo.ErrNotFound
+	}
+
+	var login oidcLoginClaims
+	for _, value := range []*string{&login.State, &login.Nonce, &login.CodeVerifier} {
+		random, err := oidc.NewCodeVerifier()
+		if err != nil {
+			c.Logger().Error(fmt.Errorf("failed to start oidc login: %w", err))
+			return echo.ErrInternalServerError
+		}
+		*value = random
+	}
+	login.Audience = jwt.ClaimStrings{oidcLoginAudience}
+	login.ExpiresAt = jwt.NewNumericDate(time.Now().Add(oidcLoginTTL))
+
+	cookie, err := s.Config.Keys.Sign(&login)
+	if err != nil {
+		c.Logger().Error(fmt.Errorf("failed to start oidc login: %w", err))
+		return echo.ErrInternalServerError
+	}
+
+	c.SetCookie(s.oidcLoginCookie(cookie, int(oidcLoginTTL.Seconds())))
+
+	return c.Redirect(http.StatusFound, s.OIDC.AuthCodeURL(login.State, login.Nonce, login.CodeVerifier))
+}
+
+// OIDCCallback logs in the user the identity provider redirected back,
+// responding like LogIn
+func (s *Server) OIDCCallback(c echo.Context) error {
+	if s.OIDC == nil {
+		return echo.ErrNotFound
+	}
+
+	if errCode := c.QueryParam("error"); errCode != "" {
+		return c.String(http.StatusUnauthorized, "identity provider returned "+errCode)
+	}
+
+	cookie, err := c.Cookie(oidcLoginCookie)
+	if err != nil {
+		return c.String(http.StatusBadRequest, "missing login state, log in again")
+	}
+	// the state can only be used once
+	c.SetCookie(s.oidcLoginCookie("", -1))
+
+	var login oidcLoginClaims
+	_, err = jwt.ParseWithClaims(cookie.Value, &login, s.Config.Keys.Keyfunc,
+		jwt.WithValidMethods(s.Config.Keys.Methods()),
+		jwt.WithAudience(oidcLoginAudience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return c.String(http.StatusBadRequest, "invalid login state, log in again")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(c.QueryParam("state")), []byte(login.State)) != 1 {
+		return c.String(http.StatusBadRequest, "state does not match, log in again")
+	}
+
+	claims, err := s.OIDC.Exchange(c.Request().Context(), c.QueryParam("code"), login.CodeVerifier, login.Nonce)
+	if err != nil {
+		c.Logger().Error(fmt.Errorf("failed to exchange oidc code: %w", err))
+		return echo.ErrUnauthorized
+	}
+
+	if claims.Email == "" {
+		return c.String(http.StatusForbidden, "identity provider did not share an email")
+	}
+
+	identity := model.Identity{
+		Issuer:        s.OIDC.Issuer(),
+		Subject:       claims.Subject,
+		Email:         strings.ToLower(claims.Email),
+		EmailVerified: claims.EmailVerified,
+		Username:      claims.PreferredUsername,
+	}
+	if identity.Username == "" {
+		identity.Username = claims.Name
+	}
+
+	user, err := s.Repository.GetUserByIdentity(c.Request().Context(), identity, s.Config.OIDCSignUp)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return c.String(http.StatusForbidden, "no account for this identity")
+		}
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			return c.String(http.StatusConflict, "an account with this email already exists, log in with its password")
+		}
+		c.Logger().Error(fmt.Errorf("failed to get user of identity[%s]: %w", identity.Subject, err))
+		return echo.ErrInternalServerError
+	}
+
+	return s.startSession(c, user)
+}
+
+func (s *Server) oidcLoginCookie(value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     oidcLoginCookie,
+		Value:    value,
+		Path:     "/api/auth/oidc",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(s.Config.BaseURL, "https://"),
+		// sent along when the identity provider redirects back
+		SameSite: http.SameSiteLaxMode,
+	}
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"notes/internal/mail"
+	"notes/internal/model"
+	"notes/internal/repository"
+	"notes/internal/validator"
+)
+
+// how long a password reset email can be used
+const passwordResetTTL = time.Hour
+
+func (s *Server) ForgotPassword(c echo.Context) error {
+	var forgot model.ForgotPasswordDTO
+	if err := c.Bind(&forgot); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	if err := validator.Email(forgot.Email); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	// the response is the same whether the email belongs to a user or not,
+	// so it can't be used to find out who has an account
+	token, err := s.Repository.CreatePasswordResetToken(c.Request().Context(), forgot.Email, passwordResetTTL)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return c.NoContent(http.StatusAccepted)
+		}
+		c.Logger().Error(fmt.Errorf("failed to create password reset token for user[%s]: %w", forgot.Email, err))
+		return echo.ErrInternalServerError
+	}
+
+	err = s.Mailer.Send(c.Request().Context(), mail.Message{
+		To:      forgot.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Someone asked to reset the password of your account. "+
+			"If it wasn't you, ignore this email.\n\n"+
+			"To choose a new password, send this token to /api/auth/password/reset within %s:\n\n%s\n",
+			passwordResetTTL, token),
+	})
+	if err != nil {
+		c.Logger().Error(fmt.Errorf("failed to send password reset email to user[%s]: %w", forgot.Email, err))
+	}
+
+	return c.NoContent(http.StatusAccepted)
+}
+
+func (s *Server) ResetPassword(c echo.Context) error {
+	var reset model.ResetPasswordDTO
+	if err := c.Bind(&reset); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	if reset.Token == "" {
+		return c.String(http.StatusBadRequest, "missing token")
+	}
+
+	if err := validator.Password(reset.Password); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	if err := s.Repository.ResetPassword(c.Request().Context(), reset.Token, reset.Password); err != nil {
+		if errors.Is(err, repository.ErrInvalidToken) {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		c.Logger().Error(fmt.Errorf("failed to reset password: %w", err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.NoContent(http.StatusOK)
+}
@@ -0,0 +1,171 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+
+	"notes/internal/model"
+	"notes/internal/repository"
+)
+
+// header visitors can use to send the password of a public link, instead
+// of posting the password form field. It is never read from the query, which
+// ends up in the browser history and the access logs.
+const publicLinkPasswordHeader = "X-Link-Password"
+
+var publicNoteTemplate = template.Must(template.New("note").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<pre style="white-space: pre-wrap">{{.Content}}</pre>
+<p><small>Last updated {{.UpdatedAt.Format "2006-01-02 15:04"}} UTC</small></p>
+</body>
+</html>
+`))
+
+var publicNotePasswordTemplate = template.Must(template.New("password").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Password required</title>
+</head>
+<body>
+<form method="post" action="?format=html">
+<label>Password <input type="password" name="password" autofocus></label>
+<button type="submit">Open</button>
+{{if .}}<p>Wrong password</p>{{end}}
+</form>
+</body>
+</html>
+`))
+
+func (s *Server) CreatePublicLink(c echo.Context) error {
+	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+	noteID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	var linkDTO model.PublicLinkDTO
+	if err := c.Bind(&linkDTO); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	if linkDTO.ExpiresAt != nil && !linkDTO.ExpiresAt.After(time.Now()) {
+		return c.String(http.StatusBadRequest, "expires_at must be in the future")
+	}
+
+	linkDTO.NoteID = int32(noteID)
+	linkDTO.UserID = userID
+
+	link, err := s.Repository.CreatePublicLink(c.Request().Context(), linkDTO)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return echo.ErrNotFound
+		}
+		c.Logger().Error(fmt.Errorf("failed to create public link for note[%d]: %w", noteID, err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusCreated, link)
+}
+
+func (s *Server) ListPublicLinks(c echo.Context) error {
+	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+	noteID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	links, err := s.Repository.ListPublicLinks(c.Request().Context(), int32(noteID), userID)
+	if err != nil {
+		c.Logger().Error(fmt.Errorf("failed to list public links of note[%d]: %w", noteID, err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, links)
+}
+
+func (s *Server) RevokePublicLink(c echo.Context) error {
+	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+	noteID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+	linkID, err := strconv.Atoi(c.Param("linkID"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	err = s.Repository.RevokePublicLink(c.Request().Context(), int32(noteID), userID, int32(linkID))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return echo.ErrNotFound
+		}
+		c.Logger().Error(fmt.Errorf("failed to revoke public link[%d] of note[%d]: %w", linkID, noteID, err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+// GetPublicNote serves a note through a public link, without authentication.
+// The password of a protected link is posted by the password form.
+func (s *Server) GetPublicNote(c echo.Context) error {
+	password := c.Request().Header.Get(publicLinkPasswordHeader)
+	if password == "" && c.Request().Method == http.MethodPost {
+		password = c.Request().PostFormValue("password")
+	}
+
+	note, err := s.Repository.GetPublicNote(c.Request().Context(), c.Param("token"), password)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return echo.ErrNotFound
+		}
+		if errors.Is(err, repository.ErrInvalidPassword) {
+			if wantsHTML(c) {
+				return render(c, http.StatusUnauthorized, publicNotePasswordTemplate, password != "")
+			}
+			return echo.ErrUnauthorized
+		}
+		c.Logger().Error(fmt.Errorf("failed to get public note: %w", err))
+		return echo.ErrInternalServerError
+	}
+
+	if wantsHTML(c) {
+		return render(c, http.StatusOK, publicNoteTemplate, note)
+	}
+	return c.JSON(http.StatusOK, note)
+}
+
+// checks whether the note should be rendered as HTML, either because it was
+// asked for with ?format=html or because the client is a browser
+func wantsHTML(c echo.Context) bool {
+	switch c.QueryParam("format") {
+	case "html":
+		return true
+	case "json":
+		return false
+	}
+	return strings.Contains(c.Request().Header.Get(echo.HeaderAccept), echo.MIMETextHTML)
+}
+
+func render(c echo.Context, code int, tmpl *template.Template, data any) error {
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return err
+	}
+	return c.HTML(code, sb.String())
+}
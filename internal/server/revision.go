@@ -0,0 +1,155 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+
+	"notes/internal/diff"
+	"notes/internal/model"
+	"notes/internal/repository"
+)
+
+// number of unchanged lines shown around each change in a note diff
+const diffContext = 3
+
+func (s *Server) ListNoteRevisions(c echo.Context) error {
+	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+	noteID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	revisions, err := s.Repository.ListNoteRevisions(c.Request().Context(), int32(noteID), userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return echo.ErrNotFound
+		}
+		c.Logger().Error(fmt.Errorf("failed to list revisions of note[%d]: %w", noteID, err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, revisions)
+}
+
+func (s *Server) GetNoteRevision(c echo.Context) error {
+	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+	noteID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+	revision, err := strconv.Atoi(c.Param("rev"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	noteRevision, err := s.Repository.GetNoteRevision(c.Request().Context(), int32(noteID), userID, int32(revision))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return echo.ErrNotFound
+		}
+		c.Logger().Error(fmt.Errorf("failed to get revision[%d] of note[%d]: %w", revision, noteID, err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, noteRevision)
+}
+
+func (s *Server) RestoreNoteRevision(c echo.Context) error {
+	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+	noteID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+	revision, err := strconv.Atoi(c.Param("rev"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	note, err := s.Repository.RestoreNoteRevision(c.Request().Context(), int32(noteID), userID, int32(revision))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return echo.ErrNotFound
+		}
+		if errors.Is(err, repository.ErrForbidden) {
+			return echo.ErrForbidden
+		}
+		c.Logger().Error(fmt.Errorf("failed to restore revision[%d] of note[%d]: %w", revision, noteID, err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, note)
+}
+
+func (s *Server) DiffNote(c echo.Context) error {
+	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+	noteID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+	from, err := strconv.Atoi(c.QueryParam("from"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, "invalid from revision")
+	}
+
+	fromRevision, err := s.Repository.GetNoteRevision(c.Request().Context(), int32(noteID), userID, int32(from))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return echo.ErrNotFound
+		}
+		c.Logger().Error(fmt.Errorf("failed to get revision[%d] of note[%d]: %w", from, noteID, err))
+		return echo.ErrInternalServerError
+	}
+
+	noteDiff := model.NoteDiff{
+		NoteID: int32(noteID),
+		From:   int32(from),
+	}
+	fromName := fmt.Sprintf("revision %d", from)
+
+	var toName, toTitle, toContent string
+	if c.QueryParam("to") == "" {
+		note, err := s.Repository.GetNoteByUserID(c.Request().Context(), int32(noteID), userID)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return echo.ErrNotFound
+			}
+			c.Logger().Error(fmt.Errorf("failed to get note[%d]: %w", noteID, err))
+			return echo.ErrInternalServerError
+		}
+		toName, toTitle, toContent = "current", note.Title, note.Content
+	} else {
+		to, err := strconv.Atoi(c.QueryParam("to"))
+		if err != nil {
+			return c.String(http.StatusBadRequest, "invalid to revision")
+		}
+
+		toRevision, err := s.Repository.GetNoteRevision(c.Request().Context(), int32(noteID), userID, int32(to))
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return echo.ErrNotFound
+			}
+			c.Logger().Error(fmt.Errorf("failed to get revision[%d] of note[%d]: %w", to, noteID, err))
+			return echo.ErrInternalServerError
+		}
+		noteDiff.To = &toRevision.Revision
+		toName, toTitle, toContent = fmt.Sprintf("revision %d", to), toRevision.Title, toRevision.Content
+	}
+
+	noteDiff.Title = diffField(fromName+"/title", toName+"/title", fromRevision.Title, toTitle)
+	noteDiff.Content = diffField(fromName+"/content", toName+"/content", fromRevision.Content, toContent)
+
+	return c.JSON(http.StatusOK, noteDiff)
+}
+
+func diffField(fromName, toName, from, to string) model.NoteFieldDiff {
+	hunks := diff.Hunks(diff.Lines(from, to), diffContext)
+	return model.NoteFieldDiff{
+		Unified: diff.Unified(fromName, toName, hunks),
+		Hunks:   hunks,
+	}
+}
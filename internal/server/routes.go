@@ -12,34 +12,131 @@ import (
 type jwtClaim struct {
 	jwt.RegisteredClaims
 	ID int32 `json:"id"`
+	// Role is the role of the user when the token was issued
+	Role string `json:"role,omitempty"`
 }
 
 func (s *Server) RegisterRoutes() *echo.Echo {
 	e := echo.New()
+	// the IP of clients is used for rate limits and login lockouts, so
+	// headers that clients can set themselves are only trusted behind a proxy
+	if s.Config.TrustProxy {
+		e.IPExtractor = echo.ExtractIPFromXFFHeader()
+	} else {
+		e.IPExtractor = echo.ExtractIPDirect()
+	}
 
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
 	e.Use(middleware.RateLimiter(middleware.NewRateLimiterMemoryStore(rate.Limit(s.Config.RateLimit))))
 
+	verifyJWT := echojwt.WithConfig(echojwt.Config{
+		KeyFunc: s.Config.Keys.Keyfunc,
+		NewClaimsFunc: func(c echo.Context) jwt.Claims {
+			return new(jwtClaim)
+		},
+	})
+	jwtMiddleware := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return verifyJWT(s.requireActiveSession(next))
+	}
+
 	users := e.Group("/api/auth")
 	users.POST("/signup", s.CreateUser)
 	users.POST("/login", s.LogIn)
+	users.POST("/login/2fa", s.LogInTOTP)
+	users.POST("/refresh", s.RefreshToken)
+	users.POST("/logout", s.LogOut, jwtMiddleware)
+	users.POST("/logout-all", s.LogOutAll, jwtMiddleware)
+	users.GET("/verify", s.VerifyEmail)
+	users.POST("/verify/resend", s.ResendVerificationEmail)
+	users.POST("/password/forgot", s.ForgotPassword)
+	users.POST("/password/reset", s.ResetPassword)
+	users.GET("/oidc/login", s.OIDCLogIn)
+	users.GET("/oidc/callback", s.OIDCCallback)
+
+	me := e.Group("/api/users/me")
+	me.Use(jwtMiddleware)
+	me.GET("", s.GetProfile)
+	me.PUT("", s.UpdateProfile)
+	me.DELETE("", s.DeleteAccount)
+	me.PUT("/password", s.ChangePassword)
+	me.PUT("/email", s.ChangeEmail)
+	me.GET("/tokens", s.ListAccessTokens)
+	me.POST("/tokens", s.CreateAccessToken)
+	me.DELETE("/tokens/:id", s.RevokeAccessToken)
+	me.POST("/2fa", s.EnrollTOTP)
+	me.POST("/2fa/confirm", s.ConfirmTOTP)
+	me.DELETE("/2fa", s.DisableTOTP)
+	me.POST("/2fa/recovery-codes", s.RegenerateRecoveryCodes)
 
 	notes := e.Group("/api/notes")
-	notes.Use(echojwt.WithConfig(echojwt.Config{
-		SigningKey: []byte(s.Config.SignInKey),
-		NewClaimsFunc: func(c echo.Context) jwt.Claims {
-			return new(jwtClaim)
-		},
-	}))
+	notes.Use(s.allowAccessTokens(jwtMiddleware))
 	notes.GET("/", s.ListNotes)
+	notes.GET("/trash", s.ListTrash)
 	notes.GET("/:id", s.GetNote)
 	notes.POST("/", s.CreateNote)
 	notes.PUT("/:id", s.UpdateNote)
 	notes.DELETE("/:id", s.DeleteNote)
+	notes.POST("/:id/restore", s.RestoreNote)
+	notes.PUT("/:id/notebook", s.MoveNote)
 	notes.POST("/:id/share", s.ShareNote)
+	notes.GET("/:id/shares", s.ListNoteShares)
+	notes.DELETE("/:id/shares/:userID", s.RevokeNoteShare)
+	notes.POST("/:id/leave", s.LeaveNote)
+	notes.GET("/:id/invitations", s.ListShareInvitations)
+	notes.DELETE("/:id/invitations/:invitationID", s.CancelShareInvitation)
+	notes.GET("/:id/links", s.ListPublicLinks)
+	notes.POST("/:id/links", s.CreatePublicLink)
+	notes.DELETE("/:id/links/:linkID", s.RevokePublicLink)
+	notes.GET("/:id/revisions", s.ListNoteRevisions)
+	notes.GET("/:id/revisions/:rev", s.GetNoteRevision)
+	notes.POST("/:id/revisions/:rev/restore", s.RestoreNoteRevision)
+	notes.GET("/:id/diff", s.DiffNote)
 
 	notes.GET("/search", s.SearchNotes)
 
+	tags := e.Group("/api/tags")
+	tags.Use(jwtMiddleware)
+	tags.GET("/", s.ListTags)
+	tags.PUT("/:name", s.RenameTag)
+
+	notebooks := e.Group("/api/notebooks")
+	notebooks.Use(jwtMiddleware)
+	notebooks.GET("/", s.ListNotebooks)
+	notebooks.GET("/:id", s.GetNotebook)
+	notebooks.POST("/", s.CreateNotebook)
+	notebooks.PUT("/:id", s.UpdateNotebook)
+	notebooks.DELETE("/:id", s.DeleteNotebook)
+	notebooks.GET("/:id/notes", s.ListNotebookNotes)
+	notebooks.POST("/:id/share", s.ShareNotebook)
+	notebooks.GET("/:id/shares", s.ListNotebookShares)
+	notebooks.DELETE("/:id/shares/:userID", s.RevokeNotebookShare)
+	notebooks.POST("/:id/leave", s.LeaveNotebook)
+
+	searches := e.Group("/api/searches")
+	searches.Use(jwtMiddleware)
+	searches.GET("/", s.ListSavedSearches)
+	searches.GET("/:id", s.GetSavedSearch)
+	searches.POST("/", s.CreateSavedSearch)
+	searches.PUT("/:id", s.UpdateSavedSearch)
+	searches.DELETE("/:id", s.DeleteSavedSearch)
+	searches.GET("/:id/results", s.GetSavedSearchResults)
+
+	admin := e.Group("/api/admin")
+	admin.Use(jwtMiddleware, s.requireAdmin, s.auditAdminAction)
+	admin.GET("/users", s.ListUsers)
+	admin.GET("/users/:id", s.GetUser)
+	admin.POST("/users/:id/disable", s.DisableUser)
+	admin.POST("/users/:id/enable", s.EnableUser)
+	admin.POST("/users/:id/password-reset", s.ForcePasswordReset)
+	admin.GET("/audit", s.ListAuditEntries)
+
+	// other services verify our tokens with these keys
+	e.GET("/.well-known/jwks.json", s.JWKS)
+
+	// public links are readable without an account
+	e.GET("/p/:token", s.GetPublicNote)
+	e.POST("/p/:token", s.GetPublicNote)
+
 	return e
 }
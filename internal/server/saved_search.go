@@ -0,0 +1,259 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+
+	"notes/internal/model"
+	"notes/internal/repository"
+	"notes/internal/validator"
+)
+
+// ListSavedSearches lists the saved searches of the user by name. With
+// ?unread=true, each comes with its number of unread notes.
+func (s *Server) ListSavedSearches(c echo.Context) error {
+	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+
+	searches, err := s.Repository.ListSavedSearches(c.Request().Context(), userID)
+	if err != nil {
+		c.Logger().Error(fmt.Errorf("failed to list saved searches for user[%d]: %w", userID, err))
+		return echo.ErrInternalServerError
+	}
+
+	if c.QueryParam("unread") == "true" {
+		for i := range searches {
+			if err := s.countUnread(c.Request().Context(), &searches[i]); err != nil {
+				c.Logger().Error(fmt.Errorf("failed to count unread notes of saved search[%d]: %w", searches[i].ID, err))
+				return echo.ErrInternalServerError
+			}
+		}
+	}
+
+	return c.JSON(http.StatusOK, searches)
+}
+
+func (s *Server) GetSavedSearch(c echo.Context) error {
+	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+	searchID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	search, err := s.Repository.GetSavedSearch(c.Request().Context(), int32(searchID), userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return echo.ErrNotFound
+		}
+		c.Logger().Error(fmt.Errorf("failed to get saved search[%d]: %w", searchID, err))
+		return echo.ErrInternalServerError
+	}
+
+	if c.QueryParam("unread") == "true" {
+		if err := s.countUnread(c.Request().Context(), search); err != nil {
+			c.Logger().Error(fmt.Errorf("failed to count unread notes of saved search[%d]: %w", searchID, err))
+			return echo.ErrInternalServerError
+		}
+	}
+
+	return c.JSON(http.StatusOK, search)
+}
+
+func (s *Server) CreateSavedSearch(c echo.Context) error {
+	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+
+	var searchDTO model.SavedSearchDTO
+	if err := c.Bind(&searchDTO); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	if err := prepareSavedSearch(&searchDTO); err != nil {
+		return badSearch(c, err)
+	}
+
+	searchDTO.UserID = userID
+
+	search, err := s.Repository.CreateSavedSearch(c.Request().Context(), searchDTO)
+	if err != nil {
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			return c.String(http.StatusConflict, "a saved search with this name already exists")
+		}
+		c.Logger().Error(fmt.Errorf("failed to create saved search: %w", err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusCreated, search)
+}
+
+func (s *Server) UpdateSavedSearch(c echo.Context) error {
+	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+	searchID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	var searchDTO model.SavedSearchDTO
+	if err := c.Bind(&searchDTO); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	if err := prepareSavedSearch(&searchDTO); err != nil {
+		return badSearch(c, err)
+	}
+
+	searchDTO.UserID = userID
+
+	search, err := s.Repository.UpdateSavedSearch(c.Request().Context(), int32(searchID), searchDTO)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return echo.ErrNotFound
+		}
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			return c.String(http.StatusConflict, "a saved search with this name already exists")
+		}
+		c.Logger().Error(fmt.Errorf("failed to update saved search[%d]: %w", searchID, err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, search)
+}
+
+func (s *Server) DeleteSavedSearch(c echo.Context) error {
+	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+	searchID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	err = s.Repository.DeleteSavedSearch(c.Request().Context(), int32(searchID), userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return echo.ErrNotFound
+		}
+		c.Logger().Error(fmt.Errorf("failed to delete saved search[%d]: %w", searchID, err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+// GetSavedSearchResults runs a saved search, paginated and highlighted like
+// other searches, and marks its notes as read
+func (s *Server) GetSavedSearchResults(c echo.Context) error {
+	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+	searchID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	saved, err := s.Repository.GetSavedSearch(c.Request().Context(), int32(searchID), userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return echo.ErrNotFound
+		}
+		c.Logger().Error(fmt.Errorf("failed to get saved search[%d]: %w", searchID, err))
+		return echo.ErrInternalServerError
+	}
+
+	search := savedNoteSearch(*saved)
+	if err := highlightFromQuery(c, &search); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	if err := prepareNoteSearch(&search); err != nil {
+		return badSearch(c, err)
+	}
+
+	page, err := notePageFromQuery(c, true)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	if err := s.defaultSearchLanguage(c.Request().Context(), userID, &search); err != nil {
+		c.Logger().Error(fmt.Errorf("failed to get the search language of user[%d]: %w", userID, err))
+		return echo.ErrInternalServerError
+	}
+
+	// the results are read as of before the search, so that notes updated
+	// while it runs stay unread
+	viewedAt := time.Now()
+	notes, err := s.Repository.SearchNotes(c.Request().Context(), userID, search, page)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		c.Logger().Error(fmt.Errorf("failed to run saved search[%d]: %w", searchID, err))
+		return echo.ErrInternalServerError
+	}
+
+	// the next pages belong to the same view of the results
+	if page.Cursor == "" {
+		if err := s.Repository.MarkSavedSearchViewed(c.Request().Context(), int32(searchID), userID, viewedAt); err != nil {
+			c.Logger().Error(fmt.Errorf("failed to mark saved search[%d] as viewed: %w", searchID, err))
+			return echo.ErrInternalServerError
+		}
+	}
+
+	return c.JSON(http.StatusOK, notes)
+}
+
+// prepareSavedSearch checks a saved search like the searches it runs, and
+// defaults its mode and threshold the same way
+func prepareSavedSearch(searchDTO *model.SavedSearchDTO) error {
+	if err := validator.SavedSearchName(searchDTO.Name); err != nil {
+		return err
+	}
+
+	search := model.NoteSearch{
+		Query:     searchDTO.Query,
+		Mode:      searchDTO.Mode,
+		Threshold: defaultSearchThreshold,
+		Language:  searchDTO.Language,
+	}
+	if searchDTO.Threshold != nil {
+		search.Threshold = *searchDTO.Threshold
+	}
+	if err := prepareNoteSearch(&search); err != nil {
+		return err
+	}
+
+	searchDTO.Mode = search.Mode
+	searchDTO.Threshold = &search.Threshold
+	searchDTO.Language = search.Language
+	return nil
+}
+
+// savedNoteSearch is the search a saved search runs, before it is prepared
+func savedNoteSearch(saved model.SavedSearch) model.NoteSearch {
+	return model.NoteSearch{
+		Query:     saved.Query,
+		Mode:      saved.Mode,
+		Threshold: saved.Threshold,
+		Language:  saved.Language,
+	}
+}
+
+// countUnread sets the number of notes matching the saved search that were
+// updated since its results were last viewed, or all of them if they never
+// were
+func (s *Server) countUnread(ctx context.Context, saved *model.SavedSearch) error {
+	search := savedNoteSearch(*saved)
+	if err := prepareNoteSearch(&search); err != nil {
+		return err
+	}
+	if err := s.defaultSearchLanguage(ctx, saved.UserID, &search); err != nil {
+		return err
+	}
+
+	unread, err := s.Repository.CountSearchNotes(ctx, saved.UserID, search, saved.LastViewedAt)
+	if err != nil {
+		return err
+	}
+	saved.Unread = &unread
+	return nil
+}
@@ -1,31 +1,94 @@
 package server
 
 import (
+	"context"
 	"fmt"
+	"log"
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	_ "github.com/joho/godotenv/autoload"
 
 	"notes/internal/database"
+	"notes/internal/jwtkeys"
+	"notes/internal/mail"
+	"notes/internal/model"
+	"notes/internal/oidc"
 	"notes/internal/repository"
 )
 
+// how long deleted notes are kept in the trash unless TRASH_RETENTION is set
+const defaultTrashRetention = 30 * 24 * time.Hour
+
+// lifetimes of the tokens issued at login unless ACCESS_TOKEN_TTL and
+// REFRESH_TOKEN_TTL are set
+const (
+	defaultAccessTokenTTL  = 15 * time.Minute
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// how failed logins lock out further ones unless LOGIN_MAX_FAILURES,
+// LOGIN_MAX_FAILURES_PER_IP, LOGIN_LOCKOUT and LOGIN_MAX_LOCKOUT are set
+var defaultLoginLockout = model.LoginLockout{
+	AccountThreshold: 5,
+	IPThreshold:      50,
+	Delay:            30 * time.Second,
+	MaxDelay:         time.Hour,
+	Window:           24 * time.Hour,
+}
+
 type Config struct {
 	Host      string
 	Port      int
 	RateLimit int
+	// SignInKey is the HMAC secret tokens are signed with when there is no
+	// signing key file, or were signed with before there was one
 	SignInKey string
+	// Keys sign and verify the JWTs of the service
+	Keys *jwtkeys.KeySet
+	// TrashRetention is how long deleted notes stay in the trash before
+	// they are purged. Purging is disabled when it is not positive.
+	TrashRetention time.Duration
+	// AccessTokenTTL is how long the JWTs used to call the API are valid,
+	// RefreshTokenTTL how long a session can be kept alive without use.
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	// Mail configures how emails, like password resets, are sent
+	Mail mail.Config
+	// BaseURL is where users reach the server, used for links in emails
+	BaseURL string
+	// RequireVerifiedEmail denies logging in, and receiving shares, to
+	// users who haven't verified their email
+	RequireVerifiedEmail bool
+	// OIDC configures logging in with an OpenID Connect provider, which is
+	// disabled when it has no issuer. With OIDCSignUp, users unknown to
+	// the service get an account the first time they log in.
+	OIDC       oidc.Config
+	OIDCSignUp bool
+	// LoginLockout is how failed logins per account and per IP lock out
+	// further ones
+	LoginLockout model.LoginLockout
+	// TrustProxy takes the IP of clients from the X-Forwarded-For header
+	// set by a reverse proxy, instead of the address they connect from
+	TrustProxy bool
 }
 
 func NewConfig(host string, port int, rateLimit int, signInKey string) Config {
 	return Config{
-		Host:      host,
-		Port:      port,
-		RateLimit: rateLimit,
-		SignInKey: signInKey,
+		Host:            host,
+		Port:            port,
+		RateLimit:       rateLimit,
+		SignInKey:       signInKey,
+		Keys:            jwtkeys.NewHMAC(signInKey),
+		TrashRetention:  defaultTrashRetention,
+		AccessTokenTTL:  defaultAccessTokenTTL,
+		RefreshTokenTTL: defaultRefreshTokenTTL,
+		Mail:            mail.Config{Driver: mail.DriverLog},
+		BaseURL:         fmt.Sprintf("http://localhost:%d", port),
+		LoginLockout:    defaultLoginLockout,
 	}
 }
 
@@ -40,30 +103,169 @@ func LoadConfigFromEnv() (Config, error) {
 		return Config{}, fmt.Errorf("failed to parse port: %w", err)
 	}
 	signInKey := os.Getenv("SIGNIN_KEY")
-	if signInKey == "" {
-		return Config{}, fmt.Errorf("missing SIGNIN_KEY env")
+	var keys *jwtkeys.KeySet
+	if signingKeyFile := os.Getenv("JWT_SIGNING_KEY_FILE"); signingKeyFile != "" {
+		var verificationKeyFiles []string
+		for _, file := range strings.Split(os.Getenv("JWT_VERIFICATION_KEY_FILES"), ",") {
+			if file = strings.TrimSpace(file); file != "" {
+				verificationKeyFiles = append(verificationKeyFiles, file)
+			}
+		}
+		keys, err = jwtkeys.Load(signingKeyFile, verificationKeyFiles, signInKey)
+		if err != nil {
+			return Config{}, fmt.Errorf("failed to load jwt keys: %w", err)
+		}
+	} else {
+		if signInKey == "" {
+			return Config{}, fmt.Errorf("missing SIGNIN_KEY or JWT_SIGNING_KEY_FILE env")
+		}
+		keys = jwtkeys.NewHMAC(signInKey)
+	}
+	trashRetention := defaultTrashRetention
+	if env := os.Getenv("TRASH_RETENTION"); env != "" {
+		trashRetention, err = time.ParseDuration(env)
+		if err != nil {
+			return Config{}, fmt.Errorf("failed to parse trash retention: %w", err)
+		}
+	}
+	accessTokenTTL := defaultAccessTokenTTL
+	if env := os.Getenv("ACCESS_TOKEN_TTL"); env != "" {
+		accessTokenTTL, err = time.ParseDuration(env)
+		if err != nil {
+			return Config{}, fmt.Errorf("failed to parse access token ttl: %w", err)
+		}
+	}
+	refreshTokenTTL := defaultRefreshTokenTTL
+	if env := os.Getenv("REFRESH_TOKEN_TTL"); env != "" {
+		refreshTokenTTL, err = time.ParseDuration(env)
+		if err != nil {
+			return Config{}, fmt.Errorf("failed to parse refresh token ttl: %w", err)
+		}
+	}
+	mailConfig := mail.Config{
+		Driver:   os.Getenv("MAIL_DRIVER"),
+		From:     os.Getenv("MAIL_FROM"),
+		Host:     os.Getenv("SMTP_HOST"),
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		Dir:      os.Getenv("MAIL_DIR"),
+	}
+	if env := os.Getenv("SMTP_PORT"); env != "" {
+		mailConfig.Port, err = strconv.Atoi(env)
+		if err != nil {
+			return Config{}, fmt.Errorf("failed to parse smtp port: %w", err)
+		}
+	}
+	baseURL := os.Getenv("BASE_URL")
+	if baseURL == "" {
+		baseURL = fmt.Sprintf("http://localhost:%d", port)
+	}
+	var requireVerifiedEmail bool
+	if env := os.Getenv("REQUIRE_VERIFIED_EMAIL"); env != "" {
+		requireVerifiedEmail, err = strconv.ParseBool(env)
+		if err != nil {
+			return Config{}, fmt.Errorf("failed to parse require verified email: %w", err)
+		}
+	}
+	oidcConfig := oidc.Config{
+		Issuer:       os.Getenv("OIDC_ISSUER"),
+		ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+	}
+	if oidcConfig.RedirectURL == "" {
+		oidcConfig.RedirectURL = strings.TrimSuffix(baseURL, "/") + "/api/auth/oidc/callback"
+	}
+	var oidcSignUp bool
+	if env := os.Getenv("OIDC_SIGNUP"); env != "" {
+		oidcSignUp, err = strconv.ParseBool(env)
+		if err != nil {
+			return Config{}, fmt.Errorf("failed to parse oidc signup: %w", err)
+		}
+	}
+	loginLockout := defaultLoginLockout
+	if env := os.Getenv("LOGIN_MAX_FAILURES"); env != "" {
+		loginLockout.AccountThreshold, err = strconv.Atoi(env)
+		if err != nil {
+			return Config{}, fmt.Errorf("failed to parse login max failures: %w", err)
+		}
+	}
+	if env := os.Getenv("LOGIN_MAX_FAILURES_PER_IP"); env != "" {
+		loginLockout.IPThreshold, err = strconv.Atoi(env)
+		if err != nil {
+			return Config{}, fmt.Errorf("failed to parse login max failures per ip: %w", err)
+		}
 	}
-	if signInKey == "" {
-		return Config{}, fmt.Errorf("missing SIGNIN_KEY env")
+	if env := os.Getenv("LOGIN_LOCKOUT"); env != "" {
+		loginLockout.Delay, err = time.ParseDuration(env)
+		if err != nil {
+			return Config{}, fmt.Errorf("failed to parse login lockout: %w", err)
+		}
+	}
+	if env := os.Getenv("LOGIN_MAX_LOCKOUT"); env != "" {
+		loginLockout.MaxDelay, err = time.ParseDuration(env)
+		if err != nil {
+			return Config{}, fmt.Errorf("failed to parse login max lockout: %w", err)
+		}
+	}
+	var trustProxy bool
+	if env := os.Getenv("TRUST_PROXY"); env != "" {
+		trustProxy, err = strconv.ParseBool(env)
+		if err != nil {
+			return Config{}, fmt.Errorf("failed to parse trust proxy: %w", err)
+		}
 	}
 
 	return Config{
-		Host:      host,
-		Port:      port,
-		RateLimit: rateLimit,
-		SignInKey: signInKey,
+		Host:                 host,
+		Port:                 port,
+		RateLimit:            rateLimit,
+		SignInKey:            signInKey,
+		Keys:                 keys,
+		TrashRetention:       trashRetention,
+		AccessTokenTTL:       accessTokenTTL,
+		RefreshTokenTTL:      refreshTokenTTL,
+		Mail:                 mailConfig,
+		BaseURL:              strings.TrimSuffix(baseURL, "/"),
+		RequireVerifiedEmail: requireVerifiedEmail,
+		OIDC:                 oidcConfig,
+		OIDCSignUp:           oidcSignUp,
+		LoginLockout:         loginLockout,
+		TrustProxy:           trustProxy,
 	}, nil
 }
 
 type Server struct {
 	Config     Config
 	Repository repository.Repository
+	Mailer     mail.Mailer
+	// OIDC is the identity provider users can log in with, if any
+	OIDC *oidc.Provider
 }
 
-func NewServer(config Config) *http.Server {
+func NewServer(config Config) (*http.Server, error) {
+	mailer, err := mail.New(config.Mail)
+	if err != nil {
+		return nil, err
+	}
+
+	repo := database.New()
+	repo.RequireVerifiedEmail = config.RequireVerifiedEmail
+
 	NewServer := &Server{
 		Config:     config,
-		Repository: database.New(),
+		Repository: repo,
+		Mailer:     mailer,
+	}
+
+	if config.OIDC.Issuer != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		NewServer.OIDC, err = oidc.Discover(ctx, config.OIDC)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	server := &http.Server{
@@ -73,5 +275,29 @@ func NewServer(config Config) *http.Server {
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 30 * time.Second,
 	}
-	return server
+
+	go NewServer.PurgeTrash(context.Background())
+	go NewServer.PurgeSessions(context.Background())
+	go NewServer.PurgeLoginFailures(context.Background())
+
+	return server, nil
+}
+
+// runPeriodically runs task right away, then every interval, logging what
+// it failed to do as name. It blocks until ctx is done.
+func runPeriodically(ctx context.Context, interval time.Duration, name string, task func(context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := task(ctx); err != nil {
+			log.Printf("failed to %s: %s", name, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
 }
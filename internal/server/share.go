@@ -0,0 +1,113 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+
+	"notes/internal/repository"
+)
+
+func (s *Server) ListNoteShares(c echo.Context) error {
+	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+	noteID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	shares, err := s.Repository.ListNoteShares(c.Request().Context(), int32(noteID), userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return echo.ErrNotFound
+		}
+		c.Logger().Error(fmt.Errorf("failed to list shares of note[%d]: %w", noteID, err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, shares)
+}
+
+func (s *Server) RevokeNoteShare(c echo.Context) error {
+	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+	noteID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+	sharedWithUserID, err := strconv.Atoi(c.Param("userID"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	err = s.Repository.RevokeNoteShare(c.Request().Context(), int32(noteID), userID, int32(sharedWithUserID))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return echo.ErrNotFound
+		}
+		c.Logger().Error(fmt.Errorf("failed to revoke share of note[%d] with user[%d]: %w", noteID, sharedWithUserID, err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+func (s *Server) LeaveNote(c echo.Context) error {
+	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+	noteID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	err = s.Repository.LeaveNote(c.Request().Context(), int32(noteID), userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return echo.ErrNotFound
+		}
+		c.Logger().Error(fmt.Errorf("failed to leave note[%d]: %w", noteID, err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+func (s *Server) ListShareInvitations(c echo.Context) error {
+	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+	noteID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	invitations, err := s.Repository.ListShareInvitations(c.Request().Context(), int32(noteID), userID)
+	if err != nil {
+		c.Logger().Error(fmt.Errorf("failed to list invitations of note[%d]: %w", noteID, err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, invitations)
+}
+
+func (s *Server) CancelShareInvitation(c echo.Context) error {
+	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+	noteID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+	invitationID, err := strconv.Atoi(c.Param("invitationID"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	err = s.Repository.CancelShareInvitation(c.Request().Context(), int32(noteID), userID, int32(invitationID))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return echo.ErrNotFound
+		}
+		c.Logger().Error(fmt.Errorf("failed to cancel invitation[%d] of note[%d]: %w", invitationID, noteID, err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.NoContent(http.StatusOK)
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+
+	"notes/internal/model"
+	"notes/internal/repository"
+	"notes/internal/validator"
+)
+
+func (s *Server) ListTags(c echo.Context) error {
+	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+
+	tags, err := s.Repository.ListTags(c.Request().Context(), userID)
+	if err != nil {
+		c.Logger().Error(fmt.Errorf("failed to list tags for user[%d]: %w", userID, err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, tags)
+}
+
+// RenameTag renames a tag on all notes of the user. Renaming a tag to the
+// name of another existing tag merges the two.
+func (s *Server) RenameTag(c echo.Context) error {
+	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+	name, err := tagFromPath(c)
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	var tagRenameDTO model.TagRenameDTO
+	if err := c.Bind(&tagRenameDTO); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	if err := validator.Tag(tagRenameDTO.Name); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	err = s.Repository.RenameTag(c.Request().Context(), userID, name, tagRenameDTO.Name)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return echo.ErrNotFound
+		}
+		c.Logger().Error(fmt.Errorf("failed to rename tag[%s] of user[%d]: %w", name, userID, err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+// tagFromPath reads the name of the tag in the path. Echo matches the path
+// as it was escaped when it has escaped characters, such as the slash of
+// a/b in /api/tags/a%2Fb, and leaves the name escaped then.
+func tagFromPath(c echo.Context) (string, error) {
+	name := c.Param("name")
+	if c.Request().URL.RawPath == "" {
+		return name, nil
+	}
+	return url.PathUnescape(name)
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+
+	"notes/internal/model"
+	"notes/internal/repository"
+)
+
+// how often the trash is checked for notes past their retention
+const trashPurgeInterval = time.Hour
+
+func (s *Server) ListTrash(c echo.Context) error {
+	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+
+	notes, err := s.Repository.ListDeletedNotes(c.Request().Context(), userID)
+	if err != nil {
+		c.Logger().Error(fmt.Errorf("failed to list deleted notes for user[%d]: %w", userID, err))
+		return echo.ErrInternalServerError
+	}
+
+	if len(notes) == 0 {
+		return c.JSON(http.StatusOK, []model.Note{})
+	}
+
+	return c.JSON(http.StatusOK, notes)
+}
+
+func (s *Server) RestoreNote(c echo.Context) error {
+	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+	noteID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.ErrBadRequest
+	}
+
+	note, err := s.Repository.RestoreNote(c.Request().Context(), int32(noteID), userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return echo.ErrNotFound
+		}
+		c.Logger().Error(fmt.Errorf("failed to restore note[%d]: %w", noteID, err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, note)
+}
+
+// PurgeTrash permanently removes notes that have been in the trash for
+// longer than the configured retention. It blocks until ctx is done.
+func (s *Server) PurgeTrash(ctx context.Context) {
+	if s.Config.TrashRetention <= 0 {
+		return
+	}
+
+	runPeriodically(ctx, trashPurgeInterval, "purge trash", func(ctx context.Context) error {
+		purged, err := s.Repository.PurgeDeletedNotes(ctx, s.Config.TrashRetention)
+		if err != nil {
+			return err
+		}
+		if purged > 0 {
+			log.Printf("purged %d notes from trash", purged)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,280 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+
+	"notes/internal/model"
+	"notes/internal/repository"
+	"notes/internal/totp"
+)
+
+const (
+	// name of the service shown by authenticator apps
+	totpIssuer = "Notes"
+	// how long users have to enter a code after their password
+	loginChallengeTTL = 5 * time.Minute
+)
+
+// issueLoginChallenge responds with a challenge to complete the login of
+// the user with a code
+func (s *Server) issueLoginChallenge(c echo.Context, userID int32) error {
+	expiresAt := time.Now().Add(loginChallengeTTL)
+
+	token, err := s.Repository.CreateLoginChallenge(c.Request().Context(), userID, loginChallengeTTL)
+	if err != nil {
+		c.Logger().Error(fmt.Errorf("failed to create login challenge for user[%d]: %w", userID, err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, model.LoginChallenge{
+		ChallengeToken: token,
+		ExpiresAt:      expiresAt,
+	})
+}
+
+// checkSecondFactor checks a code of the authenticator app of the user, or
+// one of their recovery codes if allowed. Codes are used up, and invalid
+// ones return ErrInvalidToken.
+func (s *Server) checkSecondFactor(c echo.Context, userID int32, code string, allowRecoveryCode bool) error {
+	userTOTP, err := s.Repository.GetTOTP(c.Request().Context(), userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return repository.ErrInvalidToken
+		}
+		return err
+	}
+	if userTOTP.EnabledAt == nil {
+		return repository.ErrInvalidToken
+	}
+
+	counter, err := totp.Validate(userTOTP.Secret, code, time.Now())
+	if err == nil {
+		return s.Repository.UseTOTP(c.Request().Context(), userID, counter)
+	}
+	if !errors.Is(err, totp.ErrInvalidCode) {
+		return err
+	}
+
+	if !allowRecoveryCode {
+		return repository.ErrInvalidToken
+	}
+	return s.Repository.UseRecoveryCode(c.Request().Context(), userID, code)
+}
+
+// LogInTOTP completes a login challenge with a code, responding like LogIn
+func (s *Server) LogInTOTP(c echo.Context) error {
+	var login model.LogInTOTPDTO
+	if err := c.Bind(&login); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	if login.ChallengeToken == "" || login.Code == "" {
+		return c.String(http.StatusBadRequest, "missing challenge_token or code")
+	}
+
+	userID, err := s.Repository.UseLoginChallenge(c.Request().Context(), login.ChallengeToken)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidToken) {
+			return echo.ErrUnauthorized
+		}
+		c.Logger().Error(fmt.Errorf("failed to use login challenge: %w", err))
+		return echo.ErrInternalServerError
+	}
+
+	user, err := s.Repository.GetUser(c.Request().Context(), userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return echo.ErrUnauthorized
+		}
+		c.Logger().Error(fmt.Errorf("failed to get user[%d]: %w", userID, err))
+		return echo.ErrInternalServerError
+	}
+
+	// wrong codes count as failed logins, so they can't be guessed by
+	// logging in with the password over and over
+	if locked, err := s.checkLoginLockout(c, user.Email); locked {
+		return err
+	}
+
+	if err := s.checkSecondFactor(c, userID, login.Code, true); err != nil {
+		if errors.Is(err, repository.ErrInvalidToken) {
+			return s.loginFailed(c, user.Email)
+		}
+		c.Logger().Error(fmt.Errorf("failed to check code of user[%d]: %w", userID, err))
+		return echo.ErrInternalServerError
+	}
+
+	if err := s.Repository.ClearLoginFailures(c.Request().Context(), user.Email, c.RealIP()); err != nil {
+		c.Logger().Error(fmt.Errorf("failed to clear login failures of user[%d]: %w", userID, err))
+		return echo.ErrInternalServerError
+	}
+
+	session, err := s.Repository.CreateSession(c.Request().Context(), userID, s.Config.RefreshTokenTTL)
+	if err != nil {
+		c.Logger().Error(fmt.Errorf("failed to create session for user[%d]: %w", userID, err))
+		return echo.ErrInternalServerError
+	}
+
+	return s.issueTokens(c, session)
+}
+
+// EnrollTOTP starts enabling two-factor authentication, responding with
+// the secret to add to an authenticator app
+func (s *Server) EnrollTOTP(c echo.Context) error {
+	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+
+	var enrollDTO model.EnrollTOTPDTO
+	if err := c.Bind(&enrollDTO); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	user, err := s.Repository.GetUser(c.Request().Context(), userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return echo.ErrNotFound
+		}
+		c.Logger().Error(fmt.Errorf("failed to get user[%d]: %w", userID, err))
+		return echo.ErrInternalServerError
+	}
+
+	secret, err := totp.NewSecret()
+	if err != nil {
+		c.Logger().Error(fmt.Errorf("failed to generate totp secret: %w", err))
+		return echo.ErrInternalServerError
+	}
+
+	err = s.Repository.StartTOTPEnrollment(c.Request().Context(), userID, enrollDTO.Password, secret)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidPassword) {
+			return c.String(http.StatusForbidden, err.Error())
+		}
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			return c.String(http.StatusConflict, "two-factor authentication is already enabled")
+		}
+		c.Logger().Error(fmt.Errorf("failed to enroll totp of user[%d]: %w", userID, err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, model.TOTPEnrollment{
+		Secret: secret,
+		URI:    totp.URI(totpIssuer, user.Email, secret),
+	})
+}
+
+// ConfirmTOTP enables two-factor authentication with the first code of the
+// authenticator app, responding with the recovery codes
+func (s *Server) ConfirmTOTP(c echo.Context) error {
+	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+
+	var codeDTO model.TOTPCodeDTO
+	if err := c.Bind(&codeDTO); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	userTOTP, err := s.Repository.GetTOTP(c.Request().Context(), userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return c.String(http.StatusBadRequest, "two-factor authentication was not enrolled")
+		}
+		c.Logger().Error(fmt.Errorf("failed to get totp of user[%d]: %w", userID, err))
+		return echo.ErrInternalServerError
+	}
+	if userTOTP.EnabledAt != nil {
+		return c.String(http.StatusConflict, "two-factor authentication is already enabled")
+	}
+
+	counter, err := totp.Validate(userTOTP.Secret, codeDTO.Code, time.Now())
+	if err != nil {
+		if errors.Is(err, totp.ErrInvalidCode) {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		c.Logger().Error(fmt.Errorf("failed to validate totp of user[%d]: %w", userID, err))
+		return echo.ErrInternalServerError
+	}
+
+	codes, err := s.Repository.EnableTOTP(c.Request().Context(), userID, counter)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return c.String(http.StatusConflict, "two-factor authentication is already enabled")
+		}
+		c.Logger().Error(fmt.Errorf("failed to enable totp of user[%d]: %w", userID, err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, model.RecoveryCodes{Codes: codes})
+}
+
+// DisableTOTP turns off two-factor authentication, given the password and
+// a code of the authenticator app or a recovery code
+func (s *Server) DisableTOTP(c echo.Context) error {
+	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+
+	var disableDTO model.DisableTOTPDTO
+	if err := c.Bind(&disableDTO); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	// the password is checked first, so that a wrong one doesn't use up the
+	// code
+	if err := s.Repository.CheckPassword(c.Request().Context(), userID, disableDTO.Password); err != nil {
+		if errors.Is(err, repository.ErrInvalidPassword) {
+			return c.String(http.StatusForbidden, err.Error())
+		}
+		if errors.Is(err, repository.ErrNotFound) {
+			return echo.ErrNotFound
+		}
+		c.Logger().Error(fmt.Errorf("failed to check password of user[%d]: %w", userID, err))
+		return echo.ErrInternalServerError
+	}
+
+	if err := s.checkSecondFactor(c, userID, disableDTO.Code, true); err != nil {
+		if errors.Is(err, repository.ErrInvalidToken) {
+			return c.String(http.StatusForbidden, "invalid code")
+		}
+		c.Logger().Error(fmt.Errorf("failed to check code of user[%d]: %w", userID, err))
+		return echo.ErrInternalServerError
+	}
+
+	if err := s.Repository.DisableTOTP(c.Request().Context(), userID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return echo.ErrNotFound
+		}
+		c.Logger().Error(fmt.Errorf("failed to disable totp of user[%d]: %w", userID, err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the user, given a
+// code of the authenticator app
+func (s *Server) RegenerateRecoveryCodes(c echo.Context) error {
+	userID := c.Get("user").(*jwt.Token).Claims.(*jwtClaim).ID
+
+	var codeDTO model.TOTPCodeDTO
+	if err := c.Bind(&codeDTO); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	if err := s.checkSecondFactor(c, userID, codeDTO.Code, false); err != nil {
+		if errors.Is(err, repository.ErrInvalidToken) {
+			return c.String(http.StatusForbidden, "invalid code")
+		}
+		c.Logger().Error(fmt.Errorf("failed to check code of user[%d]: %w", userID, err))
+		return echo.ErrInternalServerError
+	}
+
+	codes, err := s.Repository.RegenerateRecoveryCodes(c.Request().Context(), userID)
+	if err != nil {
+		c.Logger().Error(fmt.Errorf("failed to regenerate recovery codes of user[%d]: %w", userID, err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, model.RecoveryCodes{Codes: codes})
+}
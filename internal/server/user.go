@@ -1,14 +1,14 @@
 package server
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
-	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
 
 	"notes/internal/model"
+	"notes/internal/repository"
 	"notes/internal/validator"
 )
 
@@ -18,6 +18,10 @@ func (s *Server) CreateUser(c echo.Context) error {
 		return c.String(http.StatusBadRequest, err.Error())
 	}
 
+	if err := validator.Username(userDTO.Username); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
 	if err := validator.Email(userDTO.Email); err != nil {
 		return c.String(http.StatusBadRequest, err.Error())
 	}
@@ -31,6 +35,12 @@ func (s *Server) CreateUser(c echo.Context) error {
 		return c.String(http.StatusInternalServerError, err.Error())
 	}
 
+	// the account is created even if the email can't be sent, the user can
+	// ask for another one
+	if err := s.sendVerificationEmail(c, user.Email); err != nil {
+		c.Logger().Error(fmt.Errorf("failed to send verification email to user[%d]: %w", user.ID, err))
+	}
+
 	return c.JSON(http.StatusCreated, user)
 }
 
@@ -44,27 +54,18 @@ func (s *Server) LogIn(c echo.Context) error {
 		return c.String(http.StatusBadRequest, err.Error())
 	}
 
-	user, err := s.Repository.GetUserByEmailAndPassword(c.Request().Context(), login)
-	if err != nil {
-		c.Logger().Error(fmt.Errorf("failed to get user[%s]: %w", login.Email, err))
-		return echo.ErrUnauthorized
-	}
-
-	claims := &jwtClaim{
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-		},
-		ID: user.ID,
+	if locked, err := s.checkLoginLockout(c, login.Email); locked {
+		return err
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	tokenString, err := token.SignedString([]byte(s.Config.SignInKey))
+	user, err := s.Repository.GetUserByEmailAndPassword(c.Request().Context(), login)
 	if err != nil {
-		return c.String(http.StatusBadRequest, err.Error())
+		if errors.Is(err, repository.ErrInvalidPassword) {
+			return s.loginFailed(c, login.Email)
+		}
+		c.Logger().Error(fmt.Errorf("failed to get user[%s]: %w", login.Email, err))
+		return echo.ErrInternalServerError
 	}
 
-	return c.JSON(http.StatusOK, map[string]string{
-		"token": tokenString,
-	})
+	return s.startSession(c, user)
 }
@@ -0,0 +1,124 @@
+// Package totp implements time-based one-time passwords (RFC 6238) as used
+// by authenticator apps: HMAC-SHA1, 6 digits and a 30 second period.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// Digits is the length of the codes
+	Digits = 6
+	// Period is how long each code is valid for
+	Period = 30 * time.Second
+	// how many periods a code may be off, to allow for clock drift and
+	// for the time it takes to type the code
+	skew = 1
+	// 160 bits, the size of a SHA1 key, as recommended by RFC 4226
+	secretSize = 20
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// ErrInvalidCode is returned for codes that are not valid at the time
+var ErrInvalidCode = errors.New("invalid code")
+
+// NewSecret returns a random secret, base32 encoded like authenticator
+// apps expect it
+func NewSecret() (string, error) {
+	b := make([]byte, secretSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(b), nil
+}
+
+// URI returns the otpauth URI that authenticator apps scan as a QR code,
+// labeled with the issuer and the account of the user
+func URI(issuer, account, secret string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(Digits))
+	query.Set("period", fmt.Sprint(int(Period.Seconds())))
+
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// Counter returns the period the time falls in
+func Counter(t time.Time) int64 {
+	return t.Unix() / int64(Period.Seconds())
+}
+
+// Code returns the code of the secret for the period
+func Code(secret string, counter int64) (string, error) {
+	key, err := decode(secret)
+	if err != nil {
+		return "", err
+	}
+	return hotp(key, uint64(counter), Digits), nil
+}
+
+// Validate checks the code against the periods around t and returns the
+// period it belongs to. Callers should reject codes whose period is not
+// after the last one accepted, so a code can't be used twice.
+func Validate(secret, code string, t time.Time) (int64, error) {
+	key, err := decode(secret)
+	if err != nil {
+		return 0, err
+	}
+
+	code = strings.ReplaceAll(code, " ", "")
+	if len(code) != Digits {
+		return 0, ErrInvalidCode
+	}
+
+	counter := Counter(t)
+	for i := int64(-skew); i <= skew; i++ {
+		expected := hotp(key, uint64(counter+i), Digits)
+		if subtle.ConstantTimeCompare([]byte(code), []byte(expected)) == 1 {
+			return counter + i, nil
+		}
+	}
+	return 0, ErrInvalidCode
+}
+
+func decode(secret string) ([]byte, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return nil, fmt.Errorf("invalid secret: %w", err)
+	}
+	return key, nil
+}
+
+// hotp computes the HMAC-based one-time password of RFC 4226
+func hotp(key []byte, counter uint64, digits int) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// dynamic truncation
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, value%mod)
+}
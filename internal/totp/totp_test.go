@@ -0,0 +1,66 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// the SHA1 test vectors of RFC 6238
+func TestHOTP(t *testing.T) {
+	key := []byte("12345678901234567890")
+
+	tests := []struct {
+		time int64
+		code string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+	for _, tt := range tests {
+		counter := Counter(time.Unix(tt.time, 0))
+		assert.Equal(t, tt.code, hotp(key, uint64(counter), 8), "time %d", tt.time)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := NewSecret()
+	require.NoError(t, err)
+
+	now := time.Now()
+	code, err := Code(secret, Counter(now))
+	require.NoError(t, err)
+
+	counter, err := Validate(secret, code, now)
+	require.NoError(t, err)
+	assert.Equal(t, Counter(now), counter)
+
+	// codes of the previous period are still accepted
+	counter, err = Validate(secret, code, now.Add(Period))
+	require.NoError(t, err)
+	assert.Equal(t, Counter(now), counter)
+
+	_, err = Validate(secret, code, now.Add(3*Period))
+	assert.ErrorIs(t, err, ErrInvalidCode)
+
+	_, err = Validate(secret, "12345", now)
+	assert.ErrorIs(t, err, ErrInvalidCode)
+
+	// secrets typed by hand may be lowercase or padded
+	lower := strings.ToLower(base32.StdEncoding.EncodeToString([]byte("12345678901234567890")))
+	_, err = Code(lower, 1)
+	assert.NoError(t, err)
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("Notes", "test@example.com", "JBSWY3DPEHPK3PXP")
+	assert.Equal(t, "otpauth://totp/Notes:test@example.com?algorithm=SHA1&digits=6&issuer=Notes&period=30&secret=JBSWY3DPEHPK3PXP", uri)
+}
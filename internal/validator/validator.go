@@ -2,8 +2,14 @@ package validator
 
 import (
 	"errors"
+	"fmt"
 	"regexp"
+	"slices"
+	"strings"
 	"unicode"
+	"unicode/utf8"
+
+	"notes/internal/model"
 )
 
 // checks if the email is in a valid format
@@ -60,3 +66,122 @@ func Password(password string) error {
 
 	return nil
 }
+
+// checks that the username is non-empty, at most 50 characters long and
+// has no leading or trailing whitespace
+func Username(username string) error {
+	if username == "" {
+		return errors.New("username must not be empty")
+	}
+	if utf8.RuneCountInString(username) > 50 {
+		return errors.New("username must be at most 50 characters long")
+	}
+	if strings.TrimSpace(username) != username {
+		return errors.New("username must not start or end with whitespace")
+	}
+	return nil
+}
+
+// checks that the tag is non-empty, at most 50 characters long and
+// does not contain whitespace
+func Tag(tag string) error {
+	if tag == "" {
+		return errors.New("tag must not be empty")
+	}
+	if utf8.RuneCountInString(tag) > 50 {
+		return errors.New("tag must be at most 50 characters long")
+	}
+	if strings.IndexFunc(tag, unicode.IsSpace) >= 0 {
+		return errors.New("tag must not contain whitespace")
+	}
+	return nil
+}
+
+// checks that the notebook name is non-empty and at most 100 characters long
+func NotebookName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return errors.New("notebook name must not be empty")
+	}
+	if utf8.RuneCountInString(name) > 100 {
+		return errors.New("notebook name must be at most 100 characters long")
+	}
+	return nil
+}
+
+// checks that the saved search name is non-empty and at most 100 characters
+// long
+func SavedSearchName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return errors.New("saved search name must not be empty")
+	}
+	if utf8.RuneCountInString(name) > 100 {
+		return errors.New("saved search name must be at most 100 characters long")
+	}
+	return nil
+}
+
+// checks that the personal access token name is non-empty and at most 100
+// characters long
+func AccessTokenName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return errors.New("token name must not be empty")
+	}
+	if utf8.RuneCountInString(name) > 100 {
+		return errors.New("token name must be at most 100 characters long")
+	}
+	return nil
+}
+
+// checks that there is at least one scope and all are known
+func AccessTokenScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return errors.New("token must have at least one scope")
+	}
+	for _, scope := range scopes {
+		switch scope {
+		case model.ScopeNotesRead, model.ScopeNotesWrite, model.ScopeNotesShare:
+		default:
+			return fmt.Errorf("unknown scope %q, must be one of notes:read, notes:write or notes:share", scope)
+		}
+	}
+	return nil
+}
+
+// checks that the role is one of the note share roles
+func ShareRole(role string) error {
+	switch role {
+	case model.RoleViewer, model.RoleCommenter, model.RoleEditor:
+		return nil
+	}
+	return errors.New("role must be one of viewer, commenter or editor")
+}
+
+// checks that the search highlight marker is at most 32 characters long and
+// has no quotes or backslashes
+func HighlightMarker(marker string) error {
+	if utf8.RuneCountInString(marker) > 32 {
+		return errors.New("highlight marker must be at most 32 characters long")
+	}
+	if strings.ContainsAny(marker, `"\`) {
+		return errors.New("highlight marker must not contain quotes or backslashes")
+	}
+	return nil
+}
+
+// searchLanguages are the text search configs PostgreSQL ships with
+var searchLanguages = []string{
+	"arabic", "armenian", "basque", "catalan", "danish", "dutch", "english",
+	"finnish", "french", "german", "greek", "hindi", "hungarian", "indonesian",
+	"irish", "italian", "lithuanian", "nepali", "norwegian", "portuguese",
+	"romanian", "russian", "serbian", "simple", "spanish", "swedish", "tamil",
+	"turkish", "yiddish",
+}
+
+// checks that the search language is one of the text search configs of
+// PostgreSQL
+func SearchLanguage(language string) error {
+	if !slices.Contains(searchLanguages, language) {
+		return fmt.Errorf("unknown search language %q, must be one of %s", language, strings.Join(searchLanguages, ", "))
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package validator
 
 import (
 	"errors"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -56,3 +57,122 @@ func TestValidatePassword(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateUsername(t *testing.T) {
+	assertions := assert.New(t)
+
+	// Test cases
+	testCases := []struct {
+		username string
+		expect   error
+	}{
+		{"testuser", nil},
+		{"Jane Doe", nil},
+		{"", errors.New("username must not be empty")},
+		{" testuser", errors.New("username must not start or end with whitespace")},
+		{strings.Repeat("a", 51), errors.New("username must be at most 50 characters long")},
+	}
+
+	for _, testCase := range testCases {
+		err := Username(testCase.username)
+		if testCase.expect == nil {
+			assertions.NoError(err, "Expected no error for username: %s", testCase.username)
+		} else {
+			assertions.EqualError(err, testCase.expect.Error(), "Expected error for username: %s", testCase.username)
+		}
+	}
+}
+
+func TestValidateTag(t *testing.T) {
+	assertions := assert.New(t)
+
+	// Test cases
+	testCases := []struct {
+		tag    string
+		expect error
+	}{
+		{"work", nil},
+		{"project/notes-2024", nil},
+		{"", errors.New("tag must not be empty")},
+		{"two words", errors.New("tag must not contain whitespace")},
+		{strings.Repeat("a", 51), errors.New("tag must be at most 50 characters long")},
+	}
+
+	for _, testCase := range testCases {
+		err := Tag(testCase.tag)
+		if testCase.expect == nil {
+			assertions.NoError(err, "Expected no error for tag: %s", testCase.tag)
+		} else {
+			assertions.EqualError(err, testCase.expect.Error(), "Expected error for tag: %s", testCase.tag)
+		}
+	}
+}
+
+func TestValidateAccessTokenScopes(t *testing.T) {
+	assertions := assert.New(t)
+
+	// Test cases
+	testCases := []struct {
+		scopes []string
+		expect error
+	}{
+		{[]string{"notes:read"}, nil},
+		{[]string{"notes:read", "notes:write", "notes:share"}, nil},
+		{nil, errors.New("token must have at least one scope")},
+		{[]string{"notes:read", "admin"}, errors.New(`unknown scope "admin", must be one of notes:read, notes:write or notes:share`)},
+	}
+
+	for _, testCase := range testCases {
+		err := AccessTokenScopes(testCase.scopes)
+		if testCase.expect == nil {
+			assertions.NoError(err, "Expected no error for scopes: %v", testCase.scopes)
+		} else {
+			assertions.EqualError(err, testCase.expect.Error(), "Expected error for scopes: %v", testCase.scopes)
+		}
+	}
+}
+
+func TestValidateShareRole(t *testing.T) {
+	assertions := assert.New(t)
+
+	for _, role := range []string{"viewer", "commenter", "editor"} {
+		assertions.NoError(ShareRole(role), "Expected no error for role: %s", role)
+	}
+
+	for _, role := range []string{"", "owner", "Editor"} {
+		assertions.EqualError(ShareRole(role), "role must be one of viewer, commenter or editor", "Expected error for role: %s", role)
+	}
+}
+
+func TestValidateHighlightMarker(t *testing.T) {
+	assertions := assert.New(t)
+
+	for _, marker := range []string{"", "<b>", "<mark class='hit'>", "**"} {
+		assertions.NoError(HighlightMarker(marker), "Expected no error for marker: %s", marker)
+	}
+
+	testCases := []struct {
+		marker string
+		expect error
+	}{
+		{`<mark class="hit">`, errors.New("highlight marker must not contain quotes or backslashes")},
+		{`\`, errors.New("highlight marker must not contain quotes or backslashes")},
+		{strings.Repeat("*", 33), errors.New("highlight marker must be at most 32 characters long")},
+	}
+
+	for _, testCase := range testCases {
+		assertions.EqualError(HighlightMarker(testCase.marker), testCase.expect.Error(), "Expected error for marker: %s", testCase.marker)
+	}
+}
+
+func TestValidateSearchLanguage(t *testing.T) {
+	assertions := assert.New(t)
+
+	for _, language := range []string{"english", "german", "french", "simple"} {
+		assertions.NoError(SearchLanguage(language), "Expected no error for language: %s", language)
+	}
+
+	for _, language := range []string{"", "English", "klingon", "pg_catalog.english"} {
+		assertions.ErrorContains(SearchLanguage(language), "unknown search language", "Expected error for language: %s", language)
+	}
+}
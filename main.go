@@ -12,7 +12,10 @@ func main() {
 		panic(fmt.Sprintf("cannot start server: %s", err))
 	}
 
-	server := server.NewServer(config)
+	server, err := server.NewServer(config)
+	if err != nil {
+		panic(fmt.Sprintf("cannot start server: %s", err))
+	}
 
 	err = server.ListenAndServe()
 	if err != nil {
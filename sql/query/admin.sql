@@ -0,0 +1,44 @@
+-- name: ListUsersWithUsage :many
+SELECT u.*,
+    (SELECT COUNT(*) FROM notes n WHERE n.user_id = u.id AND n.deleted_at IS NULL) AS note_count,
+    ((SELECT COALESCE(SUM(octet_length(n.title) + octet_length(n.content)), 0) FROM notes n WHERE n.user_id = u.id)
+        + (SELECT COALESCE(SUM(octet_length(r.title) + octet_length(r.content)), 0)
+            FROM note_revisions r JOIN notes n ON n.id = r.note_id WHERE n.user_id = u.id))::bigint AS storage_bytes
+FROM users u
+WHERE (@search::text = '' OR u.username ILIKE '%' || @search || '%' OR u.email ILIKE '%' || @search || '%')
+    AND u.id > @after_id
+ORDER BY u.id
+LIMIT @row_limit;
+
+-- name: GetUserWithUsage :one
+SELECT u.*,
+    (SELECT COUNT(*) FROM notes n WHERE n.user_id = u.id AND n.deleted_at IS NULL) AS note_count,
+    ((SELECT COALESCE(SUM(octet_length(n.title) + octet_length(n.content)), 0) FROM notes n WHERE n.user_id = u.id)
+        + (SELECT COALESCE(SUM(octet_length(r.title) + octet_length(r.content)), 0)
+            FROM note_revisions r JOIN notes n ON n.id = r.note_id WHERE n.user_id = u.id))::bigint AS storage_bytes
+FROM users u
+WHERE u.id = $1;
+
+-- name: DisableUser :one
+UPDATE users
+SET disabled_at = COALESCE(disabled_at, now())
+WHERE id = $1
+RETURNING *;
+
+-- name: EnableUser :one
+UPDATE users
+SET disabled_at = NULL
+WHERE id = $1
+RETURNING *;
+
+-- name: CreateAuditEntry :exec
+INSERT INTO audit_log (actor_id, action, target_user_id, details, ip, status)
+VALUES (@actor_id, @action, sqlc.narg(target_user_id), @details, @ip, @status);
+
+-- name: ListAuditEntries :many
+SELECT * FROM audit_log
+WHERE (sqlc.narg(actor_id)::int IS NULL OR actor_id = sqlc.narg(actor_id))
+    AND (sqlc.narg(target_user_id)::int IS NULL OR target_user_id = sqlc.narg(target_user_id))
+    AND id < @before_id
+ORDER BY id DESC
+LIMIT @row_limit;
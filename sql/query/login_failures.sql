@@ -0,0 +1,33 @@
+-- name: GetLoginLockedUntil :one
+SELECT locked_until
+FROM login_failures
+WHERE ((scope = 'account' AND key = @email) OR (scope = 'ip' AND key = @ip))
+    AND locked_until > now()
+ORDER BY locked_until DESC
+LIMIT 1;
+
+-- name: RecordLoginFailure :one
+INSERT INTO login_failures (scope, key, failures, last_failure_at)
+VALUES (@scope, @key, 1, now())
+ON CONFLICT (scope, key) DO UPDATE
+SET failures = CASE
+        WHEN login_failures.last_failure_at < now() - make_interval(secs => @window_seconds::float8) THEN 1
+        ELSE login_failures.failures + 1
+    END,
+    last_failure_at = now()
+RETURNING failures;
+
+-- name: LockLogin :one
+UPDATE login_failures
+SET locked_until = now() + make_interval(secs => @lock_seconds::float8)
+WHERE scope = @scope AND key = @key
+RETURNING locked_until;
+
+-- name: ClearLoginFailures :exec
+DELETE FROM login_failures
+WHERE (scope = 'account' AND key = @email) OR (scope = 'ip' AND key = @ip);
+
+-- name: PurgeLoginFailures :execrows
+DELETE FROM login_failures
+WHERE last_failure_at < now() - make_interval(secs => @window_seconds::float8)
+    AND (locked_until IS NULL OR locked_until < now());
@@ -0,0 +1,60 @@
+-- name: CreateNotebook :one
+INSERT INTO notebooks (user_id, parent_id, name)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: ListNotebooksByUserID :many
+SELECT nb.*
+FROM notebooks nb
+WHERE nb.user_id = $1 OR nb.id IN (SELECT na.notebook_id FROM notebook_access na WHERE na.user_id = $1)
+ORDER BY nb.name;
+
+-- name: GetNotebookByUserID :one
+SELECT nb.*
+FROM notebooks nb
+WHERE nb.id = $1 AND (nb.user_id = $2 OR nb.id IN (SELECT na.notebook_id FROM notebook_access na WHERE na.user_id = $2));
+
+-- name: GetOwnedNotebook :one
+SELECT * FROM notebooks
+WHERE id = $1 AND user_id = $2;
+
+-- name: IsNotebookInSubtree :one
+WITH RECURSIVE subtree AS (
+    SELECT nb.id FROM notebooks nb WHERE nb.id = @root_id
+    UNION
+    SELECT nb.id FROM notebooks nb JOIN subtree st ON nb.parent_id = st.id
+)
+SELECT COUNT(*) > 0 AS in_subtree FROM subtree WHERE subtree.id = @notebook_id::int;
+
+-- name: UpdateNotebook :one
+UPDATE notebooks
+SET name = $3, parent_id = $4, updated_at = now()
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeleteNotebook :execrows
+DELETE FROM notebooks
+WHERE id = $1 AND user_id = $2;
+
+-- name: ShareNotebook :one
+INSERT INTO shared_notebooks (notebook_id, shared_with_user_id)
+SELECT @notebook_id, users.id
+FROM users
+WHERE email = @shared_with_email AND EXISTS (SELECT 1 FROM notebooks WHERE id = @notebook_id AND user_id = @user_id)
+RETURNING *;
+
+-- name: ListNotebookShares :many
+SELECT u.id, u.username, u.email
+FROM shared_notebooks sn
+JOIN users u ON u.id = sn.shared_with_user_id
+WHERE sn.notebook_id = $1
+ORDER BY u.username;
+
+-- name: RevokeNotebookShare :execrows
+DELETE FROM shared_notebooks sn
+WHERE sn.notebook_id = @notebook_id AND sn.shared_with_user_id = @shared_with_user_id
+    AND EXISTS (SELECT 1 FROM notebooks nb WHERE nb.id = sn.notebook_id AND nb.user_id = @user_id);
+
+-- name: LeaveNotebook :execrows
+DELETE FROM shared_notebooks
+WHERE notebook_id = $1 AND shared_with_user_id = $2;
@@ -1,41 +1,90 @@
 -- name: CreatNote :one
-INSERT INTO notes (user_id, title, content)
-VALUES ( $1, $2, $3 )
+INSERT INTO notes (user_id, title, content, notebook_id, language)
+VALUES ( @user_id, @title, @content, @notebook_id, COALESCE(sqlc.narg(language)::regconfig, (SELECT u.search_language FROM users u WHERE u.id = @user_id)) )
 RETURNING *;
 
--- name: ListNotesByUserID :many
-SELECT n.*
-FROM notes n
-LEFT JOIN shared_notes sn ON n.id = sn.note_id
-WHERE n.user_id = $1 OR sn.shared_with_user_id = $1
-ORDER BY n.created_at DESC;
-
 -- name: GetNoteByUserID :one
 SELECT n.*
 FROM notes n
 LEFT JOIN shared_notes sn ON n.id = sn.note_id
-WHERE (n.id = $1) AND (n.user_id = $2 OR sn.shared_with_user_id = $2);
+WHERE (n.id = $1) AND (n.user_id = $2 OR sn.shared_with_user_id = $2 OR n.notebook_id IN (SELECT na.notebook_id FROM notebook_access na WHERE na.user_id = $2)) AND n.deleted_at IS NULL;
 
 -- name: UpdateNote :one
 UPDATE notes
-SET title = $2, content = $3, updated_at = now()
-WHERE id = $1 AND user_id = $4
+SET title = @title, content = @content, language = COALESCE(sqlc.narg(language)::regconfig, language), updated_at = now()
+WHERE id = @id AND deleted_at IS NULL
+    AND (user_id = @user_id OR EXISTS (SELECT 1 FROM shared_notes sn WHERE sn.note_id = notes.id AND sn.shared_with_user_id = @user_id AND sn.role = 'editor'))
 RETURNING *;
 
 -- name: DeleteNote :one
+UPDATE notes
+SET deleted_at = now()
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+RETURNING *;
+
+-- name: ListDeletedNotesByUserID :many
+SELECT * FROM notes
+WHERE user_id = $1 AND deleted_at IS NOT NULL
+ORDER BY deleted_at DESC;
+
+-- name: RestoreNote :one
+UPDATE notes
+SET deleted_at = NULL
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL
+RETURNING *;
+
+-- name: PurgeDeletedNotes :execrows
 DELETE FROM notes
-WHERE id = $1 AND user_id = $2
+WHERE deleted_at < now() - make_interval(secs => @retention_seconds::float8);
+
+-- name: MoveNote :one
+UPDATE notes
+SET notebook_id = sqlc.narg(notebook_id)
+WHERE id = @id AND user_id = @user_id AND deleted_at IS NULL
 RETURNING *;
 
 -- name: ShareNote :one
-INSERT INTO shared_notes (note_id, shared_with_user_id)
-SELECT @noteID, users.id
+INSERT INTO shared_notes (note_id, shared_with_user_id, role)
+SELECT @noteID, users.id, @role
 FROM users
-WHERE email = @sharedWithEmail AND EXISTS (SELECT 1 FROM notes WHERE id = @noteID AND user_id = @userID)
+WHERE email = @sharedWithEmail AND EXISTS (SELECT 1 FROM notes WHERE id = @noteID AND user_id = @userID AND deleted_at IS NULL)
+ON CONFLICT (note_id, shared_with_user_id) DO UPDATE SET role = EXCLUDED.role
 RETURNING *;
 
--- name: SearchNotes :many
-SELECT n.*
+-- name: ListNoteShares :many
+SELECT u.id, u.username, u.email, sn.role
+FROM shared_notes sn
+JOIN users u ON u.id = sn.shared_with_user_id
+WHERE sn.note_id = $1
+ORDER BY u.username;
+
+-- name: RevokeNoteShare :execrows
+DELETE FROM shared_notes sn
+WHERE sn.note_id = @note_id AND sn.shared_with_user_id = @shared_with_user_id
+    AND EXISTS (SELECT 1 FROM notes n WHERE n.id = sn.note_id AND n.user_id = @user_id);
+
+-- name: LeaveNote :execrows
+DELETE FROM shared_notes
+WHERE note_id = $1 AND shared_with_user_id = $2;
+
+-- name: LockNote :exec
+SELECT id FROM notes
+WHERE id = $1
+FOR UPDATE;
+
+-- name: CreateNoteRevision :one
+INSERT INTO note_revisions (note_id, revision, title, content, created_at)
+SELECT n.id, COALESCE((SELECT MAX(r.revision) FROM note_revisions r WHERE r.note_id = n.id), 0) + 1, n.title, n.content, n.updated_at
 FROM notes n
-LEFT JOIN shared_notes sn ON n.id = sn.note_id
-WHERE (n.user_id = $1 OR sn.shared_with_user_id = $1) AND to_tsvector('english', n.content) @@ plainto_tsquery('english', $2);
+WHERE n.id = @note_id AND n.deleted_at IS NULL
+    AND (n.user_id = @user_id OR EXISTS (SELECT 1 FROM shared_notes sn WHERE sn.note_id = n.id AND sn.shared_with_user_id = @user_id AND sn.role = 'editor'))
+RETURNING *;
+
+-- name: ListNoteRevisions :many
+SELECT * FROM note_revisions
+WHERE note_id = $1
+ORDER BY revision DESC;
+
+-- name: GetNoteRevision :one
+SELECT * FROM note_revisions
+WHERE note_id = $1 AND revision = $2;
@@ -0,0 +1,23 @@
+-- name: CreatePersonalAccessToken :one
+INSERT INTO personal_access_tokens (user_id, name, token_hash, scopes, expires_at)
+VALUES (@user_id, @name, @token_hash, @scopes, sqlc.narg(expires_at)::timestamp)
+RETURNING *;
+
+-- name: ListPersonalAccessTokens :many
+SELECT * FROM personal_access_tokens
+WHERE user_id = $1
+ORDER BY created_at DESC;
+
+-- name: RevokePersonalAccessToken :execrows
+DELETE FROM personal_access_tokens
+WHERE id = @id AND user_id = @user_id;
+
+-- name: UsePersonalAccessToken :one
+UPDATE personal_access_tokens
+SET last_used_at = now()
+WHERE token_hash = $1 AND (expires_at IS NULL OR expires_at > now())
+    AND NOT EXISTS (
+        SELECT 1 FROM users u
+        WHERE u.id = personal_access_tokens.user_id AND u.disabled_at IS NOT NULL
+    )
+RETURNING *;
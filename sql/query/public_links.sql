@@ -0,0 +1,28 @@
+-- name: CreatePublicLink :one
+INSERT INTO public_links (note_id, token, password_hash, expires_at)
+SELECT n.id, @token, sqlc.narg(password_hash), sqlc.narg(expires_at)::timestamp
+FROM notes n
+WHERE n.id = @note_id AND n.user_id = @user_id AND n.deleted_at IS NULL
+RETURNING *;
+
+-- name: ListPublicLinks :many
+SELECT pl.*
+FROM public_links pl
+JOIN notes n ON n.id = pl.note_id
+WHERE pl.note_id = $1 AND n.user_id = $2
+ORDER BY pl.created_at DESC;
+
+-- name: RevokePublicLink :execrows
+DELETE FROM public_links pl
+WHERE pl.id = @id AND pl.note_id = @note_id
+    AND EXISTS (SELECT 1 FROM notes n WHERE n.id = pl.note_id AND n.user_id = @user_id);
+
+-- name: GetPublicLinkByToken :one
+SELECT * FROM public_links
+WHERE token = $1 AND (expires_at IS NULL OR expires_at > now());
+
+-- name: GetPublicNote :one
+SELECT n.*
+FROM notes n
+JOIN public_links pl ON pl.note_id = n.id
+WHERE pl.id = $1 AND n.deleted_at IS NULL;
@@ -0,0 +1,46 @@
+-- name: CreateRefreshToken :one
+INSERT INTO refresh_tokens (user_id, family_id, token_hash, access_token_id, expires_at)
+VALUES (@user_id, @family_id, @token_hash, @access_token_id, now() + make_interval(secs => @ttl_seconds::float8))
+RETURNING *;
+
+-- name: GetRefreshTokenByHash :one
+SELECT * FROM refresh_tokens
+WHERE token_hash = $1 AND expires_at > now();
+
+-- name: UseRefreshToken :execrows
+UPDATE refresh_tokens
+SET used_at = now()
+WHERE id = $1 AND used_at IS NULL AND revoked_at IS NULL;
+
+-- name: RevokeRefreshTokenFamily :exec
+UPDATE refresh_tokens
+SET revoked_at = now()
+WHERE family_id = $1 AND revoked_at IS NULL;
+
+-- name: RevokeSessionByAccessTokenID :exec
+UPDATE refresh_tokens
+SET revoked_at = now()
+WHERE refresh_tokens.user_id = @user_id AND refresh_tokens.revoked_at IS NULL
+    AND refresh_tokens.family_id = (SELECT rt.family_id FROM refresh_tokens rt WHERE rt.access_token_id = @access_token_id);
+
+-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = now()
+WHERE user_id = $1 AND revoked_at IS NULL;
+
+-- name: RevokeOtherUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = now()
+WHERE refresh_tokens.user_id = @user_id AND refresh_tokens.revoked_at IS NULL
+    AND refresh_tokens.family_id IS DISTINCT FROM (SELECT rt.family_id FROM refresh_tokens rt WHERE rt.access_token_id = @access_token_id);
+
+-- name: IsAccessTokenActive :one
+SELECT EXISTS (
+    SELECT 1 FROM refresh_tokens rt
+    JOIN users u ON u.id = rt.user_id
+    WHERE rt.access_token_id = $1 AND rt.revoked_at IS NULL AND u.disabled_at IS NULL
+);
+
+-- name: PurgeExpiredRefreshTokens :execrows
+DELETE FROM refresh_tokens
+WHERE expires_at < now();
@@ -0,0 +1,28 @@
+-- name: CreateSavedSearch :one
+INSERT INTO saved_searches (user_id, name, query, mode, threshold, language)
+VALUES (@user_id, @name, @query, @mode, @threshold, sqlc.narg(language)::regconfig)
+RETURNING *;
+
+-- name: ListSavedSearches :many
+SELECT * FROM saved_searches
+WHERE user_id = $1
+ORDER BY name;
+
+-- name: GetSavedSearch :one
+SELECT * FROM saved_searches
+WHERE id = $1 AND user_id = $2;
+
+-- name: UpdateSavedSearch :one
+UPDATE saved_searches
+SET name = @name, query = @query, mode = @mode, threshold = @threshold, language = sqlc.narg(language)::regconfig
+WHERE id = @id AND user_id = @user_id
+RETURNING *;
+
+-- name: DeleteSavedSearch :execrows
+DELETE FROM saved_searches
+WHERE id = $1 AND user_id = $2;
+
+-- name: MarkSavedSearchViewed :execrows
+UPDATE saved_searches
+SET last_viewed_at = @viewed_at
+WHERE id = @id AND user_id = @user_id;
@@ -0,0 +1,30 @@
+-- name: CreateShareInvitation :one
+INSERT INTO share_invitations (note_id, email, role)
+SELECT n.id, @email, @role
+FROM notes n
+WHERE n.id = @note_id AND n.user_id = @user_id AND n.deleted_at IS NULL
+ON CONFLICT (note_id, email) DO UPDATE SET role = EXCLUDED.role
+RETURNING *;
+
+-- name: ListShareInvitations :many
+SELECT si.*
+FROM share_invitations si
+JOIN notes n ON n.id = si.note_id
+WHERE si.note_id = $1 AND n.user_id = $2
+ORDER BY si.created_at;
+
+-- name: CancelShareInvitation :execrows
+DELETE FROM share_invitations si
+WHERE si.id = @id AND si.note_id = @note_id
+    AND EXISTS (SELECT 1 FROM notes n WHERE n.id = si.note_id AND n.user_id = @user_id);
+
+-- name: AcceptShareInvitations :exec
+WITH accepted AS (
+    DELETE FROM share_invitations
+    WHERE email = @email
+    RETURNING note_id, role
+)
+INSERT INTO shared_notes (note_id, shared_with_user_id, role)
+SELECT accepted.note_id, @user_id::int, accepted.role
+FROM accepted
+ON CONFLICT (note_id, shared_with_user_id) DO NOTHING;
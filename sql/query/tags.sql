@@ -0,0 +1,54 @@
+-- name: UpsertTag :one
+INSERT INTO tags (user_id, name)
+VALUES ($1, $2)
+ON CONFLICT (user_id, name) DO UPDATE SET name = EXCLUDED.name
+RETURNING *;
+
+-- name: GetTagByName :one
+SELECT * FROM tags
+WHERE user_id = $1 AND name = $2;
+
+-- name: RenameTag :exec
+UPDATE tags
+SET name = $2
+WHERE id = $1;
+
+-- name: DeleteTag :exec
+DELETE FROM tags
+WHERE id = $1;
+
+-- name: DeleteUnusedTags :exec
+DELETE FROM tags t
+WHERE t.user_id = $1 AND NOT EXISTS (SELECT 1 FROM note_tags nt WHERE nt.tag_id = t.id);
+
+-- name: ListTagsByUserID :many
+SELECT t.name, COUNT(n.id) AS note_count
+FROM tags t
+LEFT JOIN note_tags nt ON nt.tag_id = t.id
+LEFT JOIN notes n ON n.id = nt.note_id AND n.deleted_at IS NULL
+WHERE t.user_id = $1
+GROUP BY t.id, t.name
+ORDER BY t.name;
+
+-- name: AddNoteTag :exec
+INSERT INTO note_tags (note_id, tag_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteNoteTags :exec
+DELETE FROM note_tags
+WHERE note_id = $1;
+
+-- name: MergeNoteTags :exec
+INSERT INTO note_tags (note_id, tag_id)
+SELECT nt.note_id, @to_tag_id
+FROM note_tags nt
+WHERE nt.tag_id = @from_tag_id
+ON CONFLICT DO NOTHING;
+
+-- name: ListNoteTagNames :many
+SELECT t.name
+FROM note_tags nt
+JOIN tags t ON t.id = nt.tag_id
+WHERE nt.note_id = $1
+ORDER BY t.name;
@@ -0,0 +1,38 @@
+-- name: CreatePendingTOTP :execrows
+INSERT INTO user_totp (user_id, secret)
+VALUES (@user_id, @secret)
+ON CONFLICT (user_id) DO UPDATE
+SET secret = EXCLUDED.secret, last_counter = NULL, created_at = now()
+WHERE user_totp.enabled_at IS NULL;
+
+-- name: GetUserTOTP :one
+SELECT * FROM user_totp
+WHERE user_id = $1;
+
+-- name: EnableUserTOTP :execrows
+UPDATE user_totp
+SET enabled_at = now(), last_counter = @counter
+WHERE user_id = @user_id AND enabled_at IS NULL;
+
+-- name: UseTOTPCounter :execrows
+UPDATE user_totp
+SET last_counter = @counter
+WHERE user_id = @user_id AND enabled_at IS NOT NULL
+    AND (last_counter IS NULL OR last_counter < @counter);
+
+-- name: DeleteUserTOTP :execrows
+DELETE FROM user_totp
+WHERE user_id = $1;
+
+-- name: CreateRecoveryCode :exec
+INSERT INTO recovery_codes (user_id, code_hash)
+VALUES (@user_id, @code_hash);
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes
+WHERE user_id = $1;
+
+-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes
+SET used_at = now()
+WHERE user_id = @user_id AND code_hash = @code_hash AND used_at IS NULL;
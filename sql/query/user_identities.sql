@@ -0,0 +1,9 @@
+-- name: GetUserByIdentity :one
+SELECT u.*
+FROM users u
+JOIN user_identities ui ON ui.user_id = u.id
+WHERE ui.issuer = @issuer AND ui.subject = @subject;
+
+-- name: CreateUserIdentity :exec
+INSERT INTO user_identities (user_id, issuer, subject, email)
+VALUES (@user_id, @issuer, @subject, @email);
@@ -0,0 +1,15 @@
+-- name: CreateUserToken :one
+INSERT INTO user_tokens (user_id, purpose, token_hash, email, expires_at)
+VALUES (@user_id, @purpose, @token_hash, sqlc.narg(email), now() + make_interval(secs => @ttl_seconds::float8))
+RETURNING *;
+
+-- name: GetUserTokenByHash :one
+SELECT * FROM user_tokens
+WHERE token_hash = @token_hash AND purpose = @purpose
+    AND used_at IS NULL AND expires_at > now();
+
+-- name: UseUserTokens :execrows
+UPDATE user_tokens
+SET used_at = now()
+WHERE user_id = @user_id AND purpose = @purpose AND used_at IS NULL;
+
@@ -4,10 +4,57 @@ VALUES ($1, $2, $3)
 RETURNING *;
 
 -- name: GetUserByID :one
-SELECT  FROM users
+SELECT * FROM users
 WHERE id = $1;
 
 -- name: GetUserByEmail :one
 SELECT * FROM users
 WHERE email = $1;
 
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET password_hash = @password_hash
+WHERE id = @id;
+
+-- name: VerifyUserEmail :one
+UPDATE users
+SET email = @email, email_verified_at = now()
+WHERE id = @id
+RETURNING *;
+
+-- name: UpdateProfile :one
+UPDATE users
+SET username = @username, search_language = COALESCE(sqlc.narg(search_language)::regconfig, search_language)
+WHERE id = @id
+RETURNING *;
+
+-- name: DeleteUser :execrows
+DELETE FROM users
+WHERE id = $1;
+
+-- name: TransferSharedNotes :exec
+WITH heirs AS (
+    SELECT DISTINCT ON (sn.note_id) sn.note_id, sn.shared_with_user_id
+    FROM shared_notes sn
+    JOIN notes n ON n.id = sn.note_id
+    WHERE n.user_id = @user_id AND n.deleted_at IS NULL
+    ORDER BY sn.note_id,
+        CASE sn.role WHEN 'editor' THEN 0 WHEN 'commenter' THEN 1 ELSE 2 END,
+        sn.shared_with_user_id
+), transferred AS (
+    UPDATE notes n
+    SET user_id = heirs.shared_with_user_id, notebook_id = NULL
+    FROM heirs
+    WHERE n.id = heirs.note_id
+    RETURNING n.id, n.user_id
+)
+DELETE FROM shared_notes sn
+USING transferred
+WHERE sn.note_id = transferred.id AND sn.shared_with_user_id = transferred.user_id;
+
+-- name: UsernameExists :one
+SELECT EXISTS (
+    SELECT 1 FROM users
+    WHERE username = $1
+);
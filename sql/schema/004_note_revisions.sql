@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE note_revisions (
+    note_id INT NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+    revision INT NOT NULL,
+    title VARCHAR(100) NOT NULL,
+    content TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (note_id, revision)
+);
+
+-- +goose Down
+DROP TABLE note_revisions;
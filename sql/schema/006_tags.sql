@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE tags (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    UNIQUE (user_id, name)
+);
+
+CREATE TABLE note_tags (
+    note_id INT NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+    tag_id INT NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (note_id, tag_id)
+);
+CREATE INDEX idx_note_tags_tag_id ON note_tags (tag_id);
+
+-- +goose Down
+DROP TABLE note_tags;
+DROP TABLE tags;
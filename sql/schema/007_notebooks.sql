@@ -0,0 +1,38 @@
+-- +goose Up
+CREATE TABLE notebooks (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    parent_id INT REFERENCES notebooks(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_notebooks_parent_id ON notebooks (parent_id);
+
+ALTER TABLE notes ADD COLUMN notebook_id INT REFERENCES notebooks(id) ON DELETE SET NULL;
+CREATE INDEX idx_notes_notebook_id ON notes (notebook_id);
+
+CREATE TABLE shared_notebooks (
+    notebook_id INT REFERENCES notebooks(id) ON DELETE CASCADE,
+    shared_with_user_id INT REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (notebook_id, shared_with_user_id)
+);
+
+-- Sharing a notebook grants access to all notebooks nested inside it.
+CREATE VIEW notebook_access AS
+WITH RECURSIVE tree AS (
+    SELECT sn.notebook_id, sn.shared_with_user_id AS user_id
+    FROM shared_notebooks sn
+    UNION
+    SELECT nb.id, tree.user_id
+    FROM notebooks nb
+    JOIN tree ON nb.parent_id = tree.notebook_id
+)
+SELECT notebook_id, user_id FROM tree;
+
+-- +goose Down
+DROP VIEW notebook_access;
+DROP TABLE shared_notebooks;
+DROP INDEX IF EXISTS idx_notes_notebook_id;
+ALTER TABLE notes DROP COLUMN notebook_id;
+DROP TABLE notebooks;
@@ -0,0 +1,6 @@
+-- +goose Up
+ALTER TABLE shared_notes ADD COLUMN role VARCHAR(16) NOT NULL DEFAULT 'viewer'
+    CHECK (role IN ('viewer', 'commenter', 'editor'));
+
+-- +goose Down
+ALTER TABLE shared_notes DROP COLUMN role;
@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE public_links (
+    id SERIAL PRIMARY KEY,
+    note_id INT NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+    token VARCHAR(64) NOT NULL UNIQUE,
+    password_hash VARCHAR(255),
+    expires_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_public_links_note_id ON public_links (note_id);
+
+-- +goose Down
+DROP TABLE public_links;
@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE share_invitations (
+    id SERIAL PRIMARY KEY,
+    note_id INT NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+    email VARCHAR(100) NOT NULL,
+    role VARCHAR(16) NOT NULL DEFAULT 'viewer'
+        CHECK (role IN ('viewer', 'commenter', 'editor')),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (note_id, email)
+);
+CREATE INDEX idx_share_invitations_email ON share_invitations (email);
+
+-- +goose Down
+DROP TABLE share_invitations;
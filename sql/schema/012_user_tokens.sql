@@ -0,0 +1,16 @@
+-- +goose Up
+-- Single-use tokens mailed to users, such as password reset links. Only the
+-- hash of a token is stored.
+CREATE TABLE user_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    purpose VARCHAR(32) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_user_tokens_user_id ON user_tokens (user_id, purpose);
+
+-- +goose Down
+DROP TABLE user_tokens;
@@ -0,0 +1,10 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP;
+
+-- Tokens verifying an email address carry the address they were sent to,
+-- which is the one that gets verified.
+ALTER TABLE user_tokens ADD COLUMN email VARCHAR(100);
+
+-- +goose Down
+ALTER TABLE user_tokens DROP COLUMN email;
+ALTER TABLE users DROP COLUMN email_verified_at;
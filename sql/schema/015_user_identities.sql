@@ -0,0 +1,16 @@
+-- +goose Up
+-- Accounts of identity providers linked to users, who can log in with them
+-- instead of a password.
+CREATE TABLE user_identities (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    issuer VARCHAR(255) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(100),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (issuer, subject)
+);
+CREATE INDEX idx_user_identities_user_id ON user_identities (user_id);
+
+-- +goose Down
+DROP TABLE user_identities;
@@ -0,0 +1,14 @@
+-- +goose Up
+-- Failed logins per account (keyed by the email tried, whether or not a
+-- user has it) and per IP, locking further logins after too many.
+CREATE TABLE login_failures (
+    scope VARCHAR(16) NOT NULL,
+    key VARCHAR(255) NOT NULL,
+    failures INT NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    locked_until TIMESTAMP,
+    PRIMARY KEY (scope, key)
+);
+
+-- +goose Down
+DROP TABLE login_failures;
@@ -0,0 +1,24 @@
+-- +goose Up
+-- Admins manage other users through the admin API. Disabled users can't
+-- log in or use their tokens.
+ALTER TABLE users ADD COLUMN role VARCHAR(16) NOT NULL DEFAULT 'user';
+ALTER TABLE users ADD COLUMN disabled_at TIMESTAMP;
+
+-- Every request made to the admin API. Entries are kept when the admin or
+-- the user they acted on is deleted.
+CREATE TABLE audit_log (
+    id SERIAL PRIMARY KEY,
+    actor_id INT REFERENCES users(id) ON DELETE SET NULL,
+    action VARCHAR(255) NOT NULL,
+    target_user_id INT REFERENCES users(id) ON DELETE SET NULL,
+    details TEXT NOT NULL DEFAULT '',
+    ip VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_audit_log_actor_id ON audit_log (actor_id);
+CREATE INDEX idx_audit_log_target_user_id ON audit_log (target_user_id);
+
+-- +goose Down
+DROP TABLE audit_log;
+ALTER TABLE users DROP COLUMN disabled_at;
+ALTER TABLE users DROP COLUMN role;
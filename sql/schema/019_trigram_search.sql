@@ -0,0 +1,9 @@
+-- +goose Up
+-- Fuzzy searches match titles and contents by trigram similarity, which
+-- these indexes speed up.
+CREATE INDEX idx_notes_title_trgm ON notes USING GIN (title gin_trgm_ops);
+CREATE INDEX idx_notes_content_trgm ON notes USING GIN (content gin_trgm_ops);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_notes_content_trgm;
+DROP INDEX IF EXISTS idx_notes_title_trgm;
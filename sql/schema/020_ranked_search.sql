@@ -0,0 +1,12 @@
+-- +goose Up
+-- Full-text searches match titles as well as contents, titles weighing more
+-- in the rank of results. The index is on the same expression as the
+-- searches.
+DROP INDEX IF EXISTS idx_notes_content;
+CREATE INDEX idx_notes_search ON notes USING GIN (
+    (setweight(to_tsvector('english', title), 'A') || setweight(to_tsvector('english', content), 'B'))
+);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_notes_search;
+CREATE INDEX idx_notes_content ON notes USING GIN (to_tsvector('english', content));
@@ -0,0 +1,17 @@
+-- +goose Up
+-- Notes are searched with the text search config of their language, which
+-- defaults to the search language of their owner.
+ALTER TABLE users ADD COLUMN search_language regconfig NOT NULL DEFAULT 'english';
+ALTER TABLE notes ADD COLUMN language regconfig NOT NULL DEFAULT 'english';
+DROP INDEX IF EXISTS idx_notes_search;
+CREATE INDEX idx_notes_search ON notes USING GIN (
+    (setweight(to_tsvector(language, title), 'A') || setweight(to_tsvector(language, content), 'B'))
+);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_notes_search;
+CREATE INDEX idx_notes_search ON notes USING GIN (
+    (setweight(to_tsvector('english', title), 'A') || setweight(to_tsvector('english', content), 'B'))
+);
+ALTER TABLE notes DROP COLUMN language;
+ALTER TABLE users DROP COLUMN search_language;
@@ -0,0 +1,18 @@
+-- +goose Up
+-- Searches users run again by name. Notes matching a search updated since
+-- its results were last viewed count as unread.
+CREATE TABLE saved_searches (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    query TEXT NOT NULL,
+    mode VARCHAR(16) NOT NULL DEFAULT 'fulltext',
+    threshold DOUBLE PRECISION NOT NULL DEFAULT 0.3,
+    language regconfig,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_viewed_at TIMESTAMP,
+    UNIQUE (user_id, name)
+);
+
+-- +goose Down
+DROP TABLE saved_searches;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- The status of the response to each request to the admin API, so that the
+-- audit log tells refused and failed requests from the actions that were
+-- carried out. Entries made before are left at 0.
+ALTER TABLE audit_log ADD COLUMN status INT NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE audit_log DROP COLUMN status;
@@ -91,7 +91,7 @@ func TestPost(t *testing.T) {
 		Content: "description 3 with some long text",
 	}).Expect().Status(http.StatusCreated)
 
-	e.GET("/api/notes/search").WithQuery("q", "with text").WithHeader("Authorization", "Bearer "+user1_token).Expect().Status(http.StatusOK).JSON().Array().Length().IsEqual(2)
+	e.GET("/api/notes/search").WithQuery("q", "with text").WithHeader("Authorization", "Bearer "+user1_token).Expect().Status(http.StatusOK).JSON().Object().Value("notes").Array().Length().IsEqual(2)
 
 	// Delete note
 	// Other user should not be able to delete the note even if it is shared with them.
@@ -106,4 +106,41 @@ func TestPost(t *testing.T) {
 
 	e.GET("/api/notes/"+strconv.Itoa(note1_id)).WithHeader("Authorization", "Bearer "+user1_token).Expect().Status(http.StatusNotFound)
 	e.GET("/api/notes/"+strconv.Itoa(note1_id)).WithHeader("Authorization", "Bearer "+user2_token).Expect().Status(http.StatusNotFound)
+
+	// Deleted notes are kept in the owner's trash and can be restored.
+	e.GET("/api/notes/trash").WithHeader("Authorization", "Bearer "+user1_token).Expect().Status(http.StatusOK).JSON().Array().Length().IsEqual(1)
+	e.GET("/api/notes/trash").WithHeader("Authorization", "Bearer "+user2_token).Expect().Status(http.StatusOK).JSON().Array().Length().IsEqual(0)
+	e.POST("/api/notes/"+strconv.Itoa(note1_id)+"/restore").WithHeader("Authorization", "Bearer "+user2_token).Expect().Status(http.StatusNotFound)
+	e.POST("/api/notes/"+strconv.Itoa(note1_id)+"/restore").WithHeader("Authorization", "Bearer "+user1_token).Expect().Status(http.StatusOK)
+
+	// The restored note is shared again.
+	e.GET("/api/notes/"+strconv.Itoa(note1_id)).WithHeader("Authorization", "Bearer "+user2_token).Expect().Status(http.StatusOK)
+
+	// Sharing a notebook grants access to the notes nested inside it.
+	notebook := e.POST("/api/notebooks/").WithHeader("Authorization", "Bearer "+user1_token).WithJSON(model.NotebookDTO{
+		Name: "notebook 1",
+	}).Expect().Status(http.StatusCreated).JSON().Object()
+	notebook_id := int32(notebook.Value("id").Number().Raw())
+
+	child := e.POST("/api/notebooks/").WithHeader("Authorization", "Bearer "+user1_token).WithJSON(model.NotebookDTO{
+		Name:     "notebook 1.1",
+		ParentID: &notebook_id,
+	}).Expect().Status(http.StatusCreated).JSON().Object()
+	child_id := int32(child.Value("id").Number().Raw())
+
+	note4 := e.POST("/api/notes/").WithHeader("Authorization", "Bearer "+user1_token).WithJSON(model.NoteDTO{
+		Title:      "title 4",
+		Content:    "description 4",
+		NotebookID: &child_id,
+	}).Expect().Status(http.StatusCreated).JSON().Object()
+	note4_id := int(note4.Value("id").Number().Raw())
+
+	e.GET("/api/notes/"+strconv.Itoa(note4_id)).WithHeader("Authorization", "Bearer "+user3_token).Expect().Status(http.StatusNotFound)
+
+	e.POST("/api/notebooks/"+strconv.Itoa(int(notebook_id))+"/share").WithHeader("Authorization", "Bearer "+user1_token).WithJSON(model.NotebookShareDTO{
+		SharedWith: users[2].Email,
+	}).Expect().Status(http.StatusOK)
+
+	e.GET("/api/notes/"+strconv.Itoa(note4_id)).WithHeader("Authorization", "Bearer "+user3_token).Expect().Status(http.StatusOK)
+	e.GET("/api/notebooks/"+strconv.Itoa(int(notebook_id))+"/notes").WithQuery("recursive", "true").WithHeader("Authorization", "Bearer "+user3_token).Expect().Status(http.StatusOK).JSON().Object().Value("notes").Array().Length().IsEqual(1)
 }
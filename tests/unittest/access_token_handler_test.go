@@ -0,0 +1,91 @@
+package unittest
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"notes/internal/model"
+	"notes/internal/server"
+)
+
+func TestAccessTokens(t *testing.T) {
+	_, e := setupServer(server.NewConfig("", 8080, 100, "secret"))
+
+	token := signUpAndLogIn(t, e, model.UserCreateDTO{
+		Username: "testuser",
+		Email:    "test@example.com",
+		Password: "Secure@Passwprd123",
+	})
+	signUpAndLogIn(t, e, model.UserCreateDTO{
+		Username: "other",
+		Email:    "other@example.com",
+		Password: "Secure@Passwprd123",
+	})
+
+	rec := doRequest(e, http.MethodPost, "/api/users/me/tokens", token, model.PersonalAccessTokenDTO{Name: "backup"})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = doRequest(e, http.MethodPost, "/api/users/me/tokens", token, model.PersonalAccessTokenDTO{Name: "backup", Scopes: []string{"everything"}})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	past := time.Now().Add(-time.Hour)
+	rec = doRequest(e, http.MethodPost, "/api/users/me/tokens", token, model.PersonalAccessTokenDTO{Name: "backup", Scopes: []string{model.ScopeNotesRead}, ExpiresAt: &past})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	createToken := func(name string, scopes ...string) model.PersonalAccessToken {
+		rec := doRequest(e, http.MethodPost, "/api/users/me/tokens", token, model.PersonalAccessTokenDTO{Name: name, Scopes: scopes})
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+		var accessToken model.PersonalAccessToken
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &accessToken))
+		require.NotEmpty(t, accessToken.Token)
+		return accessToken
+	}
+	reader := createToken("backup", model.ScopeNotesRead)
+	writer := createToken("import", model.ScopeNotesRead, model.ScopeNotesWrite)
+
+	// Scopes limit what the tokens can do.
+	rec = doRequest(e, http.MethodPost, "/api/notes/", reader.Token, model.NoteDTO{Title: "title", Content: "content"})
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = doRequest(e, http.MethodPost, "/api/notes/", writer.Token, model.NoteDTO{Title: "title", Content: "content"})
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	rec = doRequest(e, http.MethodGet, "/api/notes/1", reader.Token, nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = doRequest(e, http.MethodPost, "/api/notes/1/share", writer.Token, model.NoteShareDTO{SharedWith: "other@example.com"})
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = doRequest(e, http.MethodGet, "/api/notes/1/links", reader.Token, nil)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// Tokens can't manage the account.
+	rec = doRequest(e, http.MethodGet, "/api/users/me/tokens", reader.Token, nil)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = doRequest(e, http.MethodGet, "/api/users/me/tokens", token, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var tokens []model.PersonalAccessToken
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &tokens))
+	require.Len(t, tokens, 2)
+	for _, listed := range tokens {
+		assert.Empty(t, listed.Token)
+		assert.NotNil(t, listed.LastUsedAt)
+	}
+
+	rec = doRequest(e, http.MethodDelete, "/api/users/me/tokens/1", token, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = doRequest(e, http.MethodGet, "/api/notes/1", reader.Token, nil)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = doRequest(e, http.MethodDelete, "/api/users/me/tokens/1", token, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,156 @@
+package unittest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"notes/internal/model"
+	"notes/internal/server"
+)
+
+func TestProfile(t *testing.T) {
+	config := server.NewConfig("", 8080, 100, "secret")
+	s, e := setupServer(config)
+	mailer := s.Mailer.(*MockMailer)
+
+	user := model.UserCreateDTO{Username: "testuser", Email: "test@example.com", Password: "Secure@Passwprd123"}
+	token := signUpAndLogIn(t, e, user)
+	signUpAndLogIn(t, e, model.UserCreateDTO{Username: "other", Email: "other@example.com", Password: "Secure@Passwprd123"})
+
+	rec := doRequest(e, http.MethodGet, "/api/users/me", token, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var profile model.User
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &profile))
+	assert.Equal(t, user.Username, profile.Username)
+	assert.Equal(t, user.Email, profile.Email)
+	assert.NotContains(t, rec.Body.String(), user.Password)
+
+	rec = doRequest(e, http.MethodPut, "/api/users/me", token, model.UpdateUserDTO{Username: "other"})
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	rec = doRequest(e, http.MethodPut, "/api/users/me", token, model.UpdateUserDTO{Username: ""})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = doRequest(e, http.MethodPut, "/api/users/me", token, model.UpdateUserDTO{Username: "renamed"})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &profile))
+	assert.Equal(t, "renamed", profile.Username)
+
+	// The email only changes once the new address is verified.
+	rec = doRequest(e, http.MethodPut, "/api/users/me/email", token, model.ChangeEmailDTO{Email: "new@example.com", Password: "wrong"})
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = doRequest(e, http.MethodPut, "/api/users/me/email", token, model.ChangeEmailDTO{Email: "other@example.com", Password: user.Password})
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	rec = doRequest(e, http.MethodPut, "/api/users/me/email", token, model.ChangeEmailDTO{Email: "new@example.com", Password: user.Password})
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+
+	rec = doRequest(e, http.MethodGet, "/api/users/me", token, nil)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &profile))
+	assert.Equal(t, user.Email, profile.Email)
+
+	rec = doRequest(e, http.MethodGet, verificationPath(t, mailer, config.BaseURL, "new@example.com"), "", nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = doRequest(e, http.MethodGet, "/api/users/me", token, nil)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &profile))
+	assert.Equal(t, "new@example.com", profile.Email)
+	assert.NotNil(t, profile.EmailVerifiedAt)
+}
+
+func TestChangePassword(t *testing.T) {
+	_, e := setupServer(server.NewConfig("", 8080, 100, "secret"))
+
+	user := model.UserCreateDTO{Username: "testuser", Email: "test@example.com", Password: "Secure@Passwprd123"}
+	token := signUpAndLogIn(t, e, user)
+
+	rec := doRequest(e, http.MethodPost, "/api/auth/login", "", model.LogInDTO{Email: user.Email, Password: user.Password})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var otherSession model.AuthTokens
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &otherSession))
+
+	newPassword := "New@Passwprd456"
+	rec = doRequest(e, http.MethodPut, "/api/users/me/password", token, model.ChangePasswordDTO{CurrentPassword: "wrong", NewPassword: newPassword})
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = doRequest(e, http.MethodPut, "/api/users/me/password", token, model.ChangePasswordDTO{CurrentPassword: user.Password, NewPassword: "weak"})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = doRequest(e, http.MethodPut, "/api/users/me/password", token, model.ChangePasswordDTO{CurrentPassword: user.Password, NewPassword: newPassword})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	// Only the session that changed the password stays logged in.
+	rec = doRequest(e, http.MethodGet, "/api/users/me", token, nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = doRequest(e, http.MethodGet, "/api/users/me", otherSession.Token, nil)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = doRequest(e, http.MethodPost, "/api/auth/login", "", model.LogInDTO{Email: user.Email, Password: newPassword})
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestDeleteAccount(t *testing.T) {
+	_, e := setupServer(server.NewConfig("", 8080, 100, "secret"))
+
+	owner := model.UserCreateDTO{Username: "owner", Email: "owner@example.com", Password: "Secure@Passwprd123"}
+	ownerToken := signUpAndLogIn(t, e, owner)
+	viewerToken := signUpAndLogIn(t, e, model.UserCreateDTO{Username: "viewer", Email: "viewer@example.com", Password: "Secure@Passwprd123"})
+	editorToken := signUpAndLogIn(t, e, model.UserCreateDTO{Username: "editor", Email: "editor@example.com", Password: "Secure@Passwprd123"})
+
+	createNote := func(title string) string {
+		rec := doRequest(e, http.MethodPost, "/api/notes/", ownerToken, model.NoteDTO{Title: title, Content: "content"})
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+		var note model.Note
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &note))
+		return "/api/notes/" + strconv.Itoa(int(note.ID))
+	}
+	sharedPath := createNote("shared")
+	privatePath := createNote("private")
+
+	rec := doRequest(e, http.MethodPost, sharedPath+"/share", ownerToken, model.NoteShareDTO{SharedWith: "viewer@example.com", Role: model.RoleViewer})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	rec = doRequest(e, http.MethodPost, sharedPath+"/share", ownerToken, model.NoteShareDTO{SharedWith: "editor@example.com", Role: model.RoleEditor})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = doRequest(e, http.MethodDelete, "/api/users/me", ownerToken, model.DeleteUserDTO{Password: owner.Password, SharedNotes: "keep"})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = doRequest(e, http.MethodDelete, "/api/users/me", ownerToken, model.DeleteUserDTO{Password: "wrong"})
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = doRequest(e, http.MethodDelete, "/api/users/me", ownerToken, model.DeleteUserDTO{Password: owner.Password, SharedNotes: model.SharedNotesTransfer})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = doRequest(e, http.MethodGet, "/api/users/me", ownerToken, nil)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = doRequest(e, http.MethodPost, "/api/auth/login", "", model.LogInDTO{Email: owner.Email, Password: owner.Password})
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// The editor inherits the shared note and can still share it with the viewer.
+	rec = doRequest(e, http.MethodGet, sharedPath, editorToken, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = doRequest(e, http.MethodGet, sharedPath+"/shares", editorToken, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var shares []model.NoteShare
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &shares))
+	require.Len(t, shares, 1)
+	assert.Equal(t, "viewer", shares[0].Username)
+
+	rec = doRequest(e, http.MethodGet, sharedPath, viewerToken, nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = doRequest(e, http.MethodGet, privatePath, editorToken, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
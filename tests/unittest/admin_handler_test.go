@@ -0,0 +1,190 @@
+package unittest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"notes/internal/model"
+	"notes/internal/server"
+)
+
+func TestAdmin(t *testing.T) {
+	s, e := setupServer(server.NewConfig("", 8080, 100, "secret"))
+	repo := s.Repository.(*MockRepository)
+	mailer := s.Mailer.(*MockMailer)
+
+	admin := model.UserCreateDTO{
+		Username: "admin",
+		Email:    "admin@example.com",
+		Password: "Secure@Passwprd123",
+	}
+	user := model.UserCreateDTO{
+		Username: "testuser",
+		Email:    "test@example.com",
+		Password: "Secure@Passwprd123",
+	}
+	adminToken := signUpAndLogIn(t, e, admin)
+	userToken := signUpAndLogIn(t, e, user)
+
+	rec := doRequest(e, http.MethodPost, "/api/notes/", userToken, model.NoteDTO{Title: "title", Content: "content"})
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	// Users can't use the admin API, nor can admins before logging in again
+	// with their new role.
+	rec = doRequest(e, http.MethodGet, "/api/admin/users", userToken, nil)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	repo.setUserRole(1, model.UserRoleAdmin)
+	rec = doRequest(e, http.MethodGet, "/api/admin/users", adminToken, nil)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = doRequest(e, http.MethodPost, "/api/auth/login", "", model.LogInDTO{Email: admin.Email, Password: admin.Password})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var tokens model.AuthTokens
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &tokens))
+	adminToken = tokens.Token
+
+	rec = doRequest(e, http.MethodGet, "/api/admin/users?limit=1", adminToken, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var users model.UserList
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &users))
+	require.Len(t, users.Users, 1)
+	assert.Equal(t, model.UserRoleAdmin, users.Users[0].Role)
+	require.NotEmpty(t, users.NextCursor)
+
+	rec = doRequest(e, http.MethodGet, "/api/admin/users?limit=1&cursor="+users.NextCursor, adminToken, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	users = model.UserList{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &users))
+	require.Len(t, users.Users, 1)
+	assert.Equal(t, user.Email, users.Users[0].Email)
+	assert.Empty(t, users.NextCursor)
+
+	rec = doRequest(e, http.MethodGet, "/api/admin/users?cursor=invalid", adminToken, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = doRequest(e, http.MethodGet, "/api/admin/users?q=TESTUSER", adminToken, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	users = model.UserList{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &users))
+	require.Len(t, users.Users, 1)
+	assert.Equal(t, int32(2), users.Users[0].ID)
+
+	rec = doRequest(e, http.MethodGet, "/api/admin/users/2", adminToken, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var usage model.UserWithUsage
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &usage))
+	assert.Equal(t, int64(1), usage.NoteCount)
+	assert.Equal(t, int64(len("title")+len("content")), usage.StorageBytes)
+	assert.NotContains(t, rec.Body.String(), "password")
+
+	rec = doRequest(e, http.MethodGet, "/api/admin/users/99", adminToken, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// Disabled users are logged out and can't log in again.
+	rec = doRequest(e, http.MethodPost, "/api/admin/users/1/disable", adminToken, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = doRequest(e, http.MethodPost, "/api/admin/users/2/disable", adminToken, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = doRequest(e, http.MethodGet, "/api/notes/", userToken, nil)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = doRequest(e, http.MethodPost, "/api/auth/login", "", model.LogInDTO{Email: user.Email, Password: user.Password})
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = doRequest(e, http.MethodPost, "/api/admin/users/2/enable", adminToken, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = doRequest(e, http.MethodPost, "/api/auth/login", "", model.LogInDTO{Email: user.Email, Password: user.Password})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &tokens))
+	userToken = tokens.Token
+
+	// A forced reset logs the user out and mails them a token to choose a
+	// new password.
+	sentBefore := len(mailer.Sent())
+	rec = doRequest(e, http.MethodPost, "/api/admin/users/2/password-reset", adminToken, nil)
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+
+	rec = doRequest(e, http.MethodGet, "/api/notes/", userToken, nil)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = doRequest(e, http.MethodPost, "/api/auth/login", "", model.LogInDTO{Email: user.Email, Password: user.Password})
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	sent := mailer.Sent()
+	require.Len(t, sent, sentBefore+1)
+	assert.Equal(t, user.Email, sent[sentBefore].To)
+	lines := strings.Split(strings.TrimSpace(sent[sentBefore].Body), "\n")
+	newPassword := "New@Passwprd456"
+	rec = doRequest(e, http.MethodPost, "/api/auth/password/reset", "", model.ResetPasswordDTO{Token: lines[len(lines)-1], Password: newPassword})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = doRequest(e, http.MethodPost, "/api/auth/login", "", model.LogInDTO{Email: user.Email, Password: newPassword})
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Demoted admins lose access even with a token issued before.
+	repo.setUserRole(1, model.UserRoleUser)
+	rec = doRequest(e, http.MethodGet, "/api/admin/users", adminToken, nil)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	repo.setUserRole(1, model.UserRoleAdmin)
+
+	// Every request of the admin is audited, newest first.
+	rec = doRequest(e, http.MethodGet, "/api/admin/audit?target_user_id=2&limit=3", adminToken, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var log model.AuditLog
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &log))
+	require.Len(t, log.Entries, 3)
+	assert.Equal(t, "POST /api/admin/users/:id/password-reset", log.Entries[0].Action)
+	assert.Equal(t, "POST /api/admin/users/:id/enable", log.Entries[1].Action)
+	assert.Equal(t, "POST /api/admin/users/:id/disable", log.Entries[2].Action)
+	assert.Equal(t, int32(http.StatusAccepted), log.Entries[0].Status)
+	require.NotNil(t, log.Entries[0].ActorID)
+	assert.Equal(t, int32(1), *log.Entries[0].ActorID)
+	assert.Equal(t, "192.0.2.1", log.Entries[0].IP)
+	require.NotEmpty(t, log.NextCursor)
+
+	rec = doRequest(e, http.MethodGet, "/api/admin/audit?target_user_id=2&cursor="+log.NextCursor, adminToken, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	log = model.AuditLog{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &log))
+	require.Len(t, log.Entries, 1)
+	assert.Equal(t, "GET /api/admin/users/:id", log.Entries[0].Action)
+
+	// Requests are recorded once handled, with the status of their response.
+	rec = doRequest(e, http.MethodPost, "/api/admin/users/999/disable", adminToken, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = doRequest(e, http.MethodGet, "/api/admin/audit?actor_id=1", adminToken, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	log = model.AuditLog{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &log))
+	assert.Equal(t, "POST /api/admin/users/:id/disable", log.Entries[0].Action)
+	assert.Equal(t, int32(http.StatusNotFound), log.Entries[0].Status)
+	assert.Equal(t, "GET /api/admin/audit", log.Entries[1].Action)
+	assert.Equal(t, int32(http.StatusOK), log.Entries[1].Status)
+
+	rec = doRequest(e, http.MethodGet, "/api/admin/audit?actor_id=1", adminToken, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	log = model.AuditLog{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &log))
+	assert.Equal(t, "GET /api/admin/audit", log.Entries[0].Action)
+	assert.Equal(t, "actor_id=1", log.Entries[0].Details)
+
+	// Requests that can't be recorded are refused.
+	repo.setAuditFailure(true)
+	rec = doRequest(e, http.MethodGet, "/api/admin/users", adminToken, nil)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.NotContains(t, rec.Body.String(), user.Email)
+	repo.setAuditFailure(false)
+
+	rec = doRequest(e, http.MethodGet, "/api/admin/audit?actor_id=admin", adminToken, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,57 @@
+package unittest
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"notes/internal/jwtkeys"
+	"notes/internal/model"
+	"notes/internal/server"
+)
+
+func TestAsymmetricSigning(t *testing.T) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+	keyFile := filepath.Join(t.TempDir(), "jwt.pem")
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+
+	config := server.NewConfig("", 8080, 100, "secret")
+	config.Keys, err = jwtkeys.Load(keyFile, nil, "")
+	require.NoError(t, err)
+	_, e := setupServer(config)
+
+	token := signUpAndLogIn(t, e, model.UserCreateDTO{Username: "testuser", Email: "test@example.com", Password: "Secure@Passwprd123"})
+	rec := doRequest(e, http.MethodGet, "/api/users/me", token, nil)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, &jwt.RegisteredClaims{})
+	require.NoError(t, err)
+	assert.Equal(t, "EdDSA", parsed.Header["alg"])
+
+	rec = doRequest(e, http.MethodGet, "/.well-known/jwks.json", "", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var jwks jwtkeys.JWKS
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &jwks))
+	require.Len(t, jwks.Keys, 1)
+	assert.Equal(t, parsed.Header["kid"], jwks.Keys[0].Kid)
+	assert.Equal(t, "OKP", jwks.Keys[0].Kty)
+
+	// Tokens signed with the shared secret are not accepted anymore.
+	hmac, err := jwtkeys.NewHMAC("secret").Sign(parsed.Claims)
+	require.NoError(t, err)
+	rec = doRequest(e, http.MethodGet, "/api/users/me", hmac, nil)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
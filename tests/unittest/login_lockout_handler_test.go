@@ -0,0 +1,91 @@
+package unittest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"notes/internal/model"
+	"notes/internal/server"
+)
+
+func TestLoginLockout(t *testing.T) {
+	config := server.NewConfig("", 8080, 100, "secret")
+	config.LoginLockout = model.LoginLockout{
+		AccountThreshold: 3,
+		IPThreshold:      10,
+		Delay:            time.Minute,
+		MaxDelay:         time.Hour,
+		Window:           24 * time.Hour,
+	}
+	_, e := setupServer(config)
+
+	user := model.UserCreateDTO{Username: "testuser", Email: "test@example.com", Password: "Secure@Passwprd123"}
+	other := model.UserCreateDTO{Username: "other", Email: "other@example.com", Password: "Secure@Passwprd123"}
+	signUpAndLogIn(t, e, user)
+	signUpAndLogIn(t, e, other)
+
+	logIn := func(ip, email, password string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		_ = json.NewEncoder(&body).Encode(model.LogInDTO{Email: email, Password: password})
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/login", &body)
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.RemoteAddr = ip + ":1234"
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// A successful login forgets the failures of the account.
+	assert.Equal(t, http.StatusUnauthorized, logIn("10.0.0.1", user.Email, "wrong").Code)
+	assert.Equal(t, http.StatusUnauthorized, logIn("10.0.0.1", user.Email, "wrong").Code)
+	assert.Equal(t, http.StatusOK, logIn("10.0.0.1", user.Email, user.Password).Code)
+	assert.Equal(t, http.StatusUnauthorized, logIn("10.0.0.1", user.Email, "wrong").Code)
+	assert.Equal(t, http.StatusUnauthorized, logIn("10.0.0.1", user.Email, "wrong").Code)
+
+	rec := logIn("10.0.0.1", user.Email, "wrong")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "60", rec.Header().Get("Retry-After"))
+
+	// The account is locked even for the right password from another IP.
+	assert.Equal(t, http.StatusTooManyRequests, logIn("10.0.0.2", user.Email, user.Password).Code)
+
+	// Unknown emails get the same responses as accounts.
+	assert.Equal(t, http.StatusUnauthorized, logIn("10.0.0.3", "nobody@example.com", "wrong").Code)
+	assert.Equal(t, http.StatusUnauthorized, logIn("10.0.0.3", "nobody@example.com", "wrong").Code)
+	assert.Equal(t, http.StatusTooManyRequests, logIn("10.0.0.3", "nobody@example.com", "wrong").Code)
+
+	// Failures spread over many accounts lock the IP.
+	for i := 1; i < 10; i++ {
+		assert.Equal(t, http.StatusUnauthorized, logIn("10.0.0.4", fmt.Sprintf("user%d@example.com", i), "wrong").Code)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, logIn("10.0.0.4", "user10@example.com", "wrong").Code)
+	assert.Equal(t, http.StatusTooManyRequests, logIn("10.0.0.4", other.Email, other.Password).Code)
+	assert.Equal(t, http.StatusOK, logIn("10.0.0.5", other.Email, other.Password).Code)
+
+	// A successful login forgets the failures of the IP too, so that users
+	// sharing it don't lock it by the odd typo.
+	for i := 1; i < 10; i++ {
+		assert.Equal(t, http.StatusUnauthorized, logIn("10.0.0.7", fmt.Sprintf("typo%d@example.com", i), "wrong").Code)
+	}
+	assert.Equal(t, http.StatusOK, logIn("10.0.0.7", other.Email, other.Password).Code)
+	for i := 1; i < 10; i++ {
+		assert.Equal(t, http.StatusUnauthorized, logIn("10.0.0.7", fmt.Sprintf("again%d@example.com", i), "wrong").Code)
+	}
+
+	// Clients can't pick their IP unless the server is behind a proxy.
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/login", bytes.NewBufferString(`{"email":"other@example.com","password":"Secure@Passwprd123"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(echo.HeaderXForwardedFor, "10.0.0.6")
+	req.RemoteAddr = "10.0.0.4:1234"
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+}
@@ -0,0 +1,30 @@
+package unittest
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"notes/internal/mail"
+)
+
+// MockMailer keeps the emails it is asked to send
+type MockMailer struct {
+	sent []mail.Message
+	mu   sync.Mutex
+}
+
+func (m *MockMailer) Send(ctx context.Context, msg mail.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func (m *MockMailer) Sent() []mail.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return slices.Clone(m.sent)
+}
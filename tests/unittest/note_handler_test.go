@@ -0,0 +1,503 @@
+package unittest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"notes/internal/model"
+	"notes/internal/server"
+)
+
+// helper function to send a request with an optional JSON payload and bearer token
+func doRequest(e *echo.Echo, method, path, token string, payload any) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	if payload != nil {
+		_ = json.NewEncoder(&body).Encode(payload)
+	}
+	req := httptest.NewRequest(method, path, &body)
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if token != "" {
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+// helper function to sign up a user and return its login token
+func signUpAndLogIn(t *testing.T, e *echo.Echo, user model.UserCreateDTO) string {
+	rec := doRequest(e, http.MethodPost, "/api/auth/signup", "", user)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	rec = doRequest(e, http.MethodPost, "/api/auth/login", "", model.LogInDTO{
+		Email:    user.Email,
+		Password: user.Password,
+	})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var resp map[string]string
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return resp["token"]
+}
+
+func TestNoteRevisions(t *testing.T) {
+	_, e := setupServer(server.NewConfig("", 8080, 100, "secret"))
+
+	token := signUpAndLogIn(t, e, model.UserCreateDTO{
+		Username: "testuser",
+		Email:    "test@example.com",
+		Password: "Secure@Passwprd123",
+	})
+
+	rec := doRequest(e, http.MethodPost, "/api/notes/", token, model.NoteDTO{Title: "v1", Content: "content 1"})
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var note model.Note
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &note))
+	notePath := "/api/notes/" + strconv.Itoa(int(note.ID))
+
+	rec = doRequest(e, http.MethodPut, notePath, token, model.NoteDTO{Title: "v2", Content: "content 2"})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = doRequest(e, http.MethodGet, notePath+"/revisions", token, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var revisions []model.NoteRevision
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &revisions))
+	require.Len(t, revisions, 1)
+	assert.Equal(t, "v1", revisions[0].Title)
+
+	rec = doRequest(e, http.MethodGet, notePath+"/revisions/1", token, nil)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = doRequest(e, http.MethodGet, notePath+"/revisions/2", token, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = doRequest(e, http.MethodPost, notePath+"/revisions/1/restore", token, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &note))
+	assert.Equal(t, "v1", note.Title)
+	assert.Equal(t, "content 1", note.Content)
+
+	// The restore itself is recorded, so it can be undone.
+	rec = doRequest(e, http.MethodGet, notePath+"/revisions", token, nil)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &revisions))
+	require.Len(t, revisions, 2)
+	assert.Equal(t, "v2", revisions[0].Title)
+}
+
+func TestDiffNote(t *testing.T) {
+	_, e := setupServer(server.NewConfig("", 8080, 100, "secret"))
+
+	token := signUpAndLogIn(t, e, model.UserCreateDTO{
+		Username: "testuser",
+		Email:    "test@example.com",
+		Password: "Secure@Passwprd123",
+	})
+
+	rec := doRequest(e, http.MethodPost, "/api/notes/", token, model.NoteDTO{Title: "title", Content: "line 1\nline 2"})
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var note model.Note
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &note))
+	notePath := "/api/notes/" + strconv.Itoa(int(note.ID))
+
+	rec = doRequest(e, http.MethodPut, notePath, token, model.NoteDTO{Title: "title", Content: "line 1\nline 2 changed"})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = doRequest(e, http.MethodGet, notePath+"/diff?from=1", token, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var noteDiff model.NoteDiff
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &noteDiff))
+	assert.Nil(t, noteDiff.To)
+	assert.Empty(t, noteDiff.Title.Hunks)
+	assert.Equal(t, "--- revision 1/content\n+++ current/content\n@@ -1,2 +1,2 @@\n line 1\n-line 2\n+line 2 changed\n", noteDiff.Content.Unified)
+	require.Len(t, noteDiff.Content.Hunks, 1)
+
+	rec = doRequest(e, http.MethodGet, notePath+"/diff?from=1&to=1", token, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &noteDiff))
+	assert.Empty(t, noteDiff.Content.Unified)
+
+	rec = doRequest(e, http.MethodGet, notePath+"/diff", token, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = doRequest(e, http.MethodGet, notePath+"/diff?from=5", token, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestTrash(t *testing.T) {
+	s, e := setupServer(server.NewConfig("", 8080, 100, "secret"))
+
+	token := signUpAndLogIn(t, e, model.UserCreateDTO{
+		Username: "testuser",
+		Email:    "test@example.com",
+		Password: "Secure@Passwprd123",
+	})
+
+	rec := doRequest(e, http.MethodPost, "/api/notes/", token, model.NoteDTO{Title: "title", Content: "content"})
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var note model.Note
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &note))
+	notePath := "/api/notes/" + strconv.Itoa(int(note.ID))
+
+	rec = doRequest(e, http.MethodDelete, notePath, token, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = doRequest(e, http.MethodGet, notePath, token, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	var notes []model.Note
+	rec = doRequest(e, http.MethodGet, "/api/notes/trash", token, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &notes))
+	require.Len(t, notes, 1)
+	assert.NotNil(t, notes[0].DeletedAt)
+
+	rec = doRequest(e, http.MethodPost, notePath+"/restore", token, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = doRequest(e, http.MethodGet, notePath, token, nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// A note that is not in the trash cannot be restored.
+	rec = doRequest(e, http.MethodPost, notePath+"/restore", token, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// Only notes past the retention are purged.
+	rec = doRequest(e, http.MethodDelete, notePath, token, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	purged, err := s.Repository.PurgeDeletedNotes(context.Background(), time.Hour)
+	require.NoError(t, err)
+	assert.Zero(t, purged)
+
+	purged, err = s.Repository.PurgeDeletedNotes(context.Background(), 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+}
+
+func TestTags(t *testing.T) {
+	_, e := setupServer(server.NewConfig("", 8080, 100, "secret"))
+
+	token := signUpAndLogIn(t, e, model.UserCreateDTO{
+		Username: "testuser",
+		Email:    "test@example.com",
+		Password: "Secure@Passwprd123",
+	})
+
+	for _, noteDTO := range []model.NoteDTO{
+		{Title: "note 1", Content: "content", Tags: []string{"work", "urgent"}},
+		{Title: "note 2", Content: "content", Tags: []string{"work"}},
+		{Title: "note 3", Content: "content", Tags: []string{"home"}},
+	} {
+		rec := doRequest(e, http.MethodPost, "/api/notes/", token, noteDTO)
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	}
+
+	rec := doRequest(e, http.MethodPost, "/api/notes/", token, model.NoteDTO{Title: "bad", Tags: []string{"two words"}})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// Test cases
+	testCases := []struct {
+		query  string
+		expect int
+	}{
+		{"", 3},
+		{"?tag=work", 2},
+		{"?tag=work&tag=urgent", 1},
+		{"?tag=work&tag=urgent&match=all", 1},
+		{"?tag=urgent&tag=home&match=any", 2},
+		{"?tag=missing", 0},
+	}
+
+	for _, testCase := range testCases {
+		rec := doRequest(e, http.MethodGet, "/api/notes/"+testCase.query, token, nil)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		var notes model.NoteList
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &notes))
+		assert.Len(t, notes.Notes, testCase.expect, "Unexpected notes for query: %s", testCase.query)
+	}
+
+	rec = doRequest(e, http.MethodGet, "/api/notes/?match=some", token, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// Renaming into an existing tag merges the two.
+	rec = doRequest(e, http.MethodPut, "/api/tags/urgent", token, model.TagRenameDTO{Name: "work"})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = doRequest(e, http.MethodGet, "/api/tags/", token, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var tags []model.Tag
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &tags))
+	assert.Equal(t, []model.Tag{{Name: "home", NoteCount: 1}, {Name: "work", NoteCount: 2}}, tags)
+
+	rec = doRequest(e, http.MethodPut, "/api/tags/urgent", token, model.TagRenameDTO{Name: "later"})
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// Tags with characters escaped in the path are renamed too.
+	rec = doRequest(e, http.MethodPost, "/api/notes/", token, model.NoteDTO{Title: "note 4", Tags: []string{"a/b", "50%"}})
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	rec = doRequest(e, http.MethodPut, "/api/tags/"+url.PathEscape("a/b"), token, model.TagRenameDTO{Name: "ab"})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	rec = doRequest(e, http.MethodPut, "/api/tags/"+url.PathEscape("50%"), token, model.TagRenameDTO{Name: "half"})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = doRequest(e, http.MethodGet, "/api/tags/", token, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &tags))
+	assert.Equal(t, []model.Tag{{Name: "ab", NoteCount: 1}, {Name: "half", NoteCount: 1}, {Name: "home", NoteCount: 1}, {Name: "work", NoteCount: 2}}, tags)
+}
+
+func TestListNotesPagination(t *testing.T) {
+	_, e := setupServer(server.NewConfig("", 8080, 100, "secret"))
+
+	token := signUpAndLogIn(t, e, model.UserCreateDTO{
+		Username: "testuser",
+		Email:    "test@example.com",
+		Password: "Secure@Passwprd123",
+	})
+
+	for _, title := range []string{"c", "a", "e", "b", "d"} {
+		rec := doRequest(e, http.MethodPost, "/api/notes/", token, model.NoteDTO{Title: title, Content: "content"})
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	}
+
+	// Follow the cursors until the last page.
+	var titles []string
+	path := "/api/notes/?sort=title&limit=2"
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, 3)
+
+		rec := doRequest(e, http.MethodGet, path, token, nil)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		var notes model.NoteList
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &notes))
+		for _, note := range notes.Notes {
+			titles = append(titles, note.Title)
+		}
+		if notes.NextCursor == "" {
+			break
+		}
+		path = "/api/notes/?sort=title&limit=2&cursor=" + notes.NextCursor
+	}
+	assert.Equal(t, []string{"a", "b", "c", "d", "e"}, titles)
+
+	rec := doRequest(e, http.MethodGet, "/api/notes/?sort=title&order=desc&limit=1", token, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var notes model.NoteList
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &notes))
+	require.Len(t, notes.Notes, 1)
+	assert.Equal(t, "e", notes.Notes[0].Title)
+	assert.NotEmpty(t, notes.NextCursor)
+
+	// Test cases
+	testCases := []string{
+		"?limit=0",
+		"?limit=101",
+		"?sort=content",
+		"?order=up",
+		"?cursor=bogus",
+	}
+
+	for _, query := range testCases {
+		rec := doRequest(e, http.MethodGet, "/api/notes/"+query, token, nil)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, "Unexpected status for query: %s", query)
+	}
+}
+
+func TestShareRoles(t *testing.T) {
+	_, e := setupServer(server.NewConfig("", 8080, 100, "secret"))
+
+	ownerToken := signUpAndLogIn(t, e, model.UserCreateDTO{
+		Username: "owner",
+		Email:    "owner@example.com",
+		Password: "Secure@Passwprd123",
+	})
+	collaboratorToken := signUpAndLogIn(t, e, model.UserCreateDTO{
+		Username: "collaborator",
+		Email:    "collaborator@example.com",
+		Password: "Secure@Passwprd123",
+	})
+
+	rec := doRequest(e, http.MethodPost, "/api/notes/", ownerToken, model.NoteDTO{Title: "title", Content: "content"})
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var note model.Note
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &note))
+	notePath := "/api/notes/" + strconv.Itoa(int(note.ID))
+
+	rec = doRequest(e, http.MethodPost, notePath+"/share", ownerToken, model.NoteShareDTO{SharedWith: "collaborator@example.com", Role: "owner"})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// Shares default to the viewer role.
+	rec = doRequest(e, http.MethodPost, notePath+"/share", ownerToken, model.NoteShareDTO{SharedWith: "collaborator@example.com"})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = doRequest(e, http.MethodGet, notePath, collaboratorToken, nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = doRequest(e, http.MethodPut, notePath, collaboratorToken, model.NoteDTO{Title: "title", Content: "edited"})
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// Sharing again changes the role.
+	rec = doRequest(e, http.MethodPost, notePath+"/share", ownerToken, model.NoteShareDTO{SharedWith: "collaborator@example.com", Role: model.RoleEditor})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = doRequest(e, http.MethodPut, notePath, collaboratorToken, model.NoteDTO{Title: "title", Content: "edited"})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &note))
+	assert.Equal(t, "edited", note.Content)
+
+	// Editors can neither delete nor re-share the note.
+	rec = doRequest(e, http.MethodDelete, notePath, collaboratorToken, nil)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = doRequest(e, http.MethodPost, notePath+"/share", collaboratorToken, model.NoteShareDTO{SharedWith: "owner@example.com", Role: model.RoleEditor})
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestNoteShares(t *testing.T) {
+	_, e := setupServer(server.NewConfig("", 8080, 100, "secret"))
+
+	ownerToken := signUpAndLogIn(t, e, model.UserCreateDTO{
+		Username: "owner",
+		Email:    "owner@example.com",
+		Password: "Secure@Passwprd123",
+	})
+	aliceToken := signUpAndLogIn(t, e, model.UserCreateDTO{
+		Username: "alice",
+		Email:    "alice@example.com",
+		Password: "Secure@Passwprd123",
+	})
+	bobToken := signUpAndLogIn(t, e, model.UserCreateDTO{
+		Username: "bob",
+		Email:    "bob@example.com",
+		Password: "Secure@Passwprd123",
+	})
+
+	rec := doRequest(e, http.MethodPost, "/api/notes/", ownerToken, model.NoteDTO{Title: "title", Content: "content"})
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var note model.Note
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &note))
+	notePath := "/api/notes/" + strconv.Itoa(int(note.ID))
+
+	for _, share := range []model.NoteShareDTO{
+		{SharedWith: "bob@example.com", Role: model.RoleEditor},
+		{SharedWith: "alice@example.com"},
+	} {
+		rec := doRequest(e, http.MethodPost, notePath+"/share", ownerToken, share)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	rec = doRequest(e, http.MethodGet, notePath+"/shares", aliceToken, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var shares []model.NoteShare
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &shares))
+	require.Len(t, shares, 2)
+	assert.Equal(t, model.NoteShare{UserID: 2, Username: "alice", Email: "alice@example.com", Role: model.RoleViewer}, shares[0])
+	assert.Equal(t, model.RoleEditor, shares[1].Role)
+
+	// Only the owner can revoke access.
+	rec = doRequest(e, http.MethodDelete, notePath+"/shares/2", bobToken, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = doRequest(e, http.MethodDelete, notePath+"/shares/2", ownerToken, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = doRequest(e, http.MethodGet, notePath, aliceToken, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// A recipient can leave the note, the owner cannot.
+	rec = doRequest(e, http.MethodPost, notePath+"/leave", ownerToken, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = doRequest(e, http.MethodPost, notePath+"/leave", bobToken, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = doRequest(e, http.MethodGet, notePath, bobToken, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = doRequest(e, http.MethodGet, notePath+"/shares", ownerToken, nil)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &shares))
+	assert.Empty(t, shares)
+}
+
+func TestShareInvitations(t *testing.T) {
+	_, e := setupServer(server.NewConfig("", 8080, 100, "secret"))
+
+	ownerToken := signUpAndLogIn(t, e, model.UserCreateDTO{
+		Username: "owner",
+		Email:    "owner@example.com",
+		Password: "Secure@Passwprd123",
+	})
+
+	var notePaths []string
+	for _, title := range []string{"note 1", "note 2"} {
+		rec := doRequest(e, http.MethodPost, "/api/notes/", ownerToken, model.NoteDTO{Title: title, Content: "content"})
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+		var note model.Note
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &note))
+		notePaths = append(notePaths, "/api/notes/"+strconv.Itoa(int(note.ID)))
+	}
+
+	// Sharing with an unknown email creates a pending invitation.
+	var invitations []model.ShareInvitation
+	for _, notePath := range notePaths {
+		rec := doRequest(e, http.MethodPost, notePath+"/share", ownerToken, model.NoteShareDTO{SharedWith: "new@example.com", Role: model.RoleEditor})
+		require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+
+		var invitation model.ShareInvitation
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &invitation))
+		invitations = append(invitations, invitation)
+	}
+
+	rec := doRequest(e, http.MethodGet, notePaths[0]+"/invitations", ownerToken, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var pending []model.ShareInvitation
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &pending))
+	require.Len(t, pending, 1)
+	assert.Equal(t, "new@example.com", pending[0].Email)
+
+	rec = doRequest(e, http.MethodDelete, notePaths[1]+"/invitations/"+strconv.Itoa(int(invitations[1].ID)), ownerToken, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	// Signing up turns the remaining invitation into a share.
+	newToken := signUpAndLogIn(t, e, model.UserCreateDTO{
+		Username: "newuser",
+		Email:    "new@example.com",
+		Password: "Secure@Passwprd123",
+	})
+
+	rec = doRequest(e, http.MethodPut, notePaths[0], newToken, model.NoteDTO{Title: "note 1", Content: "edited"})
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = doRequest(e, http.MethodGet, notePaths[1], newToken, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = doRequest(e, http.MethodGet, notePaths[0]+"/invitations", ownerToken, nil)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &pending))
+	assert.Empty(t, pending)
+}
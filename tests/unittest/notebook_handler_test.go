@@ -0,0 +1,152 @@
+package unittest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"notes/internal/model"
+	"notes/internal/server"
+)
+
+func TestNotebooks(t *testing.T) {
+	_, e := setupServer(server.NewConfig("", 8080, 100, "secret"))
+
+	token := signUpAndLogIn(t, e, model.UserCreateDTO{
+		Username: "testuser",
+		Email:    "test@example.com",
+		Password: "Secure@Passwprd123",
+	})
+
+	createNotebook := func(name string, parentID *int32) model.Notebook {
+		rec := doRequest(e, http.MethodPost, "/api/notebooks/", token, model.NotebookDTO{Name: name, ParentID: parentID})
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+		var notebook model.Notebook
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &notebook))
+		return notebook
+	}
+
+	work := createNotebook("work", nil)
+	projects := createNotebook("projects", &work.ID)
+
+	rec := doRequest(e, http.MethodPost, "/api/notebooks/", token, model.NotebookDTO{Name: ""})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	for _, noteDTO := range []model.NoteDTO{
+		{Title: "in work", NotebookID: &work.ID},
+		{Title: "in projects", NotebookID: &projects.ID},
+		{Title: "loose"},
+	} {
+		rec := doRequest(e, http.MethodPost, "/api/notes/", token, noteDTO)
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	}
+
+	workNotesPath := "/api/notebooks/" + strconv.Itoa(int(work.ID)) + "/notes"
+
+	// Test cases
+	testCases := []struct {
+		path   string
+		expect int
+	}{
+		{"/api/notes/", 3},
+		{workNotesPath, 1},
+		{workNotesPath + "?recursive=true", 2},
+	}
+
+	for _, testCase := range testCases {
+		rec := doRequest(e, http.MethodGet, testCase.path, token, nil)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		var notes model.NoteList
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &notes))
+		assert.Len(t, notes.Notes, testCase.expect, "Unexpected notes for path: %s", testCase.path)
+	}
+
+	// A notebook cannot be moved into one of its own descendants.
+	rec = doRequest(e, http.MethodPut, "/api/notebooks/"+strconv.Itoa(int(work.ID)), token, model.NotebookDTO{Name: "work", ParentID: &projects.ID})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// Move the loose note into the projects notebook.
+	rec = doRequest(e, http.MethodPut, "/api/notes/3/notebook", token, model.NoteMoveDTO{NotebookID: &projects.ID})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = doRequest(e, http.MethodGet, workNotesPath+"?recursive=true", token, nil)
+	var notes model.NoteList
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &notes))
+	assert.Len(t, notes.Notes, 3)
+
+	// Deleting a notebook keeps its notes.
+	rec = doRequest(e, http.MethodDelete, "/api/notebooks/"+strconv.Itoa(int(work.ID)), token, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = doRequest(e, http.MethodGet, "/api/notebooks/", token, nil)
+	var notebooks []model.Notebook
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &notebooks))
+	assert.Empty(t, notebooks)
+
+	rec = doRequest(e, http.MethodGet, "/api/notes/", token, nil)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &notes))
+	require.Len(t, notes.Notes, 3)
+	for _, note := range notes.Notes {
+		assert.Nil(t, note.NotebookID)
+	}
+}
+
+func TestNotebookShares(t *testing.T) {
+	_, e := setupServer(server.NewConfig("", 8080, 100, "secret"))
+
+	owner := signUpAndLogIn(t, e, model.UserCreateDTO{Username: "owner", Email: "owner@example.com", Password: "Secure@Passwprd123"})
+	other := signUpAndLogIn(t, e, model.UserCreateDTO{Username: "other", Email: "other@example.com", Password: "Secure@Passwprd123"})
+
+	rec := doRequest(e, http.MethodPost, "/api/notebooks/", owner, model.NotebookDTO{Name: "work"})
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var notebook model.Notebook
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &notebook))
+	notebookPath := "/api/notebooks/" + strconv.Itoa(int(notebook.ID))
+
+	share := model.NotebookShareDTO{SharedWith: "other@example.com"}
+	rec = doRequest(e, http.MethodPost, notebookPath+"/share", owner, share)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	// Sharing it again is a conflict rather than an error.
+	rec = doRequest(e, http.MethodPost, notebookPath+"/share", owner, share)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	rec = doRequest(e, http.MethodGet, notebookPath+"/shares", other, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var shares []model.NotebookShare
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &shares))
+	require.Len(t, shares, 1)
+	assert.Equal(t, "other", shares[0].Username)
+	otherID := strconv.Itoa(int(shares[0].UserID))
+
+	// Only the owner can revoke access.
+	rec = doRequest(e, http.MethodDelete, notebookPath+"/shares/"+otherID, other, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = doRequest(e, http.MethodDelete, notebookPath+"/shares/"+otherID, owner, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = doRequest(e, http.MethodGet, notebookPath, other, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// The user it is shared with can leave it.
+	rec = doRequest(e, http.MethodPost, notebookPath+"/share", owner, share)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = doRequest(e, http.MethodPost, notebookPath+"/leave", other, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = doRequest(e, http.MethodGet, "/api/notebooks/", other, nil)
+	var notebooks []model.Notebook
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &notebooks))
+	assert.Empty(t, notebooks)
+
+	rec = doRequest(e, http.MethodPost, notebookPath+"/leave", other, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,115 @@
+package unittest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"notes/internal/model"
+	"notes/internal/oidc"
+	"notes/internal/oidc/oidctest"
+	"notes/internal/server"
+)
+
+// helper function to log in through the identity provider, returning the
+// response of the callback
+func oidcLogIn(t *testing.T, e *echo.Echo, baseURL string) *httptest.ResponseRecorder {
+	rec := doRequest(e, http.MethodGet, "/api/auth/oidc/login", "", nil)
+	require.Equal(t, http.StatusFound, rec.Code, rec.Body.String())
+	cookies := rec.Result().Cookies()
+	require.Len(t, cookies, 1)
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(rec.Header().Get(echo.HeaderLocation))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	callback := resp.Header.Get(echo.HeaderLocation)
+	require.True(t, strings.HasPrefix(callback, baseURL), callback)
+
+	req := httptest.NewRequest(http.MethodGet, strings.TrimPrefix(callback, baseURL), nil)
+	req.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestOIDCLogIn(t *testing.T) {
+	issuer := oidctest.NewIssuer("notes")
+	defer issuer.Close()
+
+	config := server.NewConfig("", 8080, 100, "secret")
+	config.OIDCSignUp = true
+	s, e := setupServer(config)
+
+	// Without a provider the endpoints don't exist.
+	rec := doRequest(e, http.MethodGet, "/api/auth/oidc/login", "", nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	var err error
+	s.OIDC, err = oidc.Discover(context.Background(), oidc.Config{
+		Issuer:      issuer.URL,
+		ClientID:    "notes",
+		RedirectURL: config.BaseURL + "/api/auth/oidc/callback",
+	})
+	require.NoError(t, err)
+
+	// New users get an account.
+	issuer.SetUser(oidctest.User{Subject: "1", Email: "new@example.com", EmailVerified: true, PreferredUsername: "newuser"})
+	rec = oidcLogIn(t, e, config.BaseURL)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var resp map[string]string
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.NotEmpty(t, resp["token"])
+
+	rec = doRequest(e, http.MethodGet, "/api/users/me", resp["token"], nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var user model.User
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &user))
+	assert.Equal(t, "newuser", user.Username)
+	assert.Equal(t, "new@example.com", user.Email)
+
+	// Logging in again gets the same account.
+	rec = oidcLogIn(t, e, config.BaseURL)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	rec = doRequest(e, http.MethodGet, "/api/users/me", resp["token"], nil)
+	var again model.User
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &again))
+	assert.Equal(t, user.ID, again.ID)
+
+	// An account signed up with a password isn't taken over by an
+	// identity with the same email before the email is verified.
+	signUpAndLogIn(t, e, model.UserCreateDTO{Username: "testuser", Email: "test@example.com", Password: "Secure@Passwprd123"})
+	issuer.SetUser(oidctest.User{Subject: "2", Email: "test@example.com", EmailVerified: true})
+	rec = oidcLogIn(t, e, config.BaseURL)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	// Without sign up, unknown identities are denied.
+	s.Config.OIDCSignUp = false
+	issuer.SetUser(oidctest.User{Subject: "3", Email: "other@example.com", EmailVerified: true})
+	rec = oidcLogIn(t, e, config.BaseURL)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// The state must match the one of the login.
+	rec = doRequest(e, http.MethodGet, "/api/auth/oidc/login", "", nil)
+	require.Equal(t, http.StatusFound, rec.Code)
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/oidc/callback?code=code&state="+url.QueryEscape("forged"), nil)
+	req.AddCookie(rec.Result().Cookies()[0])
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = doRequest(e, http.MethodGet, "/api/auth/oidc/callback?code=code&state=state", "", nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,118 @@
+package unittest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"notes/internal/model"
+	"notes/internal/server"
+)
+
+func TestPublicLinks(t *testing.T) {
+	_, e := setupServer(server.NewConfig("", 8080, 100, "secret"))
+
+	token := signUpAndLogIn(t, e, model.UserCreateDTO{
+		Username: "testuser",
+		Email:    "test@example.com",
+		Password: "Secure@Passwprd123",
+	})
+
+	rec := doRequest(e, http.MethodPost, "/api/notes/", token, model.NoteDTO{Title: "public <title>", Content: "content"})
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var note model.Note
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &note))
+	notePath := "/api/notes/" + strconv.Itoa(int(note.ID))
+
+	past := time.Now().Add(-time.Hour)
+	rec = doRequest(e, http.MethodPost, notePath+"/links", token, model.PublicLinkDTO{ExpiresAt: &past})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = doRequest(e, http.MethodPost, notePath+"/links", token, model.PublicLinkDTO{})
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var link model.PublicLink
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &link))
+	assert.False(t, link.HasPassword)
+
+	// The link works without a token, as JSON or HTML.
+	rec = doRequest(e, http.MethodGet, "/p/"+link.Token, "", nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var publicNote model.PublicNote
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &publicNote))
+	assert.Equal(t, "public <title>", publicNote.Title)
+
+	rec = doRequest(e, http.MethodGet, "/p/"+link.Token+"?format=html", "", nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Header().Get(echo.HeaderContentType), echo.MIMETextHTML)
+	assert.Contains(t, rec.Body.String(), "<h1>public &lt;title&gt;</h1>")
+
+	// Password protected links.
+	rec = doRequest(e, http.MethodPost, notePath+"/links", token, model.PublicLinkDTO{Password: "secret"})
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var protected model.PublicLink
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &protected))
+	assert.True(t, protected.HasPassword)
+
+	rec = doRequest(e, http.MethodGet, "/p/"+protected.Token, "", nil)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	openProtected := func(method, path string, header http.Header, form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+		req.Header = header
+		if form != nil {
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+	protectedPath := "/p/" + protected.Token
+
+	rec = openProtected(http.MethodGet, protectedPath, http.Header{"X-Link-Password": {"wrong"}}, nil)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = openProtected(http.MethodGet, protectedPath, http.Header{"X-Link-Password": {"secret"}}, nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// The password form is posted, so the password stays out of the URL.
+	rec = openProtected(http.MethodGet, protectedPath+"?format=html", http.Header{}, nil)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Contains(t, rec.Body.String(), `<form method="post"`)
+
+	rec = openProtected(http.MethodPost, protectedPath+"?format=html", http.Header{}, url.Values{"password": {"wrong"}})
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Wrong password")
+
+	rec = openProtected(http.MethodPost, protectedPath+"?format=html", http.Header{}, url.Values{"password": {"secret"}})
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = openProtected(http.MethodGet, protectedPath+"?password=secret", http.Header{}, nil)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = doRequest(e, http.MethodGet, notePath+"/links", token, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var links []model.PublicLink
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &links))
+	assert.Len(t, links, 2)
+
+	// Revoked links stop working.
+	rec = doRequest(e, http.MethodDelete, notePath+"/links/"+strconv.Itoa(int(link.ID)), token, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = doRequest(e, http.MethodGet, "/p/"+link.Token, "", nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
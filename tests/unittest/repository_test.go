@@ -2,26 +2,98 @@ package unittest
 
 import (
 	"context"
-	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode"
 
 	"notes/internal/model"
+	"notes/internal/repository"
+	"notes/internal/searchquery"
 )
 
 type MockRepository struct {
-	users       map[int32]model.User
-	notes       map[int32]model.Note
-	sharedNotes map[int32][]int32 // Map of noteID to a slice of userIDs who have access
-	mu          sync.Mutex
+	users           map[int32]model.User
+	notes           map[int32]model.Note
+	sharedNotes     map[int32]map[int32]string // Map of noteID to the roles of the users who have access
+	revisions       map[int32][]model.NoteRevision
+	notebooks       map[int32]model.Notebook
+	sharedNotebooks map[int32][]int32 // Map of notebookID to a slice of userIDs who have access
+	sessionTokens   []mockSessionToken
+	userTokens      map[string]mockUserToken
+	accessTokens    map[int32]model.PersonalAccessToken
+	identities      map[string]int32 // Map of issuer and subject to the linked userID
+	totps           map[int32]mockTOTP
+	recoveryCodes   map[int32][]string           // Map of userID to the unused recovery codes
+	loginFailures   map[string]mockLoginFailures // Map of "account|email" or "ip|ip" to its failures
+	invitations     map[int32]model.ShareInvitation
+	publicLinks     map[int32]model.PublicLink
+	linkPasswords   map[int32]string // Map of linkID to its password, if any
+	auditLog        []model.AuditEntry
+	failAudit       bool // Whether audit entries fail to be written
+	savedSearches   map[int32]model.SavedSearch
+	lastUserID      int32
+	lastSearchID    int32
+	mu              sync.Mutex
+
+	// RequireVerifiedEmail mirrors the setting of the database repository
+	RequireVerifiedEmail bool
+}
+
+// mockSessionToken is a refresh token and the access token issued with it
+type mockSessionToken struct {
+	model.Session
+	family  int
+	used    bool
+	revoked bool
+}
+
+// mockTOTP is the TOTP secret of a user and the period of the last code
+// used
+type mockTOTP struct {
+	model.TOTP
+	lastCounter int64
+}
+
+// mockLoginFailures are the failed logins of an account or IP
+type mockLoginFailures struct {
+	failures      int
+	lastFailureAt time.Time
+	lockedUntil   time.Time
+}
+
+// mockUserToken is a token mailed to a user, such as a password reset
+type mockUserToken struct {
+	userID  int32
+	purpose string
+	email   string
+	used    bool
 }
 
 func NewMockRepository() *MockRepository {
 	return &MockRepository{
-		users:       make(map[int32]model.User),
-		notes:       make(map[int32]model.Note),
-		sharedNotes: make(map[int32][]int32),
+		users:           make(map[int32]model.User),
+		notes:           make(map[int32]model.Note),
+		sharedNotes:     make(map[int32]map[int32]string),
+		revisions:       make(map[int32][]model.NoteRevision),
+		notebooks:       make(map[int32]model.Notebook),
+		sharedNotebooks: make(map[int32][]int32),
+		userTokens:      make(map[string]mockUserToken),
+		accessTokens:    make(map[int32]model.PersonalAccessToken),
+		identities:      make(map[string]int32),
+		totps:           make(map[int32]mockTOTP),
+		recoveryCodes:   make(map[int32][]string),
+		loginFailures:   make(map[string]mockLoginFailures),
+		invitations:     make(map[int32]model.ShareInvitation),
+		publicLinks:     make(map[int32]model.PublicLink),
+		linkPasswords:   make(map[int32]string),
+		savedSearches:   make(map[int32]model.SavedSearch),
 	}
 }
 
@@ -29,18 +101,37 @@ func (m *MockRepository) CreateUser(ctx context.Context, user model.UserCreateDT
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	m.lastUserID++
 	newUser := model.User{
-		ID:           int32(len(m.users) + 1),
-		Username:     user.Username,
-		Email:        user.Email,
-		PasswordHash: user.Password, // Simulate password hash
-		CreatedAt:    time.Now(),
+		ID:             m.lastUserID,
+		Username:       user.Username,
+		Email:          user.Email,
+		PasswordHash:   user.Password, // Simulate password hash
+		CreatedAt:      time.Now(),
+		Role:           model.UserRoleUser,
+		SearchLanguage: model.DefaultSearchLanguage,
 	}
 
 	m.users[newUser.ID] = newUser
+
+	if !m.RequireVerifiedEmail {
+		m.acceptShareInvitations(newUser)
+	}
 	return &newUser, nil
 }
 
+func (m *MockRepository) acceptShareInvitations(user model.User) {
+	for id, invitation := range m.invitations {
+		if invitation.Email == user.Email {
+			if m.sharedNotes[invitation.NoteID] == nil {
+				m.sharedNotes[invitation.NoteID] = make(map[int32]string)
+			}
+			m.sharedNotes[invitation.NoteID][user.ID] = invitation.Role
+			delete(m.invitations, id)
+		}
+	}
+}
+
 func (m *MockRepository) GetUserByEmailAndPassword(ctx context.Context, login model.LogInDTO) (*model.User, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -50,121 +141,1830 @@ func (m *MockRepository) GetUserByEmailAndPassword(ctx context.Context, login mo
 			return &user, nil
 		}
 	}
-	return nil, errors.New("user not found")
+	return nil, repository.ErrInvalidPassword
 }
 
-func (m *MockRepository) CreateNote(ctx context.Context, noteDTO model.NoteDTO) (*model.Note, error) {
+func (m *MockRepository) userByEmail(email string) (model.User, bool) {
+	for _, user := range m.users {
+		if user.Email == email {
+			return user, true
+		}
+	}
+	return model.User{}, false
+}
+
+func (m *MockRepository) GetUserByIdentity(ctx context.Context, identity model.Identity, signUp bool) (*model.User, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	newNote := model.Note{
-		ID:        int32(len(m.notes) + 1),
-		UserID:    noteDTO.UserID,
-		Title:     noteDTO.Title,
-		Content:   noteDTO.Content,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+	key := identity.Issuer + "|" + identity.Subject
+	if userID, ok := m.identities[key]; ok {
+		user := m.users[userID]
+		return &user, nil
 	}
 
-	m.notes[newNote.ID] = newNote
-	return &newNote, nil
+	user, ok := m.userByEmail(identity.Email)
+	switch {
+	case ok:
+		if !identity.EmailVerified || user.EmailVerifiedAt == nil {
+			return nil, repository.ErrAlreadyExists
+		}
+	case signUp:
+		m.lastUserID++
+		user = model.User{
+			ID:             m.lastUserID,
+			Username:       identity.Username,
+			Email:          identity.Email,
+			CreatedAt:      time.Now(),
+			Role:           model.UserRoleUser,
+			SearchLanguage: model.DefaultSearchLanguage,
+		}
+		if identity.EmailVerified {
+			now := time.Now()
+			user.EmailVerifiedAt = &now
+		}
+		m.users[user.ID] = user
+		if identity.EmailVerified || !m.RequireVerifiedEmail {
+			m.acceptShareInvitations(user)
+		}
+	default:
+		return nil, repository.ErrNotFound
+	}
+
+	m.identities[key] = user.ID
+	return &user, nil
+}
+
+func (m *MockRepository) newUserToken(userID int32, email, purpose string) string {
+	token := purpose + strconv.Itoa(len(m.userTokens)+1)
+	m.userTokens[token] = mockUserToken{userID: userID, purpose: purpose, email: email}
+	return token
+}
+
+// useUserToken uses up the token, along with the other tokens of the user
+// for the same purpose
+func (m *MockRepository) useUserToken(token, purpose string) (mockUserToken, bool) {
+	userToken, ok := m.userTokens[token]
+	if !ok || userToken.used || userToken.purpose != purpose {
+		return userToken, false
+	}
+
+	for t, other := range m.userTokens {
+		if other.userID == userToken.userID && other.purpose == purpose {
+			other.used = true
+			m.userTokens[t] = other
+		}
+	}
+	return userToken, true
 }
 
-func (m *MockRepository) ListNotesByUserID(ctx context.Context, userID int32) ([]model.Note, error) {
+func (m *MockRepository) CreateEmailVerificationToken(ctx context.Context, email string, ttl time.Duration) (string, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	var notes []model.Note
-	for _, note := range m.notes {
-		if note.UserID == userID {
-			notes = append(notes, note)
+	user, ok := m.userByEmail(email)
+	if !ok || user.EmailVerifiedAt != nil {
+		return "", repository.ErrNotFound
+	}
+	return m.newUserToken(user.ID, email, "verify"), nil
+}
+
+func (m *MockRepository) VerifyEmail(ctx context.Context, token string) (*model.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	userToken, ok := m.useUserToken(token, "verify")
+	if !ok {
+		return nil, repository.ErrInvalidToken
+	}
+
+	if other, ok := m.userByEmail(userToken.email); ok && other.ID != userToken.userID {
+		return nil, repository.ErrAlreadyExists
+	}
+
+	now := time.Now()
+	user := m.users[userToken.userID]
+	user.Email = userToken.email
+	user.EmailVerifiedAt = &now
+	m.users[user.ID] = user
+
+	m.acceptShareInvitations(user)
+	return &user, nil
+}
+
+func (m *MockRepository) CreatePasswordResetToken(ctx context.Context, email string, ttl time.Duration) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.userByEmail(email)
+	if !ok {
+		return "", repository.ErrNotFound
+	}
+	return m.newUserToken(user.ID, email, "reset"), nil
+}
+
+func (m *MockRepository) ResetPassword(ctx context.Context, token, password string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	resetToken, ok := m.useUserToken(token, "reset")
+	if !ok {
+		return repository.ErrInvalidToken
+	}
+
+	user := m.users[resetToken.userID]
+	user.PasswordHash = password // Simulate password hash
+	m.users[user.ID] = user
+
+	m.revokeSessionTokens(func(t mockSessionToken) bool { return t.UserID == user.ID })
+	return nil
+}
+
+// userWithPassword returns the user if the password is theirs
+func (m *MockRepository) userWithPassword(userID int32, password string) (model.User, error) {
+	user, ok := m.users[userID]
+	if !ok {
+		return model.User{}, repository.ErrNotFound
+	}
+	if user.PasswordHash != password { // Simulate password hash check
+		return model.User{}, repository.ErrInvalidPassword
+	}
+	return user, nil
+}
+
+func (m *MockRepository) GetUser(ctx context.Context, userID int32) (*model.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[userID]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return &user, nil
+}
+
+func (m *MockRepository) UpdateProfile(ctx context.Context, userID int32, profile model.UpdateUserDTO) (*model.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[userID]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	for _, other := range m.users {
+		if other.Username == profile.Username && other.ID != userID {
+			return nil, repository.ErrAlreadyExists
 		}
 	}
-	return notes, nil
+
+	user.Username = profile.Username
+	if profile.SearchLanguage != "" {
+		user.SearchLanguage = profile.SearchLanguage
+	}
+	m.users[userID] = user
+	return &user, nil
 }
 
-func (m *MockRepository) GetNoteByUserID(ctx context.Context, noteID, userID int32) (*model.Note, error) {
+func (m *MockRepository) CheckPassword(ctx context.Context, userID int32, password string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	note, ok := m.notes[noteID]
-	if !ok || (note.UserID != userID && !m.isNoteSharedWithUser(noteID, userID)) {
-		return nil, errors.New("note not found or access denied")
+	_, err := m.userWithPassword(userID, password)
+	return err
+}
+
+func (m *MockRepository) ChangePassword(ctx context.Context, userID int32, currentPassword, newPassword string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, err := m.userWithPassword(userID, currentPassword)
+	if err != nil {
+		return err
 	}
-	return &note, nil
+
+	user.PasswordHash = newPassword // Simulate password hash
+	m.users[userID] = user
+	return nil
 }
 
-func (m *MockRepository) UpdateNote(ctx context.Context, noteID int32, noteDTO model.NoteDTO) (*model.Note, error) {
+func (m *MockRepository) CreateEmailChangeToken(ctx context.Context, userID int32, password, email string, ttl time.Duration) (string, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	note, ok := m.notes[noteID]
-	if !ok || (note.UserID != noteDTO.UserID && !m.isNoteSharedWithUser(noteID, noteDTO.UserID)) {
-		return nil, errors.New("note not found or access denied")
+	if _, err := m.userWithPassword(userID, password); err != nil {
+		return "", err
 	}
+	if other, ok := m.userByEmail(email); ok && other.ID != userID {
+		return "", repository.ErrAlreadyExists
+	}
+	return m.newUserToken(userID, email, "verify"), nil
+}
 
-	updatedNote := model.Note{
-		ID:        noteID,
-		UserID:    noteDTO.UserID,
-		Title:     noteDTO.Title,
-		Content:   noteDTO.Content,
-		CreatedAt: note.CreatedAt,
-		UpdatedAt: time.Now(),
+func (m *MockRepository) DeleteUser(ctx context.Context, userID int32, password string, transferSharedNotes bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := m.userWithPassword(userID, password); err != nil {
+		return err
 	}
 
-	m.notes[noteID] = updatedNote
-	return &updatedNote, nil
+	rank := map[string]int{model.RoleEditor: 3, model.RoleCommenter: 2, model.RoleViewer: 1}
+	for id, note := range m.notes {
+		if note.UserID != userID {
+			delete(m.sharedNotes[id], userID)
+			continue
+		}
+
+		// the collaborator with the highest role, then the lowest id, inherits the note
+		var heir int32
+		for sharedWith, role := range m.sharedNotes[id] {
+			if heir == 0 || rank[role] > rank[m.sharedNotes[id][heir]] ||
+				(rank[role] == rank[m.sharedNotes[id][heir]] && sharedWith < heir) {
+				heir = sharedWith
+			}
+		}
+
+		if transferSharedNotes && heir != 0 && note.DeletedAt == nil {
+			note.UserID = heir
+			note.NotebookID = nil
+			note.Tags = []string{}
+			m.notes[id] = note
+			delete(m.sharedNotes[id], heir)
+			continue
+		}
+
+		delete(m.notes, id)
+		delete(m.sharedNotes, id)
+	}
+
+	m.revokeSessionTokens(func(t mockSessionToken) bool { return t.UserID == userID })
+	delete(m.users, userID)
+	return nil
 }
 
-func (m *MockRepository) DeleteNote(ctx context.Context, noteID, userID int32) error {
+func (m *MockRepository) GetTOTP(ctx context.Context, userID int32) (*model.TOTP, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	note, ok := m.notes[noteID]
-	if !ok || (note.UserID != userID && !m.isNoteSharedWithUser(noteID, userID)) {
-		return errors.New("note not found or access denied")
+	userTOTP, ok := m.totps[userID]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return &userTOTP.TOTP, nil
+}
+
+func (m *MockRepository) StartTOTPEnrollment(ctx context.Context, userID int32, password, secret string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := m.userWithPassword(userID, password); err != nil {
+		return err
+	}
+	if m.totps[userID].EnabledAt != nil {
+		return repository.ErrAlreadyExists
+	}
+	m.totps[userID] = mockTOTP{TOTP: model.TOTP{UserID: userID, Secret: secret}}
+	return nil
+}
+
+func (m *MockRepository) EnableTOTP(ctx context.Context, userID int32, counter int64) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	userTOTP, ok := m.totps[userID]
+	if !ok || userTOTP.EnabledAt != nil {
+		return nil, repository.ErrNotFound
+	}
+	now := time.Now()
+	userTOTP.EnabledAt = &now
+	userTOTP.lastCounter = counter
+	m.totps[userID] = userTOTP
+
+	return m.newRecoveryCodes(userID), nil
+}
+
+func (m *MockRepository) newRecoveryCodes(userID int32) []string {
+	codes := make([]string, 10)
+	for i := range codes {
+		codes[i] = fmt.Sprintf("recovery-%d-%d", time.Now().UnixNano(), i)
 	}
+	m.recoveryCodes[userID] = append([]string(nil), codes...)
+	return codes
+}
+
+func (m *MockRepository) UseTOTP(ctx context.Context, userID int32, counter int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	delete(m.notes, noteID)
+	userTOTP, ok := m.totps[userID]
+	if !ok || userTOTP.EnabledAt == nil || counter <= userTOTP.lastCounter {
+		return repository.ErrInvalidToken
+	}
+	userTOTP.lastCounter = counter
+	m.totps[userID] = userTOTP
 	return nil
 }
 
-func (m *MockRepository) ShareNote(ctx context.Context, noteShareDTO model.NoteShareDTO) error {
+func (m *MockRepository) UseRecoveryCode(ctx context.Context, userID int32, code string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	_, noteExists := m.notes[noteShareDTO.NoteID]
-	if !noteExists {
-		return errors.New("note not found")
+	codes := m.recoveryCodes[userID]
+	for i, recoveryCode := range codes {
+		if recoveryCode == code {
+			m.recoveryCodes[userID] = append(codes[:i:i], codes[i+1:]...)
+			return nil
+		}
 	}
+	return repository.ErrInvalidToken
+}
+
+func (m *MockRepository) RegenerateRecoveryCodes(ctx context.Context, userID int32) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.newRecoveryCodes(userID), nil
+}
+
+func (m *MockRepository) DisableTOTP(ctx context.Context, userID int32) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	m.sharedNotes[noteShareDTO.NoteID] = append(m.sharedNotes[noteShareDTO.NoteID], noteShareDTO.UserID)
+	if _, ok := m.totps[userID]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(m.totps, userID)
+	delete(m.recoveryCodes, userID)
 	return nil
 }
 
-func (m *MockRepository) SearchNotes(ctx context.Context, userID int32, query string) ([]model.Note, error) {
+func (m *MockRepository) CreateLoginChallenge(ctx context.Context, userID int32, ttl time.Duration) (string, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	var notes []model.Note
-	for _, note := range m.notes {
-		if note.UserID == userID && contains(note.Content, query) {
-			notes = append(notes, note)
+	return m.newUserToken(userID, "", "challenge"), nil
+}
+
+func (m *MockRepository) UseLoginChallenge(ctx context.Context, token string) (int32, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	challenge, ok := m.useUserToken(token, "challenge")
+	if !ok {
+		return 0, repository.ErrInvalidToken
+	}
+	return challenge.userID, nil
+}
+
+func (m *MockRepository) CreateSession(ctx context.Context, userID int32, ttl time.Duration) (*model.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.newSessionToken(userID, len(m.sessionTokens)), nil
+}
+
+func (m *MockRepository) newSessionToken(userID int32, family int) *model.Session {
+	n := strconv.Itoa(len(m.sessionTokens) + 1)
+	token := mockSessionToken{
+		Session: model.Session{
+			UserID:       userID,
+			TokenID:      "jti" + n,
+			RefreshToken: "refresh" + n,
+		},
+		family: family,
+	}
+	m.sessionTokens = append(m.sessionTokens, token)
+	return &token.Session
+}
+
+func (m *MockRepository) RefreshSession(ctx context.Context, refreshToken string, ttl time.Duration) (*model.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, token := range m.sessionTokens {
+		if token.RefreshToken != refreshToken {
+			continue
+		}
+		if token.revoked {
+			return nil, repository.ErrInvalidToken
 		}
+		if token.used {
+			m.revokeSessionTokens(func(t mockSessionToken) bool { return t.family == token.family })
+			return nil, repository.ErrInvalidToken
+		}
+		m.sessionTokens[i].used = true
+		return m.newSessionToken(token.UserID, token.family), nil
 	}
-	return notes, nil
+	return nil, repository.ErrInvalidToken
 }
 
-// contains checks if the text contains the query (case-insensitive)
-func contains(text, query string) bool {
-	return strings.Contains(strings.ToLower(text), strings.ToLower(query))
+func (m *MockRepository) RevokeSession(ctx context.Context, userID int32, tokenID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, token := range m.sessionTokens {
+		if token.TokenID == tokenID && token.UserID == userID {
+			m.revokeSessionTokens(func(t mockSessionToken) bool { return t.family == token.family })
+		}
+	}
+	return nil
 }
 
-func (m *MockRepository) isNoteSharedWithUser(noteID, userID int32) bool {
-	for _, id := range m.sharedNotes[noteID] {
-		if id == userID {
-			return true
+func (m *MockRepository) RevokeAllSessions(ctx context.Context, userID int32) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.revokeSessionTokens(func(t mockSessionToken) bool { return t.UserID == userID })
+	return nil
+}
+
+func (m *MockRepository) RevokeOtherSessions(ctx context.Context, userID int32, tokenID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	family := -1
+	for _, token := range m.sessionTokens {
+		if token.TokenID == tokenID {
+			family = token.family
 		}
 	}
-	return false
+
+	m.revokeSessionTokens(func(t mockSessionToken) bool { return t.UserID == userID && t.family != family })
+	return nil
+}
+
+func (m *MockRepository) revokeSessionTokens(match func(mockSessionToken) bool) {
+	for i, token := range m.sessionTokens {
+		if match(token) {
+			m.sessionTokens[i].revoked = true
+		}
+	}
+}
+
+func (m *MockRepository) IsTokenActive(ctx context.Context, tokenID string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, token := range m.sessionTokens {
+		if token.TokenID == tokenID {
+			return !token.revoked && m.users[token.UserID].DisabledAt == nil, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *MockRepository) PurgeExpiredSessions(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+func (m *MockRepository) LoginLockedUntil(ctx context.Context, email, ip string) (*time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var lockedUntil *time.Time
+	for _, key := range []string{"account|" + strings.ToLower(email), "ip|" + ip} {
+		if until := m.loginFailures[key].lockedUntil; until.After(time.Now()) && (lockedUntil == nil || until.After(*lockedUntil)) {
+			lockedUntil = &until
+		}
+	}
+	return lockedUntil, nil
+}
+
+func (m *MockRepository) RecordLoginFailure(ctx context.Context, email, ip string, lockout model.LoginLockout) (*time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var lockedUntil *time.Time
+	for key, threshold := range map[string]int{"account|" + strings.ToLower(email): lockout.AccountThreshold, "ip|" + ip: lockout.IPThreshold} {
+		if threshold <= 0 {
+			continue
+		}
+
+		failures := m.loginFailures[key]
+		if time.Since(failures.lastFailureAt) > lockout.Window {
+			failures.failures = 0
+		}
+		failures.failures++
+		failures.lastFailureAt = time.Now()
+
+		if failures.failures >= threshold {
+			delay := lockout.Delay
+			for i := threshold; i < failures.failures && delay < lockout.MaxDelay; i++ {
+				delay *= 2
+			}
+			failures.lockedUntil = time.Now().Add(min(delay, lockout.MaxDelay))
+			if lockedUntil == nil || failures.lockedUntil.After(*lockedUntil) {
+				lockedUntil = &failures.lockedUntil
+			}
+		}
+		m.loginFailures[key] = failures
+	}
+	return lockedUntil, nil
+}
+
+func (m *MockRepository) ClearLoginFailures(ctx context.Context, email, ip string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.loginFailures, "account|"+strings.ToLower(email))
+	delete(m.loginFailures, "ip|"+ip)
+	return nil
+}
+
+func (m *MockRepository) PurgeLoginFailures(ctx context.Context, window time.Duration) (int64, error) {
+	return 0, nil
+}
+
+func (m *MockRepository) CreateAccessToken(ctx context.Context, tokenDTO model.PersonalAccessTokenDTO) (*model.PersonalAccessToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := int32(len(m.accessTokens) + 1)
+	token := model.PersonalAccessToken{
+		ID:        id,
+		UserID:    tokenDTO.UserID,
+		Name:      tokenDTO.Name,
+		Scopes:    tokenDTO.Scopes,
+		ExpiresAt: tokenDTO.ExpiresAt,
+		CreatedAt: time.Now(),
+		Token:     model.AccessTokenPrefix + strconv.Itoa(int(id)),
+	}
+	m.accessTokens[id] = token
+	return &token, nil
+}
+
+func (m *MockRepository) ListAccessTokens(ctx context.Context, userID int32) ([]model.PersonalAccessToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tokens := []model.PersonalAccessToken{}
+	for _, token := range m.accessTokens {
+		if token.UserID == userID {
+			token.Token = ""
+			tokens = append(tokens, token)
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].ID > tokens[j].ID })
+	return tokens, nil
+}
+
+func (m *MockRepository) RevokeAccessToken(ctx context.Context, userID, tokenID int32) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	token, ok := m.accessTokens[tokenID]
+	if !ok || token.UserID != userID {
+		return repository.ErrNotFound
+	}
+	delete(m.accessTokens, tokenID)
+	return nil
+}
+
+func (m *MockRepository) UseAccessToken(ctx context.Context, tokenString string) (*model.PersonalAccessToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, token := range m.accessTokens {
+		if token.Token == tokenString && (token.ExpiresAt == nil || token.ExpiresAt.After(time.Now())) &&
+			m.users[token.UserID].DisabledAt == nil {
+			now := time.Now()
+			token.LastUsedAt = &now
+			m.accessTokens[id] = token
+			return &token, nil
+		}
+	}
+	return nil, repository.ErrInvalidToken
+}
+
+// setUserRole changes the role of a user, which has no endpoint
+func (m *MockRepository) setUserRole(userID int32, role string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user := m.users[userID]
+	user.Role = role
+	m.users[userID] = user
+}
+
+// setAuditFailure makes writing audit entries fail, or work again
+func (m *MockRepository) setAuditFailure(fail bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.failAudit = fail
+}
+
+func (m *MockRepository) userWithUsage(user model.User) model.UserWithUsage {
+	usage := model.UserWithUsage{User: user}
+	for _, note := range m.notes {
+		if note.UserID != user.ID {
+			continue
+		}
+		if note.DeletedAt == nil {
+			usage.NoteCount++
+		}
+		usage.StorageBytes += int64(len(note.Title) + len(note.Content))
+		for _, revision := range m.revisions[note.ID] {
+			usage.StorageBytes += int64(len(revision.Title) + len(revision.Content))
+		}
+	}
+	return usage
+}
+
+func (m *MockRepository) ListUsers(ctx context.Context, search string, page model.AdminPage) (*model.UserList, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var afterID int64
+	if page.Cursor != "" {
+		var err error
+		afterID, err = strconv.ParseInt(page.Cursor, 10, 32)
+		if err != nil {
+			return nil, repository.ErrInvalidCursor
+		}
+	}
+
+	search = strings.ToLower(search)
+	list := &model.UserList{Users: []model.UserWithUsage{}}
+	for _, user := range m.users {
+		if int64(user.ID) <= afterID {
+			continue
+		}
+		if !strings.Contains(strings.ToLower(user.Username), search) && !strings.Contains(strings.ToLower(user.Email), search) {
+			continue
+		}
+		list.Users = append(list.Users, m.userWithUsage(user))
+	}
+	sort.Slice(list.Users, func(i, j int) bool { return list.Users[i].ID < list.Users[j].ID })
+
+	if len(list.Users) > int(page.Limit) {
+		list.Users = list.Users[:page.Limit]
+		list.NextCursor = strconv.Itoa(int(list.Users[len(list.Users)-1].ID))
+	}
+	return list, nil
+}
+
+func (m *MockRepository) GetUserWithUsage(ctx context.Context, userID int32) (*model.UserWithUsage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[userID]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	usage := m.userWithUsage(user)
+	return &usage, nil
+}
+
+func (m *MockRepository) SetUserDisabled(ctx context.Context, userID int32, disabled bool) (*model.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[userID]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	if !disabled {
+		user.DisabledAt = nil
+	} else if user.DisabledAt == nil {
+		now := time.Now()
+		user.DisabledAt = &now
+		m.revokeSessionTokens(func(t mockSessionToken) bool { return t.UserID == userID })
+	}
+	m.users[userID] = user
+	return &user, nil
+}
+
+func (m *MockRepository) ForcePasswordReset(ctx context.Context, userID int32, ttl time.Duration) (*model.User, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[userID]
+	if !ok {
+		return nil, "", repository.ErrNotFound
+	}
+	user.PasswordHash = ""
+	m.users[userID] = user
+
+	m.revokeSessionTokens(func(t mockSessionToken) bool { return t.UserID == userID })
+	return &user, m.newUserToken(userID, user.Email, "reset"), nil
+}
+
+func (m *MockRepository) CreateAuditEntry(ctx context.Context, entry model.AuditEntryDTO) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.failAudit {
+		return fmt.Errorf("audit log unavailable")
+	}
+
+	actorID := entry.ActorID
+	m.auditLog = append(m.auditLog, model.AuditEntry{
+		ID:           int32(len(m.auditLog) + 1),
+		ActorID:      &actorID,
+		Action:       entry.Action,
+		TargetUserID: entry.TargetUserID,
+		Details:      entry.Details,
+		IP:           entry.IP,
+		Status:       entry.Status,
+		CreatedAt:    time.Now(),
+	})
+	return nil
+}
+
+func (m *MockRepository) ListAuditEntries(ctx context.Context, filter model.AuditFilter, page model.AdminPage) (*model.AuditLog, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	beforeID := int64(len(m.auditLog) + 1)
+	if page.Cursor != "" {
+		var err error
+		beforeID, err = strconv.ParseInt(page.Cursor, 10, 32)
+		if err != nil {
+			return nil, repository.ErrInvalidCursor
+		}
+	}
+
+	matches := func(want, got *int32) bool {
+		return want == nil || (got != nil && *got == *want)
+	}
+	log := &model.AuditLog{Entries: []model.AuditEntry{}}
+	for i := len(m.auditLog) - 1; i >= 0; i-- {
+		entry := m.auditLog[i]
+		if int64(entry.ID) >= beforeID || !matches(filter.ActorID, entry.ActorID) || !matches(filter.TargetUserID, entry.TargetUserID) {
+			continue
+		}
+		log.Entries = append(log.Entries, entry)
+	}
+
+	if len(log.Entries) > int(page.Limit) {
+		log.Entries = log.Entries[:page.Limit]
+		log.NextCursor = strconv.Itoa(int(log.Entries[len(log.Entries)-1].ID))
+	}
+	return log, nil
+}
+
+func (m *MockRepository) CreateNote(ctx context.Context, noteDTO model.NoteDTO) (*model.Note, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if noteDTO.NotebookID != nil {
+		if notebook, ok := m.notebooks[*noteDTO.NotebookID]; !ok || notebook.UserID != noteDTO.UserID {
+			return nil, repository.ErrNotFound
+		}
+	}
+
+	newNote := model.Note{
+		ID:         int32(len(m.notes) + 1),
+		UserID:     noteDTO.UserID,
+		Title:      noteDTO.Title,
+		Content:    noteDTO.Content,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+		Tags:       uniqueTags(noteDTO.Tags),
+		NotebookID: noteDTO.NotebookID,
+		Language:   noteDTO.Language,
+	}
+	if newNote.Language == "" {
+		newNote.Language = m.users[noteDTO.UserID].SearchLanguage
+	}
+
+	m.notes[newNote.ID] = newNote
+	return &newNote, nil
+}
+
+func (m *MockRepository) ListNotesByUserID(ctx context.Context, userID int32, filter model.NoteFilter, page model.NotePage) (*model.NoteList, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var notes []model.Note
+	for _, note := range m.notes {
+		if note.UserID == userID && note.DeletedAt == nil && matchesTags(note.Tags, filter) && m.matchesNotebook(note, filter) {
+			notes = append(notes, note)
+		}
+	}
+	return pageNotes(notes, page, nil)
+}
+
+func (m *MockRepository) GetNoteByUserID(ctx context.Context, noteID, userID int32) (*model.Note, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	note, ok := m.notes[noteID]
+	if !ok || note.DeletedAt != nil || (note.UserID != userID && !m.isNoteSharedWithUser(noteID, userID)) {
+		return nil, repository.ErrNotFound
+	}
+	return &note, nil
+}
+
+func (m *MockRepository) UpdateNote(ctx context.Context, noteID int32, noteDTO model.NoteDTO) (*model.Note, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.updateNote(noteID, noteDTO)
+}
+
+func (m *MockRepository) updateNote(noteID int32, noteDTO model.NoteDTO) (*model.Note, error) {
+	note, ok := m.notes[noteID]
+	if !ok || note.DeletedAt != nil || (note.UserID != noteDTO.UserID && !m.isNoteSharedWithUser(noteID, noteDTO.UserID)) {
+		return nil, repository.ErrNotFound
+	}
+	if note.UserID != noteDTO.UserID && m.sharedNotes[noteID][noteDTO.UserID] != model.RoleEditor {
+		return nil, repository.ErrForbidden
+	}
+
+	m.revisions[noteID] = append(m.revisions[noteID], model.NoteRevision{
+		NoteID:    noteID,
+		Revision:  int32(len(m.revisions[noteID]) + 1),
+		Title:     note.Title,
+		Content:   note.Content,
+		CreatedAt: note.UpdatedAt,
+	})
+
+	updatedNote := model.Note{
+		ID:         noteID,
+		UserID:     note.UserID,
+		Title:      noteDTO.Title,
+		Content:    noteDTO.Content,
+		CreatedAt:  note.CreatedAt,
+		UpdatedAt:  time.Now(),
+		Tags:       note.Tags,
+		NotebookID: note.NotebookID,
+		Language:   note.Language,
+	}
+	if noteDTO.Language != "" {
+		updatedNote.Language = noteDTO.Language
+	}
+	if noteDTO.Tags != nil {
+		updatedNote.Tags = uniqueTags(noteDTO.Tags)
+	}
+
+	m.notes[noteID] = updatedNote
+	return &updatedNote, nil
+}
+
+func (m *MockRepository) DeleteNote(ctx context.Context, noteID, userID int32) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	note, ok := m.notes[noteID]
+	if !ok || note.DeletedAt != nil || note.UserID != userID {
+		return repository.ErrNotFound
+	}
+
+	now := time.Now()
+	note.DeletedAt = &now
+	m.notes[noteID] = note
+	return nil
+}
+
+func (m *MockRepository) ListDeletedNotes(ctx context.Context, userID int32) ([]model.Note, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var notes []model.Note
+	for _, note := range m.notes {
+		if note.UserID == userID && note.DeletedAt != nil {
+			notes = append(notes, note)
+		}
+	}
+	return notes, nil
+}
+
+func (m *MockRepository) RestoreNote(ctx context.Context, noteID, userID int32) (*model.Note, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	note, ok := m.notes[noteID]
+	if !ok || note.DeletedAt == nil || note.UserID != userID {
+		return nil, repository.ErrNotFound
+	}
+
+	note.DeletedAt = nil
+	m.notes[noteID] = note
+	return &note, nil
+}
+
+func (m *MockRepository) MoveNote(ctx context.Context, noteID, userID int32, notebookID *int32) (*model.Note, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	note, ok := m.notes[noteID]
+	if !ok || note.DeletedAt != nil || note.UserID != userID {
+		return nil, repository.ErrNotFound
+	}
+	if notebookID != nil {
+		if notebook, ok := m.notebooks[*notebookID]; !ok || notebook.UserID != userID {
+			return nil, repository.ErrNotFound
+		}
+	}
+
+	note.NotebookID = notebookID
+	m.notes[noteID] = note
+	return &note, nil
+}
+
+func (m *MockRepository) PurgeDeletedNotes(ctx context.Context, retention time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var purged int64
+	for id, note := range m.notes {
+		if note.DeletedAt != nil && time.Since(*note.DeletedAt) > retention {
+			delete(m.notes, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+func (m *MockRepository) ShareNote(ctx context.Context, noteShareDTO model.NoteShareDTO) (*model.ShareInvitation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	note, noteExists := m.notes[noteShareDTO.NoteID]
+	if !noteExists || note.DeletedAt != nil || note.UserID != noteShareDTO.UserID {
+		return nil, repository.ErrNotFound
+	}
+
+	for _, user := range m.users {
+		if user.Email == noteShareDTO.SharedWith && (!m.RequireVerifiedEmail || user.EmailVerifiedAt != nil) {
+			if m.sharedNotes[note.ID] == nil {
+				m.sharedNotes[note.ID] = make(map[int32]string)
+			}
+			m.sharedNotes[note.ID][user.ID] = noteShareDTO.Role
+			return nil, nil
+		}
+	}
+
+	invitation := model.ShareInvitation{
+		ID:        int32(len(m.invitations) + 1),
+		NoteID:    note.ID,
+		Email:     noteShareDTO.SharedWith,
+		Role:      noteShareDTO.Role,
+		CreatedAt: time.Now(),
+	}
+	for _, existing := range m.invitations {
+		if existing.NoteID == note.ID && existing.Email == invitation.Email {
+			invitation.ID = existing.ID
+		}
+	}
+	m.invitations[invitation.ID] = invitation
+	return &invitation, nil
+}
+
+func (m *MockRepository) ListShareInvitations(ctx context.Context, noteID, userID int32) ([]model.ShareInvitation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	invitations := []model.ShareInvitation{}
+	if note, ok := m.notes[noteID]; !ok || note.UserID != userID {
+		return invitations, nil
+	}
+	for _, invitation := range m.invitations {
+		if invitation.NoteID == noteID {
+			invitations = append(invitations, invitation)
+		}
+	}
+	return invitations, nil
+}
+
+func (m *MockRepository) CancelShareInvitation(ctx context.Context, noteID, userID, invitationID int32) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	invitation, ok := m.invitations[invitationID]
+	if !ok || invitation.NoteID != noteID || m.notes[noteID].UserID != userID {
+		return repository.ErrNotFound
+	}
+
+	delete(m.invitations, invitationID)
+	return nil
+}
+
+func (m *MockRepository) ListNoteShares(ctx context.Context, noteID, userID int32) ([]model.NoteShare, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	note, ok := m.notes[noteID]
+	if !ok || note.DeletedAt != nil || (note.UserID != userID && !m.isNoteSharedWithUser(noteID, userID)) {
+		return nil, repository.ErrNotFound
+	}
+
+	shares := []model.NoteShare{}
+	for sharedWithUserID, role := range m.sharedNotes[noteID] {
+		user := m.users[sharedWithUserID]
+		shares = append(shares, model.NoteShare{
+			UserID:   user.ID,
+			Username: user.Username,
+			Email:    user.Email,
+			Role:     role,
+		})
+	}
+	sort.Slice(shares, func(i, j int) bool { return shares[i].Username < shares[j].Username })
+	return shares, nil
+}
+
+func (m *MockRepository) RevokeNoteShare(ctx context.Context, noteID, userID, sharedWithUserID int32) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	note, ok := m.notes[noteID]
+	if !ok || note.UserID != userID || !m.isNoteSharedWithUser(noteID, sharedWithUserID) {
+		return repository.ErrNotFound
+	}
+
+	delete(m.sharedNotes[noteID], sharedWithUserID)
+	return nil
+}
+
+func (m *MockRepository) LeaveNote(ctx context.Context, noteID, userID int32) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.isNoteSharedWithUser(noteID, userID) {
+		return repository.ErrNotFound
+	}
+
+	delete(m.sharedNotes[noteID], userID)
+	return nil
+}
+
+func (m *MockRepository) SearchNotes(ctx context.Context, userID int32, search model.NoteSearch, page model.NotePage) (*model.SearchResultList, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	matches := searchquery.Matches(search.Expr)
+	notes, scores := m.searchNotes(userID, search)
+
+	list, err := pageNotes(notes, page, scores)
+	if err != nil {
+		return nil, err
+	}
+	results := &model.SearchResultList{Notes: []model.SearchResult{}, NextCursor: list.NextCursor}
+	for _, note := range list.Notes {
+		result := model.SearchResult{Note: note}
+		if search.Mode == model.SearchModeFuzzy {
+			result.Score = scores[note.ID]
+		} else {
+			result.Rank = scores[note.ID]
+			result.Snippets = &model.SearchSnippets{
+				Title:   highlight(note.Title, matches, search),
+				Content: highlight(note.Content, matches, search),
+			}
+		}
+		results.Notes = append(results.Notes, result)
+	}
+	return results, nil
+}
+
+func (m *MockRepository) CountSearchNotes(ctx context.Context, userID int32, search model.NoteSearch, since *time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	notes, _ := m.searchNotes(userID, search)
+	var count int64
+	for _, note := range notes {
+		if since == nil || note.UpdatedAt.After(*since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// searchNotes returns the notes visible to the user matching the search,
+// and their score or rank
+func (m *MockRepository) searchNotes(userID int32, search model.NoteSearch) ([]model.Note, map[int32]float64) {
+	matches := searchquery.Matches(search.Expr)
+
+	var notes []model.Note
+	scores := make(map[int32]float64)
+	for _, note := range m.notes {
+		if (note.UserID != userID && !m.isNoteSharedWithUser(note.ID, userID)) || note.DeletedAt != nil {
+			continue
+		}
+		if search.Mode == model.SearchModeFuzzy {
+			score := max(wordSimilarity(search.Query, note.Title), wordSimilarity(search.Query, note.Content))
+			if score == 0 || score < search.Threshold {
+				continue
+			}
+			scores[note.ID] = score
+		} else {
+			if (search.Language != "" && note.Language != search.Language) || !m.matchesSearch(search.Expr, note, userID) {
+				continue
+			}
+			scores[note.ID] = fullTextRank(matches, note)
+		}
+		notes = append(notes, note)
+	}
+	return notes, scores
+}
+
+// matchesSearch evaluates a parsed search query on a note, words matching
+// as substrings instead of by their stems
+func (m *MockRepository) matchesSearch(node searchquery.Node, note model.Note, userID int32) bool {
+	switch node := node.(type) {
+	case nil:
+		return true
+	case searchquery.And:
+		for _, n := range node {
+			if !m.matchesSearch(n, note, userID) {
+				return false
+			}
+		}
+		return true
+	case searchquery.Or:
+		for _, n := range node {
+			if m.matchesSearch(n, note, userID) {
+				return true
+			}
+		}
+		return false
+	case searchquery.Not:
+		return !m.matchesSearch(node.Node, note, userID)
+	case searchquery.Match:
+		text := note.Title + " " + note.Content
+		if node.TitleOnly {
+			text = note.Title
+		}
+		if node.Phrase {
+			return contains(text, node.Value)
+		}
+		for _, word := range strings.Fields(node.Value) {
+			if !contains(text, word) {
+				return false
+			}
+		}
+		return true
+	case searchquery.Tag:
+		return slices.Contains(note.Tags, string(node))
+	case searchquery.Shared:
+		return (note.UserID != userID || len(m.sharedNotes[note.ID]) > 0) == bool(node)
+	case searchquery.Owner:
+		if node == searchquery.OwnerMe {
+			return note.UserID == userID
+		}
+		return m.users[note.UserID].Email == string(node)
+	case searchquery.Date:
+		date := note.CreatedAt
+		if node.Field == searchquery.UpdatedAt {
+			date = note.UpdatedAt
+		}
+		return date.Before(node.Time) == node.Before
+	}
+	return false
+}
+
+// matchWords returns the words notes are ranked and highlighted by
+func matchWords(matches []searchquery.Match) []string {
+	var words []string
+	for _, match := range matches {
+		words = append(words, strings.Fields(match.Value)...)
+	}
+	return words
+}
+
+// fullTextRank approximates ts_rank_cd, words in the title weighing more
+// than in the content
+func fullTextRank(matches []searchquery.Match, note model.Note) float64 {
+	words := matchWords(matches)
+	if len(words) == 0 {
+		return 0
+	}
+
+	rank := 0.0
+	for _, word := range words {
+		if contains(note.Title, word) {
+			rank += 1
+		}
+		if contains(note.Content, word) {
+			rank += 0.4
+		}
+	}
+	return rank / float64(len(words))
+}
+
+// highlight surrounds the matching words in the text with the markers of
+// the search
+func highlight(text string, matches []searchquery.Match, search model.NoteSearch) string {
+	text = html.EscapeString(text)
+	for _, word := range matchWords(matches) {
+		re := regexp.MustCompile("(?i)" + regexp.QuoteMeta(word))
+		text = re.ReplaceAllStringFunc(text, func(match string) string {
+			return search.HighlightStart + match + search.HighlightStop
+		})
+	}
+	return text
+}
+
+// trigrams returns the trigrams of the words of the text, like pg_trgm
+func trigrams(text string) map[string]bool {
+	set := make(map[string]bool)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			set[string(padded[i:i+3])] = true
+		}
+	}
+	return set
+}
+
+// wordSimilarity approximates the pg_trgm function, as the share of the
+// trigrams of the query found in the text
+func wordSimilarity(query, text string) float64 {
+	queryTrigrams := trigrams(query)
+	if len(queryTrigrams) == 0 {
+		return 0
+	}
+	textTrigrams := trigrams(text)
+
+	common := 0
+	for trigram := range queryTrigrams {
+		if textTrigrams[trigram] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(queryTrigrams))
+}
+
+// Helper function to sort notes and cut out a page, the mock cursor is the
+// offset of the page. Search results can be sorted by their scores.
+func pageNotes(notes []model.Note, page model.NotePage, scores map[int32]float64) (*model.NoteList, error) {
+	less := func(a, b model.Note) bool {
+		switch page.SortBy {
+		case model.SortByRelevance:
+			if scores[a.ID] != scores[b.ID] {
+				return scores[a.ID] < scores[b.ID]
+			}
+		case model.SortByUpdatedAt:
+			if !a.UpdatedAt.Equal(b.UpdatedAt) {
+				return a.UpdatedAt.Before(b.UpdatedAt)
+			}
+		case model.SortByTitle:
+			if a.Title != b.Title {
+				return a.Title < b.Title
+			}
+		default:
+			if !a.CreatedAt.Equal(b.CreatedAt) {
+				return a.CreatedAt.Before(b.CreatedAt)
+			}
+		}
+		return a.ID < b.ID
+	}
+	sort.Slice(notes, func(i, j int) bool {
+		if page.Desc {
+			return less(notes[j], notes[i])
+		}
+		return less(notes[i], notes[j])
+	})
+
+	offset := 0
+	if page.Cursor != "" {
+		var err error
+		if offset, err = strconv.Atoi(page.Cursor); err != nil || offset > len(notes) {
+			return nil, repository.ErrInvalidCursor
+		}
+	}
+
+	list := &model.NoteList{Notes: notes[offset:]}
+	if len(list.Notes) > int(page.Limit) {
+		list.Notes = list.Notes[:page.Limit]
+		list.NextCursor = strconv.Itoa(offset + int(page.Limit))
+	}
+	if list.Notes == nil {
+		list.Notes = []model.Note{}
+	}
+	return list, nil
+}
+
+func (m *MockRepository) ListNoteRevisions(ctx context.Context, noteID, userID int32) ([]model.NoteRevision, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	note, ok := m.notes[noteID]
+	if !ok || (note.UserID != userID && !m.isNoteSharedWithUser(noteID, userID)) {
+		return nil, repository.ErrNotFound
+	}
+
+	revisions := make([]model.NoteRevision, 0, len(m.revisions[noteID]))
+	for i := len(m.revisions[noteID]) - 1; i >= 0; i-- {
+		revisions = append(revisions, m.revisions[noteID][i])
+	}
+	return revisions, nil
+}
+
+func (m *MockRepository) GetNoteRevision(ctx context.Context, noteID, userID, revision int32) (*model.NoteRevision, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.getNoteRevision(noteID, userID, revision)
+}
+
+func (m *MockRepository) getNoteRevision(noteID, userID, revision int32) (*model.NoteRevision, error) {
+	note, ok := m.notes[noteID]
+	if !ok || (note.UserID != userID && !m.isNoteSharedWithUser(noteID, userID)) {
+		return nil, repository.ErrNotFound
+	}
+
+	for _, noteRevision := range m.revisions[noteID] {
+		if noteRevision.Revision == revision {
+			return &noteRevision, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (m *MockRepository) RestoreNoteRevision(ctx context.Context, noteID, userID, revision int32) (*model.Note, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	noteRevision, err := m.getNoteRevision(noteID, userID, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.updateNote(noteID, model.NoteDTO{
+		UserID:  userID,
+		Title:   noteRevision.Title,
+		Content: noteRevision.Content,
+	})
+}
+
+func (m *MockRepository) ListTags(ctx context.Context, userID int32) ([]model.Tag, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	counts := make(map[string]int64)
+	for _, note := range m.notes {
+		if note.UserID != userID {
+			continue
+		}
+		for _, tag := range note.Tags {
+			// tags of notes in the trash are listed without counting them
+			if _, ok := counts[tag]; !ok {
+				counts[tag] = 0
+			}
+			if note.DeletedAt == nil {
+				counts[tag]++
+			}
+		}
+	}
+
+	tags := make([]model.Tag, 0, len(counts))
+	for name, count := range counts {
+		tags = append(tags, model.Tag{Name: name, NoteCount: count})
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+	return tags, nil
+}
+
+func (m *MockRepository) RenameTag(ctx context.Context, userID int32, from, to string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	found := false
+	for id, note := range m.notes {
+		if note.UserID != userID || !slices.Contains(note.Tags, from) {
+			continue
+		}
+		found = true
+		tags := make([]string, 0, len(note.Tags))
+		for _, tag := range note.Tags {
+			if tag == from {
+				tag = to
+			}
+			tags = append(tags, tag)
+		}
+		note.Tags = uniqueTags(tags)
+		m.notes[id] = note
+	}
+	if !found {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (m *MockRepository) CreateNotebook(ctx context.Context, notebookDTO model.NotebookDTO) (*model.Notebook, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if notebookDTO.ParentID != nil {
+		if parent, ok := m.notebooks[*notebookDTO.ParentID]; !ok || parent.UserID != notebookDTO.UserID {
+			return nil, repository.ErrInvalidParent
+		}
+	}
+
+	notebook := model.Notebook{
+		ID:        int32(len(m.notebooks) + 1),
+		UserID:    notebookDTO.UserID,
+		ParentID:  notebookDTO.ParentID,
+		Name:      notebookDTO.Name,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	m.notebooks[notebook.ID] = notebook
+	return &notebook, nil
+}
+
+func (m *MockRepository) ListNotebooks(ctx context.Context, userID int32) ([]model.Notebook, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	notebooks := []model.Notebook{}
+	for _, notebook := range m.notebooks {
+		if m.canAccessNotebook(notebook.ID, userID) {
+			notebooks = append(notebooks, notebook)
+		}
+	}
+	return notebooks, nil
+}
+
+func (m *MockRepository) GetNotebook(ctx context.Context, notebookID, userID int32) (*model.Notebook, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	notebook, ok := m.notebooks[notebookID]
+	if !ok || !m.canAccessNotebook(notebookID, userID) {
+		return nil, repository.ErrNotFound
+	}
+	return &notebook, nil
+}
+
+func (m *MockRepository) UpdateNotebook(ctx context.Context, notebookID int32, notebookDTO model.NotebookDTO) (*model.Notebook, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	notebook, ok := m.notebooks[notebookID]
+	if !ok || notebook.UserID != notebookDTO.UserID {
+		return nil, repository.ErrNotFound
+	}
+	if notebookDTO.ParentID != nil {
+		parent, ok := m.notebooks[*notebookDTO.ParentID]
+		if !ok || parent.UserID != notebookDTO.UserID || m.isInNotebook(parent.ID, notebookID) {
+			return nil, repository.ErrInvalidParent
+		}
+	}
+
+	notebook.Name = notebookDTO.Name
+	notebook.ParentID = notebookDTO.ParentID
+	notebook.UpdatedAt = time.Now()
+	m.notebooks[notebookID] = notebook
+	return &notebook, nil
+}
+
+func (m *MockRepository) DeleteNotebook(ctx context.Context, notebookID, userID int32) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	notebook, ok := m.notebooks[notebookID]
+	if !ok || notebook.UserID != userID {
+		return repository.ErrNotFound
+	}
+
+	deleted := make(map[int32]bool)
+	for id := range m.notebooks {
+		if m.isInNotebook(id, notebookID) {
+			deleted[id] = true
+		}
+	}
+	for id := range deleted {
+		delete(m.notebooks, id)
+	}
+	for id, note := range m.notes {
+		if note.NotebookID != nil && deleted[*note.NotebookID] {
+			note.NotebookID = nil
+			m.notes[id] = note
+		}
+	}
+	return nil
+}
+
+func (m *MockRepository) ShareNotebook(ctx context.Context, share model.NotebookShareDTO) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	notebook, ok := m.notebooks[share.NotebookID]
+	if !ok || notebook.UserID != share.UserID {
+		return repository.ErrNotFound
+	}
+
+	for _, user := range m.users {
+		if user.Email == share.SharedWith && (!m.RequireVerifiedEmail || user.EmailVerifiedAt != nil) {
+			if slices.Contains(m.sharedNotebooks[share.NotebookID], user.ID) {
+				return repository.ErrAlreadyExists
+			}
+			m.sharedNotebooks[share.NotebookID] = append(m.sharedNotebooks[share.NotebookID], user.ID)
+			return nil
+		}
+	}
+	return repository.ErrNotFound
+}
+
+func (m *MockRepository) ListNotebookShares(ctx context.Context, notebookID, userID int32) ([]model.NotebookShare, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.notebooks[notebookID]; !ok || !m.canAccessNotebook(notebookID, userID) {
+		return nil, repository.ErrNotFound
+	}
+
+	shares := []model.NotebookShare{}
+	for _, sharedWithUserID := range m.sharedNotebooks[notebookID] {
+		user := m.users[sharedWithUserID]
+		shares = append(shares, model.NotebookShare{
+			UserID:   user.ID,
+			Username: user.Username,
+			Email:    user.Email,
+		})
+	}
+	sort.Slice(shares, func(i, j int) bool { return shares[i].Username < shares[j].Username })
+	return shares, nil
+}
+
+func (m *MockRepository) RevokeNotebookShare(ctx context.Context, notebookID, userID, sharedWithUserID int32) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	notebook, ok := m.notebooks[notebookID]
+	if !ok || notebook.UserID != userID || !slices.Contains(m.sharedNotebooks[notebookID], sharedWithUserID) {
+		return repository.ErrNotFound
+	}
+
+	m.sharedNotebooks[notebookID] = slices.DeleteFunc(m.sharedNotebooks[notebookID], func(id int32) bool { return id == sharedWithUserID })
+	return nil
+}
+
+func (m *MockRepository) LeaveNotebook(ctx context.Context, notebookID, userID int32) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !slices.Contains(m.sharedNotebooks[notebookID], userID) {
+		return repository.ErrNotFound
+	}
+
+	m.sharedNotebooks[notebookID] = slices.DeleteFunc(m.sharedNotebooks[notebookID], func(id int32) bool { return id == userID })
+	return nil
+}
+
+// isInNotebook reports whether the notebook is the ancestor or one of its descendants
+func (m *MockRepository) isInNotebook(notebookID, ancestorID int32) bool {
+	for {
+		if notebookID == ancestorID {
+			return true
+		}
+		notebook, ok := m.notebooks[notebookID]
+		if !ok || notebook.ParentID == nil {
+			return false
+		}
+		notebookID = *notebook.ParentID
+	}
+}
+
+func (m *MockRepository) canAccessNotebook(notebookID, userID int32) bool {
+	if notebook, ok := m.notebooks[notebookID]; ok && notebook.UserID == userID {
+		return true
+	}
+	for sharedID, userIDs := range m.sharedNotebooks {
+		if slices.Contains(userIDs, userID) && m.isInNotebook(notebookID, sharedID) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MockRepository) matchesNotebook(note model.Note, filter model.NoteFilter) bool {
+	if filter.NotebookID == nil {
+		return true
+	}
+	if note.NotebookID == nil {
+		return false
+	}
+	if filter.Recursive {
+		return m.isInNotebook(*note.NotebookID, *filter.NotebookID)
+	}
+	return *note.NotebookID == *filter.NotebookID
+}
+
+// uniqueTags returns the sorted set of tags, never nil
+func uniqueTags(tags []string) []string {
+	unique := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if !slices.Contains(unique, tag) {
+			unique = append(unique, tag)
+		}
+	}
+	sort.Strings(unique)
+	return unique
+}
+
+func matchesTags(tags []string, filter model.NoteFilter) bool {
+	if len(filter.Tags) == 0 {
+		return true
+	}
+	for _, tag := range filter.Tags {
+		if slices.Contains(tags, tag) == filter.MatchAny {
+			return filter.MatchAny
+		}
+	}
+	return !filter.MatchAny
+}
+
+// contains checks if the text contains the query (case-insensitive)
+func contains(text, query string) bool {
+	return strings.Contains(strings.ToLower(text), strings.ToLower(query))
+}
+
+func (m *MockRepository) isNoteSharedWithUser(noteID, userID int32) bool {
+	_, ok := m.sharedNotes[noteID][userID]
+	return ok
+}
+
+func (m *MockRepository) CreatePublicLink(ctx context.Context, linkDTO model.PublicLinkDTO) (*model.PublicLink, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	note, ok := m.notes[linkDTO.NoteID]
+	if !ok || note.DeletedAt != nil || note.UserID != linkDTO.UserID {
+		return nil, repository.ErrNotFound
+	}
+
+	link := model.PublicLink{
+		ID:          int32(len(m.publicLinks) + 1),
+		NoteID:      note.ID,
+		Token:       "token" + strconv.Itoa(len(m.publicLinks)+1),
+		HasPassword: linkDTO.Password != "",
+		ExpiresAt:   linkDTO.ExpiresAt,
+		CreatedAt:   time.Now(),
+	}
+
+	m.publicLinks[link.ID] = link
+	m.linkPasswords[link.ID] = linkDTO.Password
+	return &link, nil
+}
+
+func (m *MockRepository) ListPublicLinks(ctx context.Context, noteID, userID int32) ([]model.PublicLink, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	links := []model.PublicLink{}
+	if note, ok := m.notes[noteID]; !ok || note.UserID != userID {
+		return links, nil
+	}
+	for _, link := range m.publicLinks {
+		if link.NoteID == noteID {
+			links = append(links, link)
+		}
+	}
+	return links, nil
+}
+
+func (m *MockRepository) RevokePublicLink(ctx context.Context, noteID, userID, linkID int32) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	link, ok := m.publicLinks[linkID]
+	if !ok || link.NoteID != noteID || m.notes[noteID].UserID != userID {
+		return repository.ErrNotFound
+	}
+
+	delete(m.publicLinks, linkID)
+	return nil
+}
+
+func (m *MockRepository) GetPublicNote(ctx context.Context, token, password string) (*model.PublicNote, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, link := range m.publicLinks {
+		if link.Token != token || (link.ExpiresAt != nil && !link.ExpiresAt.After(time.Now())) {
+			continue
+		}
+		if m.linkPasswords[link.ID] != password {
+			return nil, repository.ErrInvalidPassword
+		}
+
+		note, ok := m.notes[link.NoteID]
+		if !ok || note.DeletedAt != nil {
+			return nil, repository.ErrNotFound
+		}
+		return &model.PublicNote{
+			Title:     note.Title,
+			Content:   note.Content,
+			CreatedAt: note.CreatedAt,
+			UpdatedAt: note.UpdatedAt,
+		}, nil
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (m *MockRepository) CreateSavedSearch(ctx context.Context, searchDTO model.SavedSearchDTO) (*model.SavedSearch, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.savedSearchNameTaken(searchDTO, 0) {
+		return nil, repository.ErrAlreadyExists
+	}
+
+	m.lastSearchID++
+	search := model.SavedSearch{
+		ID:        m.lastSearchID,
+		UserID:    searchDTO.UserID,
+		Name:      searchDTO.Name,
+		Query:     searchDTO.Query,
+		Mode:      searchDTO.Mode,
+		Threshold: *searchDTO.Threshold,
+		Language:  searchDTO.Language,
+		CreatedAt: time.Now(),
+	}
+
+	m.savedSearches[search.ID] = search
+	return &search, nil
+}
+
+// savedSearchNameTaken reports whether another saved search of the user has
+// the name
+func (m *MockRepository) savedSearchNameTaken(searchDTO model.SavedSearchDTO, searchID int32) bool {
+	for _, other := range m.savedSearches {
+		if other.UserID == searchDTO.UserID && other.Name == searchDTO.Name && other.ID != searchID {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MockRepository) ListSavedSearches(ctx context.Context, userID int32) ([]model.SavedSearch, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	searches := []model.SavedSearch{}
+	for _, search := range m.savedSearches {
+		if search.UserID == userID {
+			searches = append(searches, search)
+		}
+	}
+	sort.Slice(searches, func(i, j int) bool {
+		return searches[i].Name < searches[j].Name
+	})
+	return searches, nil
+}
+
+func (m *MockRepository) GetSavedSearch(ctx context.Context, searchID, userID int32) (*model.SavedSearch, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	search, ok := m.savedSearches[searchID]
+	if !ok || search.UserID != userID {
+		return nil, repository.ErrNotFound
+	}
+	return &search, nil
+}
+
+func (m *MockRepository) UpdateSavedSearch(ctx context.Context, searchID int32, searchDTO model.SavedSearchDTO) (*model.SavedSearch, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	search, ok := m.savedSearches[searchID]
+	if !ok || search.UserID != searchDTO.UserID {
+		return nil, repository.ErrNotFound
+	}
+	if m.savedSearchNameTaken(searchDTO, searchID) {
+		return nil, repository.ErrAlreadyExists
+	}
+
+	search.Name = searchDTO.Name
+	search.Query = searchDTO.Query
+	search.Mode = searchDTO.Mode
+	search.Threshold = *searchDTO.Threshold
+	search.Language = searchDTO.Language
+	m.savedSearches[searchID] = search
+	return &search, nil
+}
+
+func (m *MockRepository) DeleteSavedSearch(ctx context.Context, searchID, userID int32) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	search, ok := m.savedSearches[searchID]
+	if !ok || search.UserID != userID {
+		return repository.ErrNotFound
+	}
+	delete(m.savedSearches, searchID)
+	return nil
+}
+
+func (m *MockRepository) MarkSavedSearchViewed(ctx context.Context, searchID, userID int32, viewedAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	search, ok := m.savedSearches[searchID]
+	if !ok || search.UserID != userID {
+		return repository.ErrNotFound
+	}
+	search.LastViewedAt = &viewedAt
+	m.savedSearches[searchID] = search
+	return nil
 }
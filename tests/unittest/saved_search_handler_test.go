@@ -0,0 +1,154 @@
+package unittest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"notes/internal/model"
+	"notes/internal/searchquery"
+	"notes/internal/server"
+)
+
+func TestSavedSearches(t *testing.T) {
+	_, e := setupServer(server.NewConfig("", 8080, 100, "secret"))
+
+	token := signUpAndLogIn(t, e, model.UserCreateDTO{
+		Username: "testuser",
+		Email:    "test@example.com",
+		Password: "Secure@Passwprd123",
+	})
+	otherToken := signUpAndLogIn(t, e, model.UserCreateDTO{
+		Username: "other",
+		Email:    "other@example.com",
+		Password: "Secure@Passwprd123",
+	})
+
+	var notes []model.Note
+	for _, noteDTO := range []model.NoteDTO{
+		{Title: "Pancake recipe", Content: "flour, eggs and milk", Tags: []string{"food"}},
+		{Title: "Waffle recipe", Content: "burnt waffles again", Tags: []string{"food"}},
+		{Title: "Groceries", Content: "milk"},
+	} {
+		rec := doRequest(e, http.MethodPost, "/api/notes/", token, noteDTO)
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		var note model.Note
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &note))
+		notes = append(notes, note)
+	}
+
+	// Mode and threshold default like searches.
+	rec := doRequest(e, http.MethodPost, "/api/searches/", token, model.SavedSearchDTO{Name: "Recipes", Query: "recipe -burnt tag:food"})
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var saved model.SavedSearch
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &saved))
+	assert.Equal(t, model.SearchModeFullText, saved.Mode)
+	assert.Equal(t, 0.3, saved.Threshold)
+	assert.Nil(t, saved.LastViewedAt)
+	assert.Nil(t, saved.Unread)
+	searchPath := "/api/searches/" + strconv.Itoa(int(saved.ID))
+
+	rec = doRequest(e, http.MethodPost, "/api/searches/", token, model.SavedSearchDTO{Name: "Recipes", Query: "recipe"})
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	rec = doRequest(e, http.MethodPost, "/api/searches/", token, model.SavedSearchDTO{Name: "Broken", Query: `"recipe`})
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	var queryErr searchquery.Error
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &queryErr))
+	assert.Equal(t, searchquery.Error{Position: 0, Message: "missing closing quote"}, queryErr)
+
+	for _, searchDTO := range []model.SavedSearchDTO{
+		{Name: " ", Query: "recipe"},
+		{Name: "Regex", Query: "recipe", Mode: "regex"},
+		{Name: "Klingon", Query: "recipe", Language: "klingon"},
+	} {
+		rec := doRequest(e, http.MethodPost, "/api/searches/", token, searchDTO)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, searchDTO.Name)
+	}
+
+	// Every matching note is unread until the results are viewed.
+	rec = doRequest(e, http.MethodGet, "/api/searches/?unread=true", token, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var searches []model.SavedSearch
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &searches))
+	require.Len(t, searches, 1)
+	require.NotNil(t, searches[0].Unread)
+	assert.Equal(t, int64(1), *searches[0].Unread)
+
+	rec = doRequest(e, http.MethodGet, searchPath+"/results?highlight_start=%5B&highlight_stop=%5D", token, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var results model.SearchResultList
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &results))
+	require.Len(t, results.Notes, 1)
+	assert.Equal(t, "Pancake recipe", results.Notes[0].Title)
+	assert.Equal(t, "Pancake [recipe]", results.Notes[0].Snippets.Title)
+
+	rec = doRequest(e, http.MethodGet, searchPath+"?unread=true", token, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &saved))
+	assert.NotNil(t, saved.LastViewedAt)
+	require.NotNil(t, saved.Unread)
+	assert.Zero(t, *saved.Unread)
+
+	// Only matching notes updated since become unread.
+	for _, note := range notes {
+		rec := doRequest(e, http.MethodPut, "/api/notes/"+strconv.Itoa(int(note.ID)), token, model.NoteDTO{Title: note.Title, Content: note.Content + "!"})
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	}
+	rec = doRequest(e, http.MethodGet, searchPath+"?unread=true", token, nil)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &saved))
+	require.NotNil(t, saved.Unread)
+	assert.Equal(t, int64(1), *saved.Unread)
+
+	// Updates change what the search runs.
+	threshold := 0.2
+	rec = doRequest(e, http.MethodPut, searchPath, token, model.SavedSearchDTO{Name: "Fuzzy", Query: "recpie", Mode: model.SearchModeFuzzy, Threshold: &threshold})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &saved))
+	assert.Equal(t, 0.2, saved.Threshold)
+
+	rec = doRequest(e, http.MethodGet, searchPath+"/results", token, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &results))
+	assert.Len(t, results.Notes, 2)
+	for _, result := range results.Notes {
+		assert.Greater(t, result.Score, 0.0)
+	}
+
+	// Only the first page marks the results as read.
+	rec = doRequest(e, http.MethodGet, searchPath+"/results?limit=1", token, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &results))
+	require.NotEmpty(t, results.NextCursor)
+
+	for _, note := range notes {
+		rec := doRequest(e, http.MethodPut, "/api/notes/"+strconv.Itoa(int(note.ID)), token, model.NoteDTO{Title: note.Title, Content: note.Content + "?"})
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	}
+	rec = doRequest(e, http.MethodGet, searchPath+"/results?limit=1&cursor="+url.QueryEscape(results.NextCursor), token, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = doRequest(e, http.MethodGet, searchPath+"?unread=true", token, nil)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &saved))
+	require.NotNil(t, saved.Unread)
+	assert.Equal(t, int64(2), *saved.Unread)
+
+	// Saved searches are private.
+	for _, path := range []string{searchPath, searchPath + "/results"} {
+		rec := doRequest(e, http.MethodGet, path, otherToken, nil)
+		assert.Equal(t, http.StatusNotFound, rec.Code, path)
+	}
+	rec = doRequest(e, http.MethodDelete, searchPath, otherToken, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = doRequest(e, http.MethodDelete, searchPath, token, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = doRequest(e, http.MethodGet, searchPath+"/results", token, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
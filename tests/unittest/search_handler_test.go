@@ -0,0 +1,304 @@
+package unittest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"notes/internal/model"
+	"notes/internal/searchquery"
+	"notes/internal/server"
+)
+
+// helper function to search notes and return the results
+func searchNotes(t *testing.T, e *echo.Echo, token, query string) model.SearchResultList {
+	rec := doRequest(e, http.MethodGet, "/api/notes/search?"+query, token, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var results model.SearchResultList
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &results))
+	return results
+}
+
+func TestFuzzySearch(t *testing.T) {
+	_, e := setupServer(server.NewConfig("", 8080, 100, "secret"))
+
+	token := signUpAndLogIn(t, e, model.UserCreateDTO{
+		Username: "testuser",
+		Email:    "test@example.com",
+		Password: "Secure@Passwprd123",
+	})
+
+	for _, note := range []model.NoteDTO{
+		{Title: "Pancake recipe", Content: "flour, eggs and milk"},
+		{Title: "Groceries", Content: "buy flour for the recipes"},
+		{Title: "Meeting", Content: "agenda for monday"},
+	} {
+		rec := doRequest(e, http.MethodPost, "/api/notes/", token, note)
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	}
+
+	// Full-text search needs the exact words, fuzzy search doesn't.
+	results := searchNotes(t, e, token, "q=recpie")
+	assert.Empty(t, results.Notes)
+
+	results = searchNotes(t, e, token, "q=recpie&mode=fuzzy")
+	require.Len(t, results.Notes, 2)
+	for _, result := range results.Notes {
+		assert.Greater(t, result.Score, 0.0)
+		assert.LessOrEqual(t, result.Score, 1.0)
+	}
+
+	// Titles match too, and the best match comes first.
+	results = searchNotes(t, e, token, "q=pancak&mode=fuzzy")
+	require.Len(t, results.Notes, 1)
+	assert.Equal(t, "Pancake recipe", results.Notes[0].Title)
+
+	results = searchNotes(t, e, token, "q=flour%20recipe&mode=fuzzy")
+	require.Len(t, results.Notes, 2)
+	assert.GreaterOrEqual(t, results.Notes[0].Score, results.Notes[1].Score)
+
+	// A higher threshold only keeps close matches.
+	results = searchNotes(t, e, token, "q=recpie&mode=fuzzy&threshold=0.9")
+	assert.Empty(t, results.Notes)
+
+	// Results are paginated by relevance.
+	results = searchNotes(t, e, token, "q=flour%20recipe&mode=fuzzy&limit=1")
+	require.Len(t, results.Notes, 1)
+	require.NotEmpty(t, results.NextCursor)
+	first := results.Notes[0].ID
+
+	results = searchNotes(t, e, token, "q=flour%20recipe&mode=fuzzy&limit=1&cursor="+results.NextCursor)
+	require.Len(t, results.Notes, 1)
+	assert.NotEqual(t, first, results.Notes[0].ID)
+	assert.Empty(t, results.NextCursor)
+
+	for _, query := range []string{
+		"q=recipe&mode=regex",
+		"q=recipe&mode=fuzzy&threshold=2",
+		"q=recipe&mode=fuzzy&threshold=high",
+		"q=recipe&mode=fuzzy&sort=popularity",
+	} {
+		rec := doRequest(e, http.MethodGet, "/api/notes/search?"+query, token, nil)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+
+	// Only ranked listings can be sorted by relevance.
+	rec := doRequest(e, http.MethodGet, "/api/notes/?sort=relevance", token, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestRankedSearch(t *testing.T) {
+	_, e := setupServer(server.NewConfig("", 8080, 100, "secret"))
+
+	token := signUpAndLogIn(t, e, model.UserCreateDTO{
+		Username: "testuser",
+		Email:    "test@example.com",
+		Password: "Secure@Passwprd123",
+	})
+
+	for _, note := range []model.NoteDTO{
+		{Title: "Groceries", Content: "buy flour for the pancake"},
+		{Title: "Pancake recipe", Content: "flour, eggs and milk"},
+		{Title: "Meeting", Content: "agenda for <i>monday</i>"},
+	} {
+		rec := doRequest(e, http.MethodPost, "/api/notes/", token, note)
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	}
+
+	// Matches in the title rank higher than in the content.
+	results := searchNotes(t, e, token, "q=pancake")
+	require.Len(t, results.Notes, 2)
+	assert.Equal(t, "Pancake recipe", results.Notes[0].Title)
+	assert.Equal(t, "Groceries", results.Notes[1].Title)
+	assert.Greater(t, results.Notes[0].Rank, results.Notes[1].Rank)
+	assert.Zero(t, results.Notes[0].Score)
+
+	require.NotNil(t, results.Notes[0].Snippets)
+	assert.Equal(t, "<b>Pancake</b> recipe", results.Notes[0].Snippets.Title)
+	assert.Equal(t, "buy flour for the <b>pancake</b>", results.Notes[1].Snippets.Content)
+
+	results = searchNotes(t, e, token, "q=pancake&highlight_start=%5B&highlight_stop=%5D")
+	require.Len(t, results.Notes, 2)
+	assert.Equal(t, "[Pancake] recipe", results.Notes[0].Snippets.Title)
+
+	// The text of snippets is escaped, so they can be shown as HTML.
+	results = searchNotes(t, e, token, "q=monday")
+	require.Len(t, results.Notes, 1)
+	assert.Equal(t, "agenda for &lt;i&gt;<b>monday</b>&lt;/i&gt;", results.Notes[0].Snippets.Content)
+
+	// Results are paginated in order of rank, unless sorted otherwise.
+	results = searchNotes(t, e, token, "q=pancake&limit=1")
+	require.Len(t, results.Notes, 1)
+	assert.Equal(t, "Pancake recipe", results.Notes[0].Title)
+	require.NotEmpty(t, results.NextCursor)
+
+	results = searchNotes(t, e, token, "q=pancake&limit=1&cursor="+results.NextCursor)
+	require.Len(t, results.Notes, 1)
+	assert.Equal(t, "Groceries", results.Notes[0].Title)
+	assert.Empty(t, results.NextCursor)
+
+	results = searchNotes(t, e, token, "q=pancake&sort=title")
+	require.Len(t, results.Notes, 2)
+	assert.Equal(t, "Groceries", results.Notes[0].Title)
+
+	for _, query := range []string{
+		"q=pancake&highlight_start=%22",
+		"q=pancake&highlight_stop=%5C",
+	} {
+		rec := doRequest(e, http.MethodGet, "/api/notes/search?"+query, token, nil)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+}
+
+func TestSearchQueryLanguage(t *testing.T) {
+	_, e := setupServer(server.NewConfig("", 8080, 100, "secret"))
+
+	token := signUpAndLogIn(t, e, model.UserCreateDTO{
+		Username: "testuser",
+		Email:    "test@example.com",
+		Password: "Secure@Passwprd123",
+	})
+	otherToken := signUpAndLogIn(t, e, model.UserCreateDTO{
+		Username: "other",
+		Email:    "other@example.com",
+		Password: "Secure@Passwprd123",
+	})
+
+	for _, note := range []model.NoteDTO{
+		{Title: "Pancake recipe", Content: "flour, eggs and milk", Tags: []string{"food"}},
+		{Title: "Waffle recipe", Content: "burnt waffles again", Tags: []string{"food"}},
+		{Title: "Groceries", Content: "pancake flour and milk"},
+	} {
+		rec := doRequest(e, http.MethodPost, "/api/notes/", token, note)
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	}
+
+	rec := doRequest(e, http.MethodPost, "/api/notes/", otherToken, model.NoteDTO{Title: "Shared recipe", Content: "crepes"})
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var shared model.Note
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &shared))
+	rec = doRequest(e, http.MethodPost, "/api/notes/"+strconv.Itoa(int(shared.ID))+"/share", otherToken, model.NoteShareDTO{SharedWith: "test@example.com"})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	testCases := []struct {
+		query  string
+		expect []string
+	}{
+		{`"flour and milk"`, []string{"Groceries"}},
+		{`recipe -burnt`, []string{"Pancake recipe", "Shared recipe"}},
+		{`waffle OR groceries`, []string{"Groceries", "Waffle recipe"}},
+		{`title:pancake`, []string{"Pancake recipe"}},
+		{`tag:food -(waffle OR burnt)`, []string{"Pancake recipe"}},
+		{`recipe shared:yes`, []string{"Shared recipe"}},
+		{`recipe shared:no`, []string{"Pancake recipe", "Waffle recipe"}},
+		{`recipe owner:other@example.com`, []string{"Shared recipe"}},
+		{`recipe owner:me`, []string{"Pancake recipe", "Waffle recipe"}},
+		{`milk after:2000-01-01`, []string{"Groceries", "Pancake recipe"}},
+		{`milk before:2000-01-01`, nil},
+		{`milk updated_before:2999-01-01T00:00:00Z`, []string{"Groceries", "Pancake recipe"}},
+	}
+
+	for _, testCase := range testCases {
+		results := searchNotes(t, e, token, "q="+url.QueryEscape(testCase.query))
+
+		var titles []string
+		for _, result := range results.Notes {
+			titles = append(titles, result.Title)
+		}
+		slices.Sort(titles)
+		assert.Equal(t, testCase.expect, titles, "Unexpected notes for query: %s", testCase.query)
+	}
+
+	// Parse errors point at the problem.
+	for _, testCase := range []struct {
+		query    string
+		expected searchquery.Error
+	}{
+		{`"pancake`, searchquery.Error{Position: 0, Message: "missing closing quote"}},
+		{`milk shared:maybe`, searchquery.Error{Position: 12, Message: "shared: must be yes or no"}},
+		{`milk OR`, searchquery.Error{Position: 7, Message: "expected a term after OR"}},
+	} {
+		rec := doRequest(e, http.MethodGet, "/api/notes/search?q="+url.QueryEscape(testCase.query), token, nil)
+		require.Equal(t, http.StatusBadRequest, rec.Code, testCase.query)
+
+		var queryErr searchquery.Error
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &queryErr))
+		assert.Equal(t, testCase.expected, queryErr, testCase.query)
+	}
+}
+
+func TestSearchLanguage(t *testing.T) {
+	_, e := setupServer(server.NewConfig("", 8080, 100, "secret"))
+
+	token := signUpAndLogIn(t, e, model.UserCreateDTO{
+		Username: "testuser",
+		Email:    "test@example.com",
+		Password: "Secure@Passwprd123",
+	})
+
+	// Notes default to the search language of their owner.
+	rec := doRequest(e, http.MethodPost, "/api/notes/", token, model.NoteDTO{Title: "Pancake recipe", Content: "flour"})
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var note model.Note
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &note))
+	assert.Equal(t, model.DefaultSearchLanguage, note.Language)
+
+	rec = doRequest(e, http.MethodPut, "/api/users/me", token, model.UpdateUserDTO{Username: "testuser", SearchLanguage: "german"})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var user model.User
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &user))
+	assert.Equal(t, "german", user.SearchLanguage)
+
+	rec = doRequest(e, http.MethodPost, "/api/notes/", token, model.NoteDTO{Title: "Pfannkuchen recipe", Content: "Mehl"})
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &note))
+	assert.Equal(t, "german", note.Language)
+
+	rec = doRequest(e, http.MethodPost, "/api/notes/", token, model.NoteDTO{Title: "Crêpes recipe", Content: "farine", Language: "french"})
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &note))
+	assert.Equal(t, "french", note.Language)
+
+	// Updates keep the language unless one is given.
+	notePath := "/api/notes/" + strconv.Itoa(int(note.ID))
+	rec = doRequest(e, http.MethodPut, notePath, token, model.NoteDTO{Title: "Crêpes recipe", Content: "farine, œufs"})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &note))
+	assert.Equal(t, "french", note.Language)
+
+	// Searches in a language only match the notes in it, and default to the
+	// search language of the user.
+	results := searchNotes(t, e, token, "q=recipe")
+	require.Len(t, results.Notes, 1)
+	assert.Equal(t, "Pfannkuchen recipe", results.Notes[0].Title)
+
+	results = searchNotes(t, e, token, "q=recipe&language=english")
+	require.Len(t, results.Notes, 1)
+	assert.Equal(t, "Pancake recipe", results.Notes[0].Title)
+
+	// Fuzzy searches compare the query to notes in every language.
+	results = searchNotes(t, e, token, "q=recipe&mode=fuzzy")
+	assert.Len(t, results.Notes, 3)
+
+	for _, request := range []struct {
+		method, path string
+		payload      any
+	}{
+		{http.MethodGet, "/api/notes/search?q=recipe&language=klingon", nil},
+		{http.MethodGet, "/api/notes/search?q=recipe&language=note", nil},
+		{http.MethodPost, "/api/notes/", model.NoteDTO{Title: "title", Language: "klingon"}},
+		{http.MethodPut, notePath, model.NoteDTO{Title: "title", Language: "English"}},
+		{http.MethodPut, "/api/users/me", model.UpdateUserDTO{Username: "testuser", SearchLanguage: "klingon"}},
+	} {
+		rec := doRequest(e, request.method, request.path, token, request.payload)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, request.path)
+	}
+}
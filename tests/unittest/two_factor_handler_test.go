@@ -0,0 +1,115 @@
+package unittest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"notes/internal/model"
+	"notes/internal/server"
+	"notes/internal/totp"
+)
+
+func TestTwoFactorAuthentication(t *testing.T) {
+	_, e := setupServer(server.NewConfig("", 8080, 100, "secret"))
+
+	user := model.UserCreateDTO{Username: "testuser", Email: "test@example.com", Password: "Secure@Passwprd123"}
+	token := signUpAndLogIn(t, e, user)
+
+	// The codes of the previous, current and next periods are valid, so
+	// the test has three codes to use unless a period ends while it runs.
+	now := time.Now()
+	if untilNext := totp.Period - time.Duration(now.UnixNano())%totp.Period; untilNext < 2*time.Second {
+		time.Sleep(untilNext)
+		now = time.Now()
+	}
+	code := func(secret string, offset int64) string {
+		code, err := totp.Code(secret, totp.Counter(now)+offset)
+		require.NoError(t, err)
+		return code
+	}
+	logIn := func() *model.LoginChallenge {
+		rec := doRequest(e, http.MethodPost, "/api/auth/login", "", model.LogInDTO{Email: user.Email, Password: user.Password})
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var challenge model.LoginChallenge
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &challenge))
+		return &challenge
+	}
+
+	rec := doRequest(e, http.MethodPost, "/api/users/me/2fa", token, model.EnrollTOTPDTO{Password: "wrong"})
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = doRequest(e, http.MethodPost, "/api/users/me/2fa", token, model.EnrollTOTPDTO{Password: user.Password})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var enrollment model.TOTPEnrollment
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &enrollment))
+	assert.True(t, strings.HasPrefix(enrollment.URI, "otpauth://totp/Notes:test@example.com?"))
+	assert.Contains(t, enrollment.URI, "secret="+enrollment.Secret)
+
+	// Until it is confirmed, logging in doesn't need a code.
+	assert.Empty(t, logIn().ChallengeToken)
+
+	rec = doRequest(e, http.MethodPost, "/api/users/me/2fa/confirm", token, model.TOTPCodeDTO{Code: code(enrollment.Secret, 10)})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = doRequest(e, http.MethodPost, "/api/users/me/2fa/confirm", token, model.TOTPCodeDTO{Code: code(enrollment.Secret, -1)})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var recoveryCodes model.RecoveryCodes
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &recoveryCodes))
+	assert.Len(t, recoveryCodes.Codes, 10)
+
+	rec = doRequest(e, http.MethodPost, "/api/users/me/2fa", token, model.EnrollTOTPDTO{Password: user.Password})
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	// Logging in now takes a code after the password.
+	challenge := logIn()
+	require.NotEmpty(t, challenge.ChallengeToken)
+
+	// A code can't be used twice, and a wrong guess uses up the challenge.
+	rec = doRequest(e, http.MethodPost, "/api/auth/login/2fa", "", model.LogInTOTPDTO{ChallengeToken: challenge.ChallengeToken, Code: code(enrollment.Secret, -1)})
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	rec = doRequest(e, http.MethodPost, "/api/auth/login/2fa", "", model.LogInTOTPDTO{ChallengeToken: challenge.ChallengeToken, Code: code(enrollment.Secret, 0)})
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = doRequest(e, http.MethodPost, "/api/auth/login/2fa", "", model.LogInTOTPDTO{ChallengeToken: logIn().ChallengeToken, Code: code(enrollment.Secret, 0)})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var tokens model.AuthTokens
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &tokens))
+	require.NotEmpty(t, tokens.Token)
+
+	// Recovery codes work once instead of a code.
+	rec = doRequest(e, http.MethodPost, "/api/auth/login/2fa", "", model.LogInTOTPDTO{ChallengeToken: logIn().ChallengeToken, Code: recoveryCodes.Codes[0]})
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	rec = doRequest(e, http.MethodPost, "/api/auth/login/2fa", "", model.LogInTOTPDTO{ChallengeToken: logIn().ChallengeToken, Code: recoveryCodes.Codes[0]})
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// Regenerating the recovery codes takes a code of the app.
+	rec = doRequest(e, http.MethodPost, "/api/users/me/2fa/recovery-codes", tokens.Token, model.TOTPCodeDTO{Code: recoveryCodes.Codes[1]})
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = doRequest(e, http.MethodPost, "/api/users/me/2fa/recovery-codes", tokens.Token, model.TOTPCodeDTO{Code: code(enrollment.Secret, 1)})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var newRecoveryCodes model.RecoveryCodes
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &newRecoveryCodes))
+	assert.Len(t, newRecoveryCodes.Codes, 10)
+
+	// Disabling takes the password and a code, the old recovery codes no
+	// longer work.
+	rec = doRequest(e, http.MethodDelete, "/api/users/me/2fa", tokens.Token, model.DisableTOTPDTO{Password: user.Password, Code: recoveryCodes.Codes[1]})
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// A wrong password doesn't use up the code.
+	rec = doRequest(e, http.MethodDelete, "/api/users/me/2fa", tokens.Token, model.DisableTOTPDTO{Password: "wrong", Code: newRecoveryCodes.Codes[0]})
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = doRequest(e, http.MethodDelete, "/api/users/me/2fa", tokens.Token, model.DisableTOTPDTO{Password: user.Password, Code: newRecoveryCodes.Codes[0]})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	// Without two-factor authentication, the password is enough again.
+	assert.Empty(t, logIn().ChallengeToken)
+}
@@ -6,10 +6,12 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"notes/internal/model"
 	"notes/internal/server"
@@ -21,6 +23,7 @@ func setupServer(config server.Config) (*server.Server, *echo.Echo) {
 	s := &server.Server{
 		Repository: mockRepo,
 		Config:     config,
+		Mailer:     &MockMailer{},
 	}
 	e := s.RegisterRoutes()
 	e.Logger.SetOutput(io.Discard)
@@ -120,3 +123,201 @@ func TestRateLimit(t *testing.T) {
 
 	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
 }
+
+func TestRefreshAndLogOut(t *testing.T) {
+	_, e := setupServer(server.NewConfig("", 8080, 100, "secret"))
+
+	user := model.UserCreateDTO{
+		Username: "testuser",
+		Email:    "test@example.com",
+		Password: "Secure@Passwprd123",
+	}
+	rec := doRequest(e, http.MethodPost, "/api/auth/signup", "", user)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	logIn := func() model.AuthTokens {
+		rec := doRequest(e, http.MethodPost, "/api/auth/login", "", model.LogInDTO{Email: user.Email, Password: user.Password})
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		var tokens model.AuthTokens
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &tokens))
+		return tokens
+	}
+	session1, session2 := logIn(), logIn()
+
+	rec = doRequest(e, http.MethodPost, "/api/auth/refresh", "", model.RefreshDTO{RefreshToken: session1.RefreshToken})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var refreshed model.AuthTokens
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &refreshed))
+	assert.NotEqual(t, session1.RefreshToken, refreshed.RefreshToken)
+
+	rec = doRequest(e, http.MethodGet, "/api/notes/", refreshed.Token, nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Reusing a refresh token revokes its session.
+	rec = doRequest(e, http.MethodPost, "/api/auth/refresh", "", model.RefreshDTO{RefreshToken: session1.RefreshToken})
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = doRequest(e, http.MethodGet, "/api/notes/", refreshed.Token, nil)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// Logging out only ends the current session.
+	session3 := logIn()
+	rec = doRequest(e, http.MethodPost, "/api/auth/logout", session3.Token, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = doRequest(e, http.MethodGet, "/api/notes/", session3.Token, nil)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = doRequest(e, http.MethodPost, "/api/auth/refresh", "", model.RefreshDTO{RefreshToken: session3.RefreshToken})
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = doRequest(e, http.MethodGet, "/api/notes/", session2.Token, nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = doRequest(e, http.MethodPost, "/api/auth/logout-all", session2.Token, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = doRequest(e, http.MethodGet, "/api/notes/", session2.Token, nil)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestPasswordReset(t *testing.T) {
+	s, e := setupServer(server.NewConfig("", 8080, 100, "secret"))
+	mailer := s.Mailer.(*MockMailer)
+
+	user := model.UserCreateDTO{
+		Username: "testuser",
+		Email:    "test@example.com",
+		Password: "Secure@Passwprd123",
+	}
+	token := signUpAndLogIn(t, e, user)
+	sentAtSignUp := len(mailer.Sent())
+
+	// Unknown emails get the same response, but no email.
+	rec := doRequest(e, http.MethodPost, "/api/auth/password/forgot", "", model.ForgotPasswordDTO{Email: "unknown@example.com"})
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Len(t, mailer.Sent(), sentAtSignUp)
+
+	rec = doRequest(e, http.MethodPost, "/api/auth/password/forgot", "", model.ForgotPasswordDTO{Email: "invalid-email"})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = doRequest(e, http.MethodPost, "/api/auth/password/forgot", "", model.ForgotPasswordDTO{Email: user.Email})
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+
+	sent := mailer.Sent()
+	require.Len(t, sent, sentAtSignUp+1)
+	assert.Equal(t, user.Email, sent[sentAtSignUp].To)
+	lines := strings.Split(strings.TrimSpace(sent[sentAtSignUp].Body), "\n")
+	resetToken := lines[len(lines)-1]
+
+	rec = doRequest(e, http.MethodPost, "/api/auth/password/reset", "", model.ResetPasswordDTO{Token: resetToken, Password: "weak"})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	newPassword := "New@Passwprd456"
+	rec = doRequest(e, http.MethodPost, "/api/auth/password/reset", "", model.ResetPasswordDTO{Token: resetToken, Password: newPassword})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	// Resetting the password logs out existing sessions.
+	rec = doRequest(e, http.MethodGet, "/api/notes/", token, nil)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = doRequest(e, http.MethodPost, "/api/auth/login", "", model.LogInDTO{Email: user.Email, Password: user.Password})
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = doRequest(e, http.MethodPost, "/api/auth/login", "", model.LogInDTO{Email: user.Email, Password: newPassword})
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Reset tokens can only be used once.
+	rec = doRequest(e, http.MethodPost, "/api/auth/password/reset", "", model.ResetPasswordDTO{Token: resetToken, Password: "Other@Passwprd789"})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+// helper function to get the path of the link in the last verification
+// email sent to the address
+func verificationPath(t *testing.T, mailer *MockMailer, baseURL, email string) string {
+	sent := mailer.Sent()
+	for i := len(sent) - 1; i >= 0; i-- {
+		if sent[i].To != email {
+			continue
+		}
+		for _, line := range strings.Split(sent[i].Body, "\n") {
+			if strings.HasPrefix(line, baseURL) {
+				return strings.TrimPrefix(line, baseURL)
+			}
+		}
+	}
+	t.Fatalf("no verification email sent to %s", email)
+	return ""
+}
+
+func TestEmailVerification(t *testing.T) {
+	config := server.NewConfig("", 8080, 100, "secret")
+	config.RequireVerifiedEmail = true
+	s, e := setupServer(config)
+	s.Repository.(*MockRepository).RequireVerifiedEmail = true
+	mailer := s.Mailer.(*MockMailer)
+
+	owner := model.UserCreateDTO{Username: "owner", Email: "owner@example.com", Password: "Secure@Passwprd123"}
+	user := model.UserCreateDTO{Username: "testuser", Email: "test@example.com", Password: "Secure@Passwprd123"}
+
+	verifyLink := func(email string) string {
+		return verificationPath(t, mailer, config.BaseURL, email)
+	}
+
+	rec := doRequest(e, http.MethodPost, "/api/auth/signup", "", owner)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	rec = doRequest(e, http.MethodGet, verifyLink(owner.Email), "", nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	rec = doRequest(e, http.MethodPost, "/api/auth/login", "", model.LogInDTO{Email: owner.Email, Password: owner.Password})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var tokens model.AuthTokens
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &tokens))
+	ownerToken := tokens.Token
+
+	rec = doRequest(e, http.MethodPost, "/api/notes/", ownerToken, model.NoteDTO{Title: "shared", Content: "content"})
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	rec = doRequest(e, http.MethodPost, "/api/auth/signup", "", user)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	// Unverified users can't log in, and shares with them stay pending.
+	rec = doRequest(e, http.MethodPost, "/api/auth/login", "", model.LogInDTO{Email: user.Email, Password: user.Password})
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = doRequest(e, http.MethodPost, "/api/notes/1/share", ownerToken, model.NoteShareDTO{SharedWith: user.Email})
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+
+	rec = doRequest(e, http.MethodGet, "/api/auth/verify?token=invalid", "", nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// A new link can be requested, and only works once.
+	rec = doRequest(e, http.MethodPost, "/api/auth/verify/resend", "", model.VerifyEmailDTO{Email: user.Email})
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	link := verifyLink(user.Email)
+
+	rec = doRequest(e, http.MethodGet, link, "", nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var verified model.User
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &verified))
+	assert.NotNil(t, verified.EmailVerifiedAt)
+
+	rec = doRequest(e, http.MethodGet, link, "", nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = doRequest(e, http.MethodPost, "/api/auth/login", "", model.LogInDTO{Email: user.Email, Password: user.Password})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &tokens))
+
+	rec = doRequest(e, http.MethodGet, "/api/notes/1", tokens.Token, nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Verified emails get no more links.
+	sent := len(mailer.Sent())
+	rec = doRequest(e, http.MethodPost, "/api/auth/verify/resend", "", model.VerifyEmailDTO{Email: user.Email})
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Len(t, mailer.Sent(), sent)
+}